@@ -0,0 +1,315 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AntiPattern identifies an inefficient input habit the coach looks for.
+type AntiPattern int
+
+const (
+	PatternRepeatedVertical   AntiPattern = iota // jjjj → 4j
+	PatternRepeatedHorizontal                    // llll → w, e or f{char}
+	PatternRepeatedDelete                        // xxxx → 4x
+	PatternInsertArrows                          // moving with arrows while in insert mode
+	PatternInsertReentry                         // ESC immediately followed by i/a
+)
+
+// Minimum run lengths before a repeated key is reported.
+const (
+	minVerticalRun   = 3
+	minHorizontalRun = 4
+	minDeleteRun     = 3
+)
+
+// patternKeys name the anti-patterns in profiles.
+var patternKeys = [...]string{
+	PatternRepeatedVertical:   "repeated_vertical",
+	PatternRepeatedHorizontal: "repeated_horizontal",
+	PatternRepeatedDelete:     "repeated_delete",
+	PatternInsertArrows:       "insert_arrows",
+	PatternInsertReentry:      "insert_reentry",
+}
+
+// MarshalText encodes an anti-pattern by key.
+func (p AntiPattern) MarshalText() ([]byte, error) {
+	if p < 0 || int(p) >= len(patternKeys) {
+		return nil, fmt.Errorf("invalid anti-pattern %d", int(p))
+	}
+	return []byte(patternKeys[p]), nil
+}
+
+// UnmarshalText decodes an anti-pattern key written by MarshalText.
+func (p *AntiPattern) UnmarshalText(text []byte) error {
+	for i, key := range patternKeys {
+		if string(text) == key {
+			*p = AntiPattern(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown anti-pattern %q", text)
+}
+
+func (p AntiPattern) String() string {
+	switch p {
+	case PatternRepeatedVertical:
+		return "repeated j/k"
+	case PatternRepeatedHorizontal:
+		return "repeated h/l"
+	case PatternRepeatedDelete:
+		return "repeated x"
+	case PatternInsertArrows:
+		return "arrows in insert mode"
+	case PatternInsertReentry:
+		return "leaving and re-entering insert mode"
+	default:
+		return ""
+	}
+}
+
+// Tip is a coaching suggestion for one detected anti-pattern.
+type Tip struct {
+	Pattern AntiPattern
+	Used    string // what the player typed, e.g. "jjjj"
+	Better  string // the suggested alternative, e.g. "4j"
+	Saved   int    // keystrokes the alternative would have saved
+}
+
+func (t Tip) String() string {
+	return t.Used + " → " + t.Better
+}
+
+// Coach watches the parsed input of the current target or exercise and
+// counts the anti-patterns it finds across the run, and across every run
+// in Totals.
+type Coach struct {
+	History []ParseResult
+	Counts  map[AntiPattern]int // this run's
+	Totals  map[AntiPattern]int // the player's, kept in their profile; nil keeps none
+	Allowed MotionSet           // motions tips may suggest; nil for any
+}
+
+// Record appends a parsed keypress to the current history.
+// Partial input (pending g/f/r, count digits) carries no action and is skipped.
+func (c *Coach) Record(r ParseResult) {
+	if !r.Consumed || r.Action == ActionNone {
+		return
+	}
+	c.History = append(c.History, r)
+}
+
+// Reset clears the current history, keeping the run's counts.
+func (c *Coach) Reset() {
+	c.History = nil
+}
+
+// Finish analyzes the current history, adds every detected anti-pattern to
+// the run's counts and the totals, and returns the tip that would have saved the most
+// keystrokes. The history is cleared for the next target or exercise.
+func (c *Coach) Finish() (Tip, bool) {
+	tips := AnalyzeInput(c.History, c.Allowed)
+	c.History = nil
+	if len(tips) == 0 {
+		return Tip{}, false
+	}
+	if c.Counts == nil {
+		c.Counts = make(map[AntiPattern]int)
+	}
+	best := tips[0]
+	for _, t := range tips {
+		c.Counts[t.Pattern]++
+		if c.Totals != nil {
+			c.Totals[t.Pattern]++
+		}
+		if t.Saved > best.Saved {
+			best = t
+		}
+	}
+	return best, true
+}
+
+// PatternCount pairs an anti-pattern with how often it was seen.
+type PatternCount struct {
+	Pattern AntiPattern `json:"pattern"`
+	Count   int         `json:"count"`
+}
+
+// TopPatterns returns the run's anti-patterns, most frequent first.
+func (c *Coach) TopPatterns(n int) []PatternCount {
+	return topPatterns(c.Counts, n)
+}
+
+// topPatterns returns the n most frequent anti-patterns of counts, or all
+// of them if n is 0.
+func topPatterns(counts map[AntiPattern]int, n int) []PatternCount {
+	out := []PatternCount{}
+	for p, cnt := range counts {
+		out = append(out, PatternCount{p, cnt})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Pattern < out[j].Pattern
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// AnalyzeInput scans a sequence of parse results for inefficient patterns.
+// Tips only suggest allowed motions; nil allows every motion.
+func AnalyzeInput(history []ParseResult, allowed MotionSet) []Tip {
+	var tips []Tip
+
+	// Runs of the same uncounted single-step command.
+	for i := 0; i < len(history); {
+		r := history[i]
+		j := i + 1
+		for j < len(history) && sameSingleStep(history[j], r) {
+			j++
+		}
+		run := j - i
+		if isSingleStep(r) {
+			if tip, ok := runTip(r, run, allowed); ok {
+				tips = append(tips, tip)
+			}
+		}
+		i = j
+	}
+
+	// Arrow keys while in insert mode.
+	arrows := 0
+	var used strings.Builder
+	for _, r := range history {
+		if r.Action == ActionInsertArrow {
+			arrows++
+			used.WriteString(arrowName(r.Motion))
+		}
+	}
+	if arrows > 2 { // fewer arrows are no slower than leaving insert mode
+		tips = append(tips, Tip{
+			Pattern: PatternInsertArrows,
+			Used:    used.String() + " in insert mode",
+			Better:  "ESC, a motion, then i/a",
+			Saved:   arrows - 2,
+		})
+	}
+
+	// ESC straight back into insert mode at (nearly) the same spot.
+	for i := 0; i+1 < len(history); i++ {
+		if history[i].Action != ActionExitInsert {
+			continue
+		}
+		next := history[i+1]
+		if next.Action == ActionInsertBefore || next.Action == ActionInsertAfter {
+			tips = append(tips, Tip{
+				Pattern: PatternInsertReentry,
+				Used:    "ESC " + actionKey(next.Action),
+				Better:  "keep typing in insert mode",
+				Saved:   2,
+			})
+		}
+	}
+
+	return tips
+}
+
+func isSingleStep(r ParseResult) bool {
+	if r.Count > 0 {
+		return false
+	}
+	if r.Action == ActionDeleteChar {
+		return true
+	}
+	if r.Action != ActionMotion {
+		return false
+	}
+	switch r.Motion {
+	case MotionH, MotionJ, MotionK, MotionL:
+		return true
+	}
+	return false
+}
+
+func sameSingleStep(a, b ParseResult) bool {
+	return isSingleStep(a) && isSingleStep(b) && a.Action == b.Action && a.Motion == b.Motion
+}
+
+func runTip(r ParseResult, run int, allowed MotionSet) (Tip, bool) {
+	if r.Action == ActionDeleteChar {
+		if run < minDeleteRun {
+			return Tip{}, false
+		}
+		better := fmt.Sprintf("%dx", run)
+		return Tip{
+			Pattern: PatternRepeatedDelete,
+			Used:    strings.Repeat("x", run),
+			Better:  better,
+			Saved:   run - len(better),
+		}, true
+	}
+
+	key := MotionName(r.Motion)
+	used := strings.Repeat(key, run)
+	switch r.Motion {
+	case MotionJ, MotionK:
+		if run < minVerticalRun {
+			return Tip{}, false
+		}
+		better := fmt.Sprintf("%d%s", run, key)
+		return Tip{Pattern: PatternRepeatedVertical, Used: used, Better: better, Saved: run - len(better)}, true
+	case MotionH, MotionL:
+		if run < minHorizontalRun {
+			return Tip{}, false
+		}
+		alternatives := []Motion{MotionW, MotionE, MotionFChar}
+		if r.Motion == MotionH {
+			alternatives = []Motion{MotionB, MotionBigFChar}
+		}
+		var names []string
+		for _, mo := range alternatives {
+			if allowed == nil || allowed[mo] {
+				names = append(names, MotionName(mo))
+			}
+		}
+		if len(names) == 0 {
+			return Tip{}, false
+		}
+		better := names[len(names)-1]
+		if len(names) > 1 {
+			better = strings.Join(names[:len(names)-1], ", ") + " or " + better
+		}
+		return Tip{Pattern: PatternRepeatedHorizontal, Used: used, Better: better, Saved: run - 2}, true
+	}
+	return Tip{}, false
+}
+
+func arrowName(m Motion) string {
+	switch m {
+	case MotionH:
+		return "←"
+	case MotionL:
+		return "→"
+	case MotionK:
+		return "↑"
+	case MotionJ:
+		return "↓"
+	default:
+		return ""
+	}
+}
+
+func actionKey(a Action) string {
+	switch a {
+	case ActionInsertBefore:
+		return "i"
+	case ActionInsertAfter:
+		return "a"
+	default:
+		return ""
+	}
+}
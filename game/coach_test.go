@@ -0,0 +1,81 @@
+package game
+
+import "testing"
+
+// feedCoach records keys, parsed in normal mode, with the coach.
+func feedCoach(c *Coach, keys ...string) {
+	var p InputParser
+	for _, k := range keys {
+		c.Record(p.Feed(k))
+	}
+}
+
+func TestCoachTips(t *testing.T) {
+	for _, tc := range []struct {
+		keys    []string
+		pattern AntiPattern
+		better  string
+	}{
+		{[]string{"j", "j", "j", "j"}, PatternRepeatedVertical, "4j"},
+		{[]string{"l", "l", "l", "l", "l"}, PatternRepeatedHorizontal, "w, e or f{char}"},
+		{[]string{"x", "x", "x"}, PatternRepeatedDelete, "3x"},
+		{[]string{"i", "esc", "a"}, PatternInsertReentry, "keep typing in insert mode"},
+	} {
+		var c Coach
+		feedCoach(&c, tc.keys...)
+		tip, ok := c.Finish()
+		if !ok || tip.Pattern != tc.pattern || tip.Better != tc.better {
+			t.Errorf("%v: tip %+v, %v; want %v → %q", tc.keys, tip, ok, tc.pattern, tc.better)
+		}
+	}
+
+	var c Coach
+	feedCoach(&c, "j", "j", "k")
+	if tip, ok := c.Finish(); ok {
+		t.Errorf("short runs: got tip %v", tip)
+	}
+}
+
+func TestCoachHabitsPersist(t *testing.T) {
+	profiles, err := OpenProfileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel()
+	m.Profiles = profiles
+	if err := m.UseProfile("ada"); err != nil {
+		t.Fatal(err)
+	}
+	m.beginRun(GameModeEndless, 0, 0, 1)
+	feedCoach(&m.Coach, "j", "j", "j", "j")
+	m.Coach.Finish()
+	feedCoach(&m.Coach, "x", "x", "x")
+	m.Coach.Finish()
+	m.saveProfile()
+	if m.ProfileErr != nil {
+		t.Fatal(m.ProfileErr)
+	}
+
+	// A new run starts counting afresh; the profile keeps counting
+	m.beginRun(GameModeEndless, 0, 0, 2)
+	if len(m.Coach.TopPatterns(0)) != 0 {
+		t.Errorf("new run starts with %v", m.Coach.TopPatterns(0))
+	}
+	feedCoach(&m.Coach, "k", "k", "k")
+	m.Coach.Finish()
+	m.saveProfile()
+
+	loaded := NewModel()
+	loaded.Profiles = profiles
+	if err := loaded.UseProfile("ada"); err != nil {
+		t.Fatal(err)
+	}
+	want := []PatternCount{{PatternRepeatedVertical, 2}, {PatternRepeatedDelete, 1}}
+	got := loaded.statsReport().Habits
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("loaded habits %v, want %v", got, want)
+	}
+	if loaded.Coach.Totals[PatternRepeatedVertical] != 2 {
+		t.Errorf("coach counts on from %v", loaded.Coach.Totals)
+	}
+}
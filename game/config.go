@@ -0,0 +1,151 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"vimgame/store"
+	"vimgame/ui"
+)
+
+// ConfigVersion is the current config file schema version.
+const ConfigVersion = 1
+
+// Scoring selects how reaching a motion target earns a medal.
+type Scoring int
+
+const (
+	ScoringThresholds Scoring = iota // fixed keystroke thresholds
+	ScoringSolver                    // keystrokes compared with the solver's optimum
+)
+
+var scoringNames = [...]string{"thresholds", "solver"}
+
+func (s Scoring) String() string {
+	if s < 0 || int(s) >= len(scoringNames) {
+		return fmt.Sprintf("Scoring(%d)", int(s))
+	}
+	return scoringNames[s]
+}
+
+// MarshalText encodes a scoring mode by name.
+func (s Scoring) MarshalText() ([]byte, error) {
+	if s < 0 || int(s) >= len(scoringNames) {
+		return nil, fmt.Errorf("invalid scoring %d", int(s))
+	}
+	return []byte(scoringNames[s]), nil
+}
+
+// UnmarshalText decodes a scoring mode name written by MarshalText.
+func (s *Scoring) UnmarshalText(text []byte) error {
+	for i, name := range scoringNames {
+		if string(text) == name {
+			*s = Scoring(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown scoring %q", text)
+}
+
+// Rules are the settings that change how keys play out. Replays record
+// them so a run plays back the same way; the zero Rules are the game's
+// behavior before they existed.
+type Rules struct {
+	Scoring    Scoring `json:"scoring,omitempty"`
+	ShiftWidth int     `json:"shiftwidth,omitempty"` // Tab in insert mode indents to a multiple of this; 0 ignores Tab
+	KeyTimeout int     `json:"timeoutlen,omitempty"` // ms after which a half-typed command is dropped; 0 waits forever
+}
+
+// Config is a player's settings, kept in their profile once changed.
+// config.json in the config directory holds the settings of profiles that
+// have none of their own.
+type Config struct {
+	Version        int     `json:"version"`
+	Theme          string  `json:"theme"` // built-in theme name or JSON theme file; empty for the default
+	Scoring        Scoring `json:"scoring"`
+	Number         bool    `json:"number"`         // with RelativeNumber, number the cursor line absolutely
+	RelativeNumber bool    `json:"relativenumber"` // number lines by their distance from the cursor
+	ScrollOff      int     `json:"scrolloff"`
+	TabWidth       int     `json:"tabwidth"` // default tab expansion for practice files
+	ShiftWidth     int     `json:"shiftwidth"`
+	ShowCmd        bool    `json:"showcmd"`
+	Bell           bool    `json:"bell"`
+	KeyTimeout     int     `json:"timeoutlen"`
+
+	path string
+}
+
+// DefaultScrollOff keeps the cursor centered, as the game always has.
+const DefaultScrollOff = 999
+
+// NewConfig returns the default settings.
+func NewConfig() *Config {
+	return &Config{
+		Version:    ConfigVersion,
+		Number:     true,
+		ScrollOff:  DefaultScrollOff,
+		TabWidth:   DefaultTabWidth,
+		ShiftWidth: 4,
+		ShowCmd:    true,
+	}
+}
+
+// SchemaVersion implements store.Versioned.
+func (c *Config) SchemaVersion() int { return c.Version }
+
+// OpenConfig loads the config file in a config directory. A missing file
+// or missing fields give the defaults.
+func OpenConfig(dir string) (*Config, error) {
+	c := NewConfig()
+	c.path = filepath.Join(dir, "config.json")
+	err := store.LoadJSON(c.path, c)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := store.CheckVersion(c.path, c, ConfigVersion); err != nil {
+		return nil, err
+	}
+	c.Version = ConfigVersion
+	return c, nil
+}
+
+// DefaultConfig opens the config file in the user's config directory.
+func DefaultConfig() (*Config, error) {
+	return OpenConfig(store.ConfigDir())
+}
+
+// Save writes the config file atomically.
+func (c *Config) Save() error {
+	return store.SaveJSON(c.path, c)
+}
+
+// Rules returns the settings that runs record in their replays.
+func (c Config) Rules() Rules {
+	return Rules{Scoring: c.Scoring, ShiftWidth: c.ShiftWidth, KeyTimeout: c.KeyTimeout}
+}
+
+// LineNumbers returns how the buffer's lines are numbered. Like vim, number
+// and relativenumber together are the hybrid mode; lines are numbered
+// absolutely when neither is set.
+func (c Config) LineNumbers() ui.LineNumbers {
+	switch {
+	case c.RelativeNumber && c.Number:
+		return ui.NumberHybrid
+	case c.RelativeNumber:
+		return ui.NumberRelative
+	}
+	return ui.NumberAbsolute
+}
+
+// LoadTheme resolves the theme setting; empty gives def.
+func (c Config) LoadTheme(def ui.Theme) (ui.Theme, error) {
+	if c.Theme == "" {
+		return def, nil
+	}
+	return ui.LoadTheme(c.Theme)
+}
@@ -0,0 +1,25 @@
+package game
+
+import (
+	"embed"
+	"path"
+)
+
+//go:embed content/*.pack
+var builtinContent embed.FS
+
+// builtinPack parses one of the embedded pack files. Built-in content ships
+// with the binary, so a parse or validation failure here is a bug.
+func builtinPack(name string) Pack {
+	file := path.Join("content", name)
+	f, err := builtinContent.Open(file)
+	if err != nil {
+		panic("vimgame: missing built-in pack " + file)
+	}
+	defer f.Close()
+	pack, err := ParsePack(file, f)
+	if err != nil {
+		panic("vimgame: invalid built-in pack:\n" + err.Error())
+	}
+	return pack
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+// DailyDateFormat is the layout used to identify a daily challenge.
+const DailyDateFormat = "2006-01-02"
+
+// dailyMotionBuffers is the pool of buffers a daily challenge navigates:
+// every built-in motion exercise buffer.
+func dailyMotionBuffers() [][]string {
+	var pool [][]string
+	for _, lesson := range AllLessons() {
+		for _, ex := range lesson.Exercises {
+			if ex.Type == ExerciseMotion {
+				pool = append(pool, ex.InitBuffer)
+			}
+		}
+	}
+	for _, level := range AllLevels() {
+		for _, ex := range level.Exercises {
+			if ex.Type == ExerciseMotion {
+				pool = append(pool, ex.InitBuffer)
+			}
+		}
+	}
+	return pool
+}
+
+// DailySeed derives the daily challenge seed from a date.
+func DailySeed(date time.Time) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("vimgame-daily-" + date.Format(DailyDateFormat)))
+	seed := int64(h.Sum64() &^ (1 << 63))
+	if seed == 0 {
+		seed = 1
+	}
+	return seed
+}
+
+// DailyLevel builds the daily challenge for a date. Everyone playing on the
+// same date gets the same buffers, edits and target sequence.
+func DailyLevel(date time.Time) Level {
+	seed := DailySeed(date)
+	rng := rand.New(rand.NewSource(seed))
+
+	pool := dailyMotionBuffers()
+	var edits []Exercise
+	for _, level := range AllLevels() {
+		for _, ex := range level.Exercises {
+			if ex.Type == ExerciseEdit {
+				edits = append(edits, ex)
+			}
+		}
+	}
+
+	exercises := []Exercise{
+		{
+			Type:        ExerciseMotion,
+			Instruction: "Daily challenge — hit every target in as few keys as you can.",
+			InitBuffer:  pool[rng.Intn(len(pool))],
+			StartCursor: Position{0, 0},
+			NumTargets:  10,
+			Difficulty:  4,
+		},
+	}
+	for _, i := range rng.Perm(len(edits))[:2] {
+		exercises = append(exercises, edits[i])
+	}
+
+	return Level{
+		Name:      "Daily Challenge " + date.Format(DailyDateFormat),
+		Exercises: exercises,
+		Commands:  allCommands(),
+		Seed:      seed,
+	}
+}
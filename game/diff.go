@@ -0,0 +1,180 @@
+package game
+
+import "vimgame/ui"
+
+// DiffOp is one kind of step in an edit script.
+type DiffOp int
+
+const (
+	DiffEqual  DiffOp = iota // element present in both sequences
+	DiffDelete               // element only in the old sequence
+	DiffInsert               // element only in the new sequence
+)
+
+// DiffStep is one step of an edit script from sequence a to sequence b.
+// A indexes a for equal and delete steps; B indexes b for equal and insert
+// steps. The unused index is -1.
+type DiffStep struct {
+	Op   DiffOp
+	A, B int
+}
+
+// diffSeq computes a shortest edit script from a to b using a longest common
+// subsequence table. Deletions are emitted before insertions at each point.
+func diffSeq[T comparable](a, b []T) []DiffStep {
+	n, m := len(a), len(b)
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	steps := make([]DiffStep, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			steps = append(steps, DiffStep{DiffEqual, i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			steps = append(steps, DiffStep{DiffDelete, i, -1})
+			i++
+		default:
+			steps = append(steps, DiffStep{DiffInsert, -1, j})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		steps = append(steps, DiffStep{DiffDelete, i, -1})
+	}
+	for ; j < m; j++ {
+		steps = append(steps, DiffStep{DiffInsert, -1, j})
+	}
+	return steps
+}
+
+// DiffChars returns the character edit script from line a to line b.
+func DiffChars(a, b string) []DiffStep {
+	return diffSeq([]byte(a), []byte(b))
+}
+
+// LinePair aligns a line of the old buffer with a line of the new one.
+// A or B is -1 when the line only exists on one side.
+type LinePair struct {
+	A, B int
+}
+
+// AlignLines pairs the lines of a and b. Identical lines are matched by a
+// line-level diff; within each run of changed lines, old and new lines are
+// paired in order and any surplus is left unpaired.
+func AlignLines(a, b []string) []LinePair {
+	var pairs []LinePair
+	var dels, ins []int
+	flush := func() {
+		n := max(len(dels), len(ins))
+		for k := 0; k < n; k++ {
+			p := LinePair{-1, -1}
+			if k < len(dels) {
+				p.A = dels[k]
+			}
+			if k < len(ins) {
+				p.B = ins[k]
+			}
+			pairs = append(pairs, p)
+		}
+		dels, ins = dels[:0], ins[:0]
+	}
+	for _, s := range diffSeq(a, b) {
+		switch s.Op {
+		case DiffEqual:
+			flush()
+			pairs = append(pairs, LinePair{s.A, s.B})
+		case DiffDelete:
+			dels = append(dels, s.A)
+		case DiffInsert:
+			ins = append(ins, s.B)
+		}
+	}
+	flush()
+	return pairs
+}
+
+// GoalDiff is the live difference between the buffer and the goal of an
+// edit exercise.
+type GoalDiff struct {
+	Buffer    ui.DiffMarks // buffer text to delete or change
+	Goal      ui.DiffMarks // goal text the buffer still lacks
+	Remaining int          // separate changes left, as the edit solver counts them
+}
+
+// diffGoal compares buf with goal line by line and, within paired lines,
+// character by character. Remaining counts the changes the way editSites
+// does: each run of changed characters and each unpaired line is one.
+func diffGoal(buf, goal []string) GoalDiff {
+	d := GoalDiff{
+		Buffer: ui.NewDiffMarks(buf),
+		Goal:   ui.NewDiffMarks(goal),
+	}
+	for _, p := range AlignLines(buf, goal) {
+		switch {
+		case p.A >= 0 && p.B >= 0:
+			d.Remaining += markChars(d.Buffer.Chars[p.A], d.Goal.Chars[p.B], buf[p.A], goal[p.B])
+		case p.A >= 0:
+			d.Buffer.Lines[p.A] = true
+			d.Remaining++
+		default:
+			d.Goal.Lines[p.B] = true
+			d.Remaining++
+		}
+	}
+	return d
+}
+
+// markChars marks the characters of a and b that the other line lacks and
+// returns the number of separate changes. A pure insertion or deletion
+// leaves nothing to mark on one side, so the character after where the
+// text is missing is marked as a gap instead.
+func markChars(am, bm []ui.DiffMark, a, b string) int {
+	ai, bi := 0, 0 // positions after the last step in a and b
+	dels, ins := false, false
+	changes := 0
+	flush := func() {
+		if dels || ins {
+			changes++
+		}
+		switch {
+		case dels && !ins:
+			bm[bi] = ui.DiffGap
+		case ins && !dels:
+			am[ai] = ui.DiffGap
+		}
+		dels, ins = false, false
+	}
+	for _, s := range DiffChars(a, b) {
+		switch s.Op {
+		case DiffEqual:
+			flush()
+			ai, bi = s.A+1, s.B+1
+		case DiffDelete:
+			am[s.A] = ui.DiffChanged
+			dels = true
+			ai = s.A + 1
+		case DiffInsert:
+			bm[s.B] = ui.DiffChanged
+			ins = true
+			bi = s.B + 1
+		}
+	}
+	flush()
+	return changes
+}
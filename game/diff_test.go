@@ -0,0 +1,70 @@
+package game
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"vimgame/ui"
+)
+
+func TestAlignLines(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b []string
+		want []LinePair
+	}{
+		{"same", []string{"a", "b"}, []string{"a", "b"}, []LinePair{{0, 0}, {1, 1}}},
+		{"changed line", []string{"a", "b", "c"}, []string{"a", "B", "c"}, []LinePair{{0, 0}, {1, 1}, {2, 2}}},
+		{"added line", []string{"a", "c"}, []string{"a", "b", "c"}, []LinePair{{0, 0}, {-1, 1}, {1, 2}}},
+		{"removed line", []string{"a", "b", "c"}, []string{"a", "c"}, []LinePair{{0, 0}, {1, -1}, {2, 1}}},
+		{"more new than old", []string{"x"}, []string{"y", "z"}, []LinePair{{0, 0}, {-1, 1}}},
+		{"empty goal", []string{"a"}, nil, []LinePair{{0, -1}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := AlignLines(tc.a, tc.b); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("AlignLines = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// marks renders a line's diff marks as "." for the same, "x" for changed
+// and "^" for a gap, one per character plus one past the end.
+func marks(m []ui.DiffMark) string {
+	var sb strings.Builder
+	for _, mk := range m {
+		sb.WriteByte(".x^"[mk])
+	}
+	return sb.String()
+}
+
+func TestMarkChars(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		a, b         string
+		changes      int
+		aMark, bMark string
+	}{
+		{"same", "abc", "abc", 0, "....", "...."},
+		{"changed char", "cat", "car", 1, "..x.", "..x."},
+		{"deleted word", "x bad y", "x y", 1, "..xxxx..", "..^."},
+		{"inserted at end", "foo", "foo;", 1, "...^", "...x."},
+		{"two changes", "abcde", "aXcdY", 2, ".x..x.", ".x..x."},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			am := make([]ui.DiffMark, len(tc.a)+1)
+			bm := make([]ui.DiffMark, len(tc.b)+1)
+			got := markChars(am, bm, tc.a, tc.b)
+			if got != tc.changes {
+				t.Errorf("changes %d, want %d", got, tc.changes)
+			}
+			if sites := len(lineSites(0, tc.a, tc.b)); got != sites {
+				t.Errorf("changes %d, but the edit solver counts %d", got, sites)
+			}
+			if marks(am) != tc.aMark || marks(bm) != tc.bMark {
+				t.Errorf("marks %q / %q, want %q / %q", marks(am), marks(bm), tc.aMark, tc.bMark)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Edit challenge timing.
+const (
+	EditChallengeTime = 90 * time.Second // clock at the start of a run
+	editParKeyTime    = 400 * time.Millisecond
+	editParBaseTime   = 5 * time.Second
+	editTickInterval  = 100 * time.Millisecond
+)
+
+// clockKey is recorded in place of a key when the edit challenge clock runs
+// out between keys, so replays time out at the same point.
+const clockKey = "clock"
+
+// editTickMsg refreshes the edit challenge countdown.
+type editTickMsg struct{}
+
+func editTick() tea.Cmd {
+	return tea.Tick(editTickInterval, func(time.Time) tea.Msg { return editTickMsg{} })
+}
+
+// EditChallengeRun tracks a timed edit challenge: the built-in edit
+// exercises, easiest first, against a countdown. Finishing an exercise
+// faster than its par time banks the difference as bonus time.
+type EditChallengeRun struct {
+	Deadline time.Duration // run time at which the clock runs out
+	ExStart  time.Duration // run time when the current exercise started
+	Par      int           // estimated optimal keys for the current exercise
+	Keys     int           // keys pressed on the current exercise
+	Bonus    time.Duration // bonus time earned on the last exercise
+	Medals   []Medal       // medal per finished exercise
+	TimedOut bool
+}
+
+// Remaining returns the time left on the clock at run time t.
+func (r EditChallengeRun) Remaining(t time.Duration) time.Duration {
+	return max(r.Deadline-t, 0)
+}
+
+// editParTime is the time an exercise is expected to take.
+func editParTime(par int) time.Duration {
+	return editParBaseTime + time.Duration(par)*editParKeyTime
+}
+
+// editChallengeLevel builds the level played by the edit challenge.
+func editChallengeLevel() Level {
+	level := Level{Name: "Edit Challenge", Commands: allCommands()}
+	for _, e := range editsByPar() {
+		level.Exercises = append(level.Exercises, e.ex)
+	}
+	return level
+}
+
+// startEditChallenge sets up the clock for a new run.
+func (m *Model) startEditChallenge() {
+	m.EditRun = EditChallengeRun{Deadline: EditChallengeTime}
+	m.startEditExercise()
+}
+
+// startEditExercise starts the clock and par for the current exercise.
+func (m *Model) startEditExercise() {
+	m.EditRun.ExStart = m.Elapsed
+	m.EditRun.Keys = 0
+	m.EditRun.Par = SolveEdit(m.Buffer.Lines, m.GoalLines, m.Cursor, allCommands()).Par
+}
+
+// editTimeUp ends the run if the clock has run out.
+func (m *Model) editTimeUp() bool {
+	if m.Elapsed < m.EditRun.Deadline {
+		return false
+	}
+	m.EditRun.TimedOut = true
+	m.State = StateGameOver
+	return true
+}
+
+// afterEditChallengeKey grades a finished exercise, banks bonus time and
+// moves straight on to the next exercise so the clock keeps running.
+func (m *Model) afterEditChallengeKey() {
+	m.EditRun.Keys++
+	if m.State != StateExerciseComplete {
+		return
+	}
+	m.LastMedal = parMedal(m.EditRun.Keys, m.EditRun.Par)
+	m.Score += ScoreForMedal(m.LastMedal)
+	m.LevelMedals = append(m.LevelMedals, m.LastMedal)
+	m.EditRun.Medals = append(m.EditRun.Medals, m.LastMedal)
+	taken := m.Elapsed - m.EditRun.ExStart
+	m.EditRun.Bonus = max(editParTime(m.EditRun.Par)-taken, 0)
+	m.EditRun.Deadline += m.EditRun.Bonus
+
+	m.ExIndex++
+	if m.ExIndex >= len(m.Levels[m.LevelIndex].Exercises) {
+		m.ExIndex--
+		m.State = StateLevelComplete
+		return
+	}
+	tip, showTip := m.LastTip, m.ShowTip
+	m.State = StatePlaying
+	m.startChallengeLevel()
+	m.startEditExercise()
+	m.ShowMedal = true
+	m.LastTip, m.ShowTip = tip, showTip
+}
@@ -0,0 +1,258 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// EditPlan is the edit solver's estimate for an edit exercise.
+type EditPlan struct {
+	Par      int      // estimated fewest keystrokes to reach the goal
+	Problems []string // edits that cannot be made with the allowed commands
+}
+
+// Reachable reports whether every edit can be made with the allowed commands.
+func (p EditPlan) Reachable() bool {
+	return len(p.Problems) == 0
+}
+
+// editCaps records which editing commands are allowed.
+type editCaps struct {
+	x, r, i, a, bigA, o, bigO bool
+}
+
+func (c editCaps) insert() bool {
+	return c.i || c.a || c.bigA
+}
+
+func editCapsFor(commands []string) editCaps {
+	var c editCaps
+	for _, cmd := range commands {
+		switch cmd {
+		case "x":
+			c.x = true
+		case "r":
+			c.r = true
+		case "i":
+			c.i = true
+		case "a":
+			c.a = true
+		case "A":
+			c.bigA = true
+		case "o":
+			c.o = true
+		case "O":
+			c.bigO = true
+		}
+	}
+	return c
+}
+
+// editSite is one contiguous change the player has to make.
+type editSite struct {
+	row, col int    // position in the initial buffer
+	del      int    // characters to delete at col
+	ins      string // text to insert at col
+	newLine  bool   // ins is a whole new line below row (above row 0 if row is -1)
+	dropLine bool   // the whole line at row has to go
+}
+
+// SolveEdit estimates the fewest keystrokes needed to turn init into goal
+// starting from start, using only the given commands (a lesson's taught
+// commands or a level's Commands). It diffs the buffers, prices each change
+// with the cheapest allowed method and adds the motion cost between changes
+// as computed by KeystrokeDistances. Positions are measured in the initial
+// buffer, so the par is an estimate rather than a proven optimum.
+func SolveEdit(init, goal []string, start Position, commands []string) EditPlan {
+	caps := editCapsFor(commands)
+	motions := MotionsForCommands(commands)
+	var plan EditPlan
+	problem := func(format string, args ...any) {
+		plan.Problems = append(plan.Problems, fmt.Sprintf(format, args...))
+	}
+
+	sites := editSites(init, goal)
+	cursor := start
+	for _, s := range sites {
+		// Motion cost to the spot where the edit starts.
+		moveTo := func(p Position) (int, bool) {
+			p.Row = min(max(p.Row, 0), len(init)-1)
+			p.Col = min(max(p.Col, 0), max(len(init[p.Row])-1, 0))
+			d := OptimalKeystrokes(init, cursor, p, motions)
+			if d < 0 {
+				problem("line %d col %d cannot be reached with the allowed motions", p.Row+1, p.Col+1)
+				return 0, false
+			}
+			cursor = p
+			return d, true
+		}
+		// Cheapest motion to any column of a line, for A/o/O.
+		moveToLine := func(row int) (int, bool) {
+			row = min(max(row, 0), len(init)-1)
+			dist := KeystrokeDistances(init, cursor, motions)
+			best, bestCol := -1, 0
+			for c, d := range dist[row] {
+				if d >= 0 && (best < 0 || d < best) {
+					best, bestCol = d, c
+				}
+			}
+			if best < 0 {
+				problem("line %d cannot be reached with the allowed motions", row+1)
+				return 0, false
+			}
+			cursor = Position{row, bestCol}
+			return best, true
+		}
+
+		switch {
+		case s.newLine:
+			text := len(s.ins)
+			switch {
+			case s.row >= 0 && caps.o:
+				d, _ := moveToLine(s.row)
+				plan.Par += d + text + 2 // o text ESC
+			case s.row < 0 && caps.bigO:
+				d, _ := moveToLine(0)
+				plan.Par += d + text + 2 // O text ESC
+			case s.row >= 0 && caps.bigA:
+				d, _ := moveToLine(s.row)
+				plan.Par += d + text + 3 // A Enter text ESC
+			case caps.insert():
+				d, _ := moveTo(Position{s.row + 1, 0})
+				plan.Par += d + text + 3 // i text Enter ESC
+			default:
+				problem("line %d: adding a line needs o, O or an insert command", s.row+2)
+			}
+
+		case s.dropLine:
+			if !caps.insert() {
+				problem("line %d: removing a line needs an insert command (backspace joins lines)", s.row+1)
+				continue
+			}
+			n := len(init[s.row])
+			d, _ := moveTo(Position{s.row, 0})
+			cost := n + 3 // i, backspace per char plus one to join, ESC
+			if caps.x && n > 0 {
+				cost = min(cost, countCost(n)+3) // {n}x, then i BS ESC
+			}
+			plan.Par += d + cost
+
+		default:
+			lineLen := len(init[s.row])
+			best := -1
+			var bestPos Position
+			try := func(pos Position, cost int) {
+				if best < 0 || cost < best {
+					best, bestPos = cost, pos
+				}
+			}
+			insertCost := len(s.ins) + 2 // i/a text ESC
+			if s.del > 0 {
+				if caps.x {
+					cost := countCost(s.del)
+					if len(s.ins) > 0 {
+						if caps.i || (caps.bigA && s.col+s.del >= lineLen) {
+							try(Position{s.row, s.col}, cost+insertCost)
+						}
+					} else {
+						try(Position{s.row, s.col}, cost)
+					}
+				}
+				if caps.r && s.del == len(s.ins) {
+					try(Position{s.row, s.col}, 3*s.del-1) // r{c} per char, l between
+				}
+				if caps.a {
+					// a after the last deleted char, backspace over them, type
+					try(Position{s.row, s.col + s.del - 1}, s.del+insertCost)
+				}
+			} else {
+				switch {
+				case s.col < lineLen && caps.i:
+					try(Position{s.row, s.col}, insertCost)
+				case s.col > 0 && caps.a:
+					try(Position{s.row, s.col - 1}, insertCost)
+				}
+				if s.col >= lineLen && caps.bigA {
+					if d, ok := moveToLine(s.row); ok {
+						plan.Par += d + insertCost
+					}
+					continue
+				}
+			}
+			if best < 0 {
+				problem("line %d col %d: %s needs commands that are not allowed", s.row+1, s.col+1, describeSite(s))
+				continue
+			}
+			if d, ok := moveTo(bestPos); ok {
+				plan.Par += d + best
+			}
+		}
+	}
+	return plan
+}
+
+// countCost is the keystrokes for a command repeated n times with a count.
+func countCost(n int) int {
+	if n == 1 {
+		return 1
+	}
+	return len(strconv.Itoa(n)) + 1
+}
+
+func describeSite(s editSite) string {
+	switch {
+	case s.del > 0 && len(s.ins) > 0:
+		return fmt.Sprintf("changing %d characters to %q", s.del, s.ins)
+	case s.del > 0:
+		return fmt.Sprintf("deleting %d characters", s.del)
+	default:
+		return fmt.Sprintf("inserting %q", s.ins)
+	}
+}
+
+// editSites lists the changes between init and goal in buffer order.
+func editSites(init, goal []string) []editSite {
+	var sites []editSite
+	lastRow := -1
+	for _, p := range AlignLines(init, goal) {
+		switch {
+		case p.A >= 0 && p.B >= 0:
+			lastRow = p.A
+			sites = append(sites, lineSites(p.A, init[p.A], goal[p.B])...)
+		case p.A >= 0:
+			lastRow = p.A
+			sites = append(sites, editSite{row: p.A, dropLine: true})
+		default:
+			sites = append(sites, editSite{row: lastRow, ins: goal[p.B], newLine: true})
+		}
+	}
+	return sites
+}
+
+// lineSites groups a character diff into contiguous changes.
+func lineSites(row int, a, b string) []editSite {
+	var sites []editSite
+	var cur *editSite
+	col := 0
+	for _, s := range DiffChars(a, b) {
+		switch s.Op {
+		case DiffEqual:
+			cur = nil
+			col = s.A + 1
+		case DiffDelete:
+			if cur == nil {
+				sites = append(sites, editSite{row: row, col: s.A})
+				cur = &sites[len(sites)-1]
+			}
+			cur.del++
+			col = s.A + 1
+		case DiffInsert:
+			if cur == nil {
+				sites = append(sites, editSite{row: row, col: col})
+				cur = &sites[len(sites)-1]
+			}
+			cur.ins += string(b[s.B])
+		}
+	}
+	return sites
+}
@@ -0,0 +1,42 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSolveEdit(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		init, goal []string
+		commands   string
+		par        int
+		problem    string // substring of the only problem; "" when reachable
+	}{
+		{"x at the cursor", []string{"abc"}, []string{"bc"}, "x", 1, ""},
+		{"counted x after w", []string{"x bad y"}, []string{"x y"}, "w x", 3, ""},
+		{"r after counted l", []string{"cat"}, []string{"car"}, "l r", 4, ""},
+		{"A at the end", []string{"foo"}, []string{"foo;"}, "A", 3, ""},
+		{"o below", []string{"a"}, []string{"a", "b"}, "o", 3, ""},
+		{"drop a line", []string{"a", "bc"}, []string{"a"}, "j i x", 6, ""},
+		{"insert without i", []string{"abc"}, []string{"abXc"}, "l x", 0, `inserting "X" needs commands`},
+		{"new line without o", []string{"a"}, []string{"a", "b"}, "x", 0, "adding a line needs o, O"},
+		{"target out of reach", []string{"abc"}, []string{"ab"}, "x", 0, "cannot be reached with the allowed motions"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := SolveEdit(tc.init, tc.goal, Position{0, 0}, strings.Fields(tc.commands))
+			if tc.problem == "" {
+				if !plan.Reachable() {
+					t.Fatalf("problems %q", plan.Problems)
+				}
+				if plan.Par != tc.par {
+					t.Errorf("par %d, want %d", plan.Par, tc.par)
+				}
+				return
+			}
+			if len(plan.Problems) != 1 || !strings.Contains(plan.Problems[0], tc.problem) {
+				t.Errorf("problems %q, want one containing %q", plan.Problems, tc.problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package game
+
+import (
+	"slices"
+	"sort"
+)
+
+// Endless mode settings.
+const (
+	EndlessLives     = 3
+	endlessTargets   = 5 // targets per motion round
+	endlessEditEvery = 3 // every third round is an edit task
+	endlessMaxDiff   = 8 // cap on the motion difficulty ramp
+)
+
+// EndlessRun tracks the state of an endless run. Rounds alternate between
+// motion targets on a random built-in buffer and edit tasks drawn from the
+// built-in edit exercises, both getting harder as the run goes on. Every
+// target or edit has a keystroke budget; going over it costs a life.
+type EndlessRun struct {
+	Round    int // current round, 1-based
+	Lives    int
+	Budget   int  // keystrokes allowed for the current target or edit
+	Keys     int  // keys pressed on the current edit, typed text included
+	Par      int  // optimal keystrokes for the current target or edit
+	LostLife bool // the last target or edit ran over budget
+
+	buffers [][]string // motion round buffers
+	edits   []parEdit  // edit tasks, easiest first
+}
+
+type parEdit struct {
+	ex  Exercise
+	par int
+}
+
+// editsByPar returns the built-in edit exercises ordered by par.
+func editsByPar() []parEdit {
+	var edits []parEdit
+	add := func(ex Exercise) {
+		if ex.Type == ExerciseEdit {
+			plan := SolveEdit(ex.InitBuffer, ex.GoalBuffer, ex.StartCursor, allCommands())
+			edits = append(edits, parEdit{ex, plan.Par})
+		}
+	}
+	for _, lesson := range AllLessons() {
+		for _, ex := range lesson.Exercises {
+			add(ex)
+		}
+	}
+	for _, level := range AllLevels() {
+		for _, ex := range level.Exercises {
+			add(ex)
+		}
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].par < edits[j].par })
+	return edits
+}
+
+// startEndless sets up a new endless run. The model's RNG must be seeded.
+func (m *Model) startEndless() {
+	m.Endless = EndlessRun{
+		Lives:   EndlessLives,
+		buffers: dailyMotionBuffers(),
+		edits:   editsByPar(),
+	}
+	m.Levels = []Level{{Name: "Endless", Commands: allCommands()}}
+	m.LevelIndex = 0
+	m.addEndlessRound()
+}
+
+// addEndlessRound appends the next round's exercise to the endless level and
+// makes it current.
+func (m *Model) addEndlessRound() {
+	m.Endless.Round++
+	level := m.Levels[0]
+	level.Exercises = append(slices.Clip(level.Exercises), m.endlessExercise())
+	m.Levels = []Level{level}
+	m.ExIndex = len(level.Exercises) - 1
+}
+
+// endlessExercise picks the exercise for the current round.
+func (m *Model) endlessExercise() Exercise {
+	round := m.Endless.Round
+	if edits := m.Endless.edits; round%endlessEditEvery == 0 && len(edits) > 0 {
+		// Draw from the harder half of a window that widens as the run goes on
+		k := min(len(edits), 2+round/endlessEditEvery)
+		return edits[k/2+m.Rng.Intn(k-k/2)].ex
+	}
+	buffers := m.Endless.buffers
+	return Exercise{
+		Type:        ExerciseMotion,
+		Instruction: "Endless — hit each target within the keystroke budget.",
+		InitBuffer:  buffers[m.Rng.Intn(len(buffers))],
+		StartCursor: Position{0, 0},
+		NumTargets:  endlessTargets,
+		Targets:     TargetStrategy(m.Rng.Intn(int(TargetUniqueChar) + 1)),
+		Difficulty:  min(2+(round-1)/2, endlessMaxDiff),
+	}
+}
+
+// endlessSlack is the extra keystrokes allowed over par, shrinking as the
+// run goes on.
+func endlessSlack(round int) int {
+	return max(4-round/5, 1)
+}
+
+// setEndlessBudget computes par and the keystroke budget for the current
+// target or edit.
+func (m *Model) setEndlessBudget() {
+	ex := m.currentExercise()
+	m.Endless.Keys = 0
+	if ex.Type == ExerciseEdit {
+		m.Endless.Par = SolveEdit(m.Buffer.Lines, m.GoalLines, m.Cursor, allCommands()).Par
+		m.Endless.Budget = m.Endless.Par*3/2 + endlessSlack(m.Endless.Round) + 2
+		return
+	}
+	m.Endless.Par = max(OptimalKeystrokes(m.Buffer.Lines, m.Cursor, m.Target, MotionsForCommands(allCommands())), 1)
+	m.Endless.Budget = m.Endless.Par*2 + endlessSlack(m.Endless.Round)
+}
+
+// parMedal grades an edit by the keys pressed against its par.
+func parMedal(keys, par int) Medal {
+	switch {
+	case keys <= par:
+		return MedalDiamond
+	case keys <= par*3/2:
+		return MedalGold
+	case keys <= par*2:
+		return MedalSilver
+	default:
+		return MedalBronze
+	}
+}
+
+// afterEndlessKey advances an endless run after a key was handled in
+// StatePlaying. Motion targets are charged the usual keystroke count; edits
+// are charged every key, typed text included, since that is what their par
+// counts. It moves on to the next round when an exercise is finished,
+// sets a new budget when a target was hit and takes a life when the budget
+// is exceeded.
+func (m *Model) afterEndlessKey(prevTargets int) {
+	ex := m.currentExercise()
+	m.Endless.Keys++
+	used := m.Keystrokes
+	if ex.Type == ExerciseEdit {
+		used = m.Endless.Keys
+	}
+	switch {
+	case m.State == StateExerciseComplete:
+		if ex.Type == ExerciseEdit {
+			m.LastMedal = parMedal(used, m.Endless.Par)
+			m.Score += ScoreForMedal(m.LastMedal)
+			m.LevelMedals = append(m.LevelMedals, m.LastMedal)
+		}
+		m.Endless.LostLife = false
+		m.nextEndlessRound()
+	case m.TargetsHit != prevTargets:
+		m.Endless.LostLife = false
+		m.setEndlessBudget()
+	case used > m.Endless.Budget:
+		m.Endless.Lives--
+		m.Endless.LostLife = true
+		m.ShowMedal = false
+		if m.Endless.Lives <= 0 {
+			m.State = StateGameOver
+			return
+		}
+		if ex.Type == ExerciseEdit {
+			m.nextEndlessRound()
+			return
+		}
+		m.Keystrokes = 0
+		m.StartPos = m.Cursor
+		m.Target = m.nextTarget(ex)
+		m.setEndlessBudget()
+	}
+}
+
+// nextEndlessRound starts the next round, keeping the last medal and tip on
+// screen.
+func (m *Model) nextEndlessRound() {
+	medal, showMedal := m.LastMedal, m.State == StateExerciseComplete
+	tip, showTip := m.LastTip, m.ShowTip
+	m.addEndlessRound()
+	m.State = StatePlaying
+	m.startChallengeLevel()
+	m.setEndlessBudget()
+	m.LastMedal, m.ShowMedal = medal, showMedal
+	m.LastTip, m.ShowTip = tip, showTip
+}
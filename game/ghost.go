@@ -0,0 +1,211 @@
+package game
+
+import (
+	"errors"
+	"time"
+
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ghostTickInterval is how often the ghost cursor moves between keys.
+const ghostTickInterval = 50 * time.Millisecond
+
+// ghostTickMsg moves the ghost to the current run time.
+type ghostTickMsg struct{}
+
+func ghostTick() tea.Cmd {
+	return tea.Tick(ghostTickInterval, func(time.Time) tea.Msg { return ghostTickMsg{} })
+}
+
+// ghostReplayMsg carries a replay fetched from the team server to race.
+type ghostReplayMsg struct {
+	name   string
+	replay Replay
+	err    error
+}
+
+// ghostFrame is where a replayed run stood from a moment on.
+type ghostFrame struct {
+	At       time.Duration
+	Level    int
+	Exercise int
+	Row, Col int
+	Done     int  // targets and edits finished in the level
+	Finished bool // the level is complete
+}
+
+// GhostRun is a previous run replayed alongside the live one. The seed is
+// the same, so the ghost faces the same targets; its frames are worked out
+// up front by simulating the replay.
+type GhostRun struct {
+	Name   string        // whose run it is
+	Frames []ghostFrame  // in time order
+	Now    time.Duration // run time the ghost has been moved to
+	Delta  time.Duration // live minus ghost time at the last target; negative is ahead
+	Timed  bool          // Delta has been measured
+	Outrun bool          // the live run got further than the ghost ever did
+}
+
+// ghostFrames simulates a replay on the same packs as the model and records
+// every change of position or progress.
+func (m Model) ghostFrames(r Replay) ([]ghostFrame, error) {
+	m, err := NewReplayModel(r, m.packs...)
+	if err != nil {
+		return nil, err
+	}
+	frames := []ghostFrame{m.ghostFrame(0)}
+	for _, k := range r.Keys {
+		at := time.Duration(k.At) * time.Millisecond
+		next, _ := m.Update(ReplayKeyMsg{Key: k.Key, At: at})
+		m = next.(Model)
+		if m.GameMode == GameModeTutorial || m.State == StateMenu || m.State == StateGameOver {
+			break
+		}
+		f := m.ghostFrame(at)
+		if last := frames[len(frames)-1]; f.Level != last.Level || f.Exercise != last.Exercise ||
+			f.Row != last.Row || f.Col != last.Col || f.Done != last.Done || f.Finished != last.Finished {
+			frames = append(frames, f)
+		}
+	}
+	return frames, nil
+}
+
+func (m Model) ghostFrame(at time.Duration) ghostFrame {
+	p := m.raceProgress()
+	return ghostFrame{At: at, Level: m.LevelIndex, Exercise: p.Exercise, Row: p.Row, Col: p.Col, Done: p.Done, Finished: p.Finished}
+}
+
+// StartGhost starts a run on the replay's level and seed with the replayed
+// run as a ghost. name is whose run it was.
+func (m *Model) StartGhost(r Replay, name string) error {
+	if r.Mode == GameModeTutorial {
+		return errors.New("ghosts race challenge runs, not tutorial lessons")
+	}
+	if r.Mode == GameModeRace {
+		r.Mode = GameModeMotionChallenge
+	}
+	frames, err := m.ghostFrames(r)
+	if err != nil {
+		return err
+	}
+	if err := m.replayRun(r); err != nil {
+		return err
+	}
+	m.startLevelTracking()
+	m.Ghost = &GhostRun{Name: name, Frames: frames}
+	return nil
+}
+
+// at returns the ghost's frame at run time t.
+func (g *GhostRun) at(t time.Duration) ghostFrame {
+	i := 0
+	for i+1 < len(g.Frames) && g.Frames[i+1].At <= t {
+		i++
+	}
+	return g.Frames[i]
+}
+
+// reached returns when the ghost finished done targets and edits of a
+// level, and false if it never did.
+func (g *GhostRun) reached(level, done int) (time.Duration, bool) {
+	for _, f := range g.Frames {
+		if f.Level > level || (f.Level == level && f.Done >= done) {
+			return f.At, true
+		}
+	}
+	return 0, false
+}
+
+// afterGhostKey times the live run against the ghost when a key finished
+// a target or edit.
+func (m *Model) afterGhostKey(prev Model) {
+	g := m.Ghost
+	if g == nil || m.State == StateMenu || m.State == StateGameOver {
+		return
+	}
+	g.Now = m.Elapsed
+	done := m.raceProgress().Done
+	if m.LevelIndex != prev.LevelIndex || done <= prev.raceProgress().Done {
+		return
+	}
+	at, ok := g.reached(m.LevelIndex, done)
+	g.Outrun = !ok
+	g.Timed = ok
+	g.Delta = m.Elapsed - at
+}
+
+// ghostMarkers returns the ghost's cursor if it is on the live exercise.
+func (m Model) ghostMarkers() []ui.Marker {
+	if m.Ghost == nil {
+		return nil
+	}
+	f := m.Ghost.at(m.Ghost.Now)
+	if f.Finished || f.Level != m.LevelIndex || f.Exercise != m.ExIndex {
+		return nil
+	}
+	return []ui.Marker{{Row: f.Row, Col: f.Col}}
+}
+
+// raceGhost loads the highlighted leaderboard entry's replay and races it.
+func (m Model) raceGhost() (tea.Model, tea.Cmd) {
+	if len(m.BoardKeys) == 0 {
+		return m, nil
+	}
+	key := m.BoardKeys[m.BoardCursor]
+	board := m.shownBoard(key)
+	if m.BoardRow >= len(board) {
+		return m, nil
+	}
+	e := board[m.BoardRow]
+	if m.ShowTeam {
+		team, rank := m.Team, m.BoardRow
+		m.GhostErr = nil
+		return m, func() tea.Msg {
+			r, err := team.Replay(key, rank)
+			return ghostReplayMsg{e.Name, r, err}
+		}
+	}
+	path := m.Leaderboards.ReplayPath(e)
+	if path == "" {
+		m.GhostErr = errors.New("no replay saved for this entry")
+		return m, nil
+	}
+	r, err := LoadReplay(path)
+	if err == nil {
+		err = m.StartGhost(r, e.Name)
+	}
+	if err != nil {
+		m.GhostErr = err
+		return m, nil
+	}
+	return m, m.ghostStarted()
+}
+
+// ghostFetched races a replay fetched from the team server.
+func (m Model) ghostFetched(msg ghostReplayMsg) (tea.Model, tea.Cmd) {
+	err := msg.err
+	if err == nil && m.State == StateLeaderboard {
+		err = m.StartGhost(msg.replay, msg.name)
+	}
+	if err != nil {
+		m.GhostErr = err
+		return m, nil
+	}
+	return m, m.ghostStarted()
+}
+
+// ghostStarted returns the commands a run with a ghost needs.
+func (m Model) ghostStarted() tea.Cmd {
+	if m.GameMode == GameModeEditChallenge {
+		return tea.Batch(ghostTick(), editTick())
+	}
+	return ghostTick()
+}
+
+// ghostStatus renders the HUD line comparing the run with the ghost.
+func (m Model) ghostStatus() string {
+	g := m.Ghost
+	return ui.RenderGhostStatus(g.Name, g.Delta, g.Timed, g.Outrun)
+}
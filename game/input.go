@@ -1,6 +1,9 @@
 package game
 
-import "unicode"
+import (
+	"strconv"
+	"unicode"
+)
 
 // Motion represents a parsed vim motion.
 type Motion int
@@ -70,6 +73,16 @@ func (p *InputParser) feedInsert(key string) ParseResult {
 		return ParseResult{Action: ActionInsertNewline, Consumed: true}
 	case "backspace":
 		return ParseResult{Action: ActionInsertBackspace, Consumed: true}
+	case "tab":
+		return ParseResult{Action: ActionInsertTab, Consumed: true}
+	case "left":
+		return ParseResult{Action: ActionInsertArrow, Motion: MotionH, Consumed: true}
+	case "right":
+		return ParseResult{Action: ActionInsertArrow, Motion: MotionL, Consumed: true}
+	case "up":
+		return ParseResult{Action: ActionInsertArrow, Motion: MotionK, Consumed: true}
+	case "down":
+		return ParseResult{Action: ActionInsertArrow, Motion: MotionJ, Consumed: true}
 	}
 	// Single printable character
 	if len(key) == 1 {
@@ -218,6 +231,26 @@ func (p *InputParser) feedNormal(key string) ParseResult {
 	return ParseResult{}
 }
 
+// Pending returns the keys of a command still being typed, such as "3" or
+// "g", or "" when there is none.
+func (p *InputParser) Pending() string {
+	keys := ""
+	if p.Count > 0 {
+		keys = strconv.Itoa(p.Count)
+	}
+	switch p.State {
+	case InputPendingG:
+		keys += "g"
+	case InputPendingF:
+		keys += "f"
+	case InputPendingBigF:
+		keys += "F"
+	case InputPendingR:
+		keys += "r"
+	}
+	return keys
+}
+
 // Reset clears any pending input state.
 func (p *InputParser) Reset() {
 	p.State = InputReady
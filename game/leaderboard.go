@@ -0,0 +1,483 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vimgame/store"
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// LeaderboardVersion is the current leaderboard file schema version.
+const LeaderboardVersion = 1
+
+// LeaderboardSize is how many entries each board keeps.
+const LeaderboardSize = 10
+
+// Leaderboard board keys are "<kind>:<name>":
+//
+//	level:<level name>         every run of a level, any seed
+//	seed:<level name>:<seed>   runs of a level with the same fixed seed
+//	daily:<date>               the daily challenge for a date
+const (
+	boardLevel = "level"
+	boardSeed  = "seed"
+	boardDaily = "daily"
+)
+
+// LeaderboardEntry is one ranked result.
+type LeaderboardEntry struct {
+	Name       string        `json:"name"`
+	Score      int           `json:"score"`
+	Keystrokes int           `json:"keystrokes"`
+	Time       time.Duration `json:"time"`
+	Date       time.Time     `json:"date"`
+	Seed       int64         `json:"seed"`
+	Replay     string        `json:"replay,omitempty"` // file in the replay directory
+}
+
+// better ranks entries: higher score, then faster, then fewer keys.
+func (e LeaderboardEntry) better(o LeaderboardEntry) bool {
+	if e.Score != o.Score {
+		return e.Score > o.Score
+	}
+	if e.Time != o.Time {
+		return e.Time < o.Time
+	}
+	return e.Keystrokes < o.Keystrokes
+}
+
+// Leaderboards is the local leaderboard store: one JSON file holding every
+// board, with each ranked run's replay kept in a directory next to it. It is
+// safe for concurrent use, so games served to several players can share it.
+type Leaderboards struct {
+	Version int                           `json:"version"`
+	Boards  map[string][]LeaderboardEntry `json:"boards"`
+
+	mu        sync.Mutex
+	path      string
+	replayDir string
+}
+
+// SchemaVersion implements store.Versioned.
+func (l *Leaderboards) SchemaVersion() int { return l.Version }
+
+// OpenLeaderboards loads the leaderboards under a data directory. A missing
+// file gives empty boards.
+func OpenLeaderboards(dataDir string) (*Leaderboards, error) {
+	l := &Leaderboards{
+		Version:   LeaderboardVersion,
+		Boards:    make(map[string][]LeaderboardEntry),
+		path:      filepath.Join(dataDir, "leaderboards.json"),
+		replayDir: filepath.Join(dataDir, "replays"),
+	}
+	err := store.LoadJSON(l.path, l)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := store.CheckVersion(l.path, l, LeaderboardVersion); err != nil {
+		return nil, err
+	}
+	if l.Boards == nil {
+		l.Boards = make(map[string][]LeaderboardEntry)
+	}
+	l.Version = LeaderboardVersion
+	return l, nil
+}
+
+// DefaultLeaderboards opens the leaderboards in the user's data directory.
+func DefaultLeaderboards() (*Leaderboards, error) {
+	return OpenLeaderboards(store.DataDir())
+}
+
+// Save writes the leaderboards atomically.
+func (l *Leaderboards) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return store.SaveJSON(l.path, l)
+}
+
+// Keys returns the keys of every board, sorted by kind and name.
+func (l *Leaderboards) Keys() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	keys := make([]string, 0, len(l.Boards))
+	for k := range l.Boards {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Board returns a board's entries, best first.
+func (l *Leaderboards) Board(key string) []LeaderboardEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.Boards[key])
+}
+
+// ReplayPath returns the replay file of an entry, or "" if it has none.
+func (l *Leaderboards) ReplayPath(e LeaderboardEntry) string {
+	if e.Replay == "" {
+		return ""
+	}
+	return filepath.Join(l.replayDir, e.Replay)
+}
+
+// Rank returns where an entry would place on a board, or -1 if it would
+// not. It reports false, with the existing entry's rank, if the same run is
+// already on the board.
+func (l *Leaderboards) Rank(key string, e LeaderboardEntry) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	board := l.Boards[key]
+	for i, o := range board {
+		if o.Name == e.Name && o.Score == e.Score && o.Keystrokes == e.Keystrokes && o.Time == e.Time && o.Seed == e.Seed {
+			return i, false
+		}
+	}
+	rank := sort.Search(len(board), func(i int) bool { return e.better(board[i]) })
+	if rank >= LeaderboardSize {
+		return -1, true
+	}
+	return rank, true
+}
+
+// Add ranks an entry on a board and returns its 0-based rank, or -1 if it
+// did not make the board. A ranked entry's replay is saved with it and the
+// replay of an entry pushed off the board is deleted. The caller saves the
+// leaderboards.
+func (l *Leaderboards) Add(key string, e LeaderboardEntry, r Replay) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	board := l.Boards[key]
+	rank := sort.Search(len(board), func(i int) bool { return e.better(board[i]) })
+	if rank >= LeaderboardSize {
+		return -1, nil
+	}
+
+	e.Replay = fmt.Sprintf("%s-%s.json", e.Date.Format("20060102-150405.000"), profileSlug(key))
+	if err := os.MkdirAll(l.replayDir, 0o755); err != nil {
+		return -1, err
+	}
+	if err := SaveReplay(l.ReplayPath(e), r); err != nil {
+		return -1, err
+	}
+
+	board = slices.Insert(board, rank, e)
+	if len(board) > LeaderboardSize {
+		for _, dropped := range board[LeaderboardSize:] {
+			if path := l.ReplayPath(dropped); path != "" {
+				os.Remove(path)
+			}
+		}
+		board = board[:LeaderboardSize]
+	}
+	l.Boards[key] = board
+	return rank, nil
+}
+
+// BoardTitle describes a board key for display.
+func BoardTitle(key string) string {
+	kind, name, _ := strings.Cut(key, ":")
+	switch kind {
+	case boardDaily:
+		return "Daily Challenge " + name
+	case boardSeed:
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			return name[:i] + " — seed " + name[i+1:]
+		}
+	}
+	return name
+}
+
+// --- Model integration ---
+
+// leaderboardKeys returns the boards the current level's result goes on, or
+// nil if it is not ranked. Tutorial lessons and reviews are practice and
+// are not ranked.
+func (m Model) leaderboardKeys() []string {
+	if m.GameMode == GameModeTutorial || m.GameMode == GameModeReview || m.GameMode == GameModeRace {
+		return nil
+	}
+	if m.Rules.Scoring != ScoringThresholds {
+		return nil // scores are not comparable with everyone else's
+	}
+	if m.Daily != "" {
+		return []string{boardDaily + ":" + m.Daily}
+	}
+	name := m.Levels[m.LevelIndex].Name
+	keys := []string{boardLevel + ":" + name}
+	if m.FixedSeed != 0 {
+		keys = append(keys, boardSeed+":"+name+":"+strconv.FormatInt(m.Seed, 10))
+	}
+	return keys
+}
+
+// LevelResult is a level result to be ranked, with the boards it goes on
+// and the replay of the level.
+type LevelResult struct {
+	Boards []string
+	Entry  LeaderboardEntry
+	Replay Replay
+}
+
+// levelResult returns the result of the level just played. Motion challenge
+// levels are ranked when completed; endless runs and edit challenges also
+// when they end early.
+func (m Model) levelResult(completed bool, score int, elapsed time.Duration) (LevelResult, bool) {
+	keys := m.leaderboardKeys()
+	if len(keys) == 0 || (!completed && m.GameMode == GameModeMotionChallenge) {
+		return LevelResult{}, false
+	}
+	name := "player"
+	if m.Profile != nil {
+		name = m.Profile.Name
+	}
+	return LevelResult{
+		Boards: keys,
+		Entry: LeaderboardEntry{
+			Name:       name,
+			Score:      score,
+			Keystrokes: m.LevelKeystrokes,
+			Time:       elapsed,
+			Date:       time.Now(),
+			Seed:       m.Seed,
+		},
+		Replay: m.levelReplay(),
+	}, true
+}
+
+// recordLeaderboard ranks the level just played on its boards.
+func (m *Model) recordLeaderboard(completed bool, score int, elapsed time.Duration) {
+	m.LastBoard, m.LastRank = "", -1
+	res, ok := m.levelResult(completed, score, elapsed)
+	if !ok {
+		return
+	}
+	if m.verified != nil {
+		*m.verified = append(*m.verified, res)
+	}
+	if m.Team != nil && m.Playback == nil {
+		if res.Replay.ClientTimed() {
+			m.TeamMsg = "Team board: timed runs are only ranked locally"
+		} else {
+			m.teamPending = true
+			m.TeamMsg = "Team board: submitting…"
+		}
+	}
+	if m.Leaderboards == nil {
+		return
+	}
+	for i, key := range res.Boards {
+		rank, err := m.Leaderboards.Add(key, res.Entry, res.Replay)
+		if err != nil {
+			m.BoardErr = err
+			return
+		}
+		if i == 0 {
+			m.LastBoard, m.LastRank = key, rank
+		}
+	}
+	m.BoardErr = m.Leaderboards.Save()
+}
+
+// boardRows converts a board for ui.RenderLeaderboard.
+func boardRows(board []LeaderboardEntry) []ui.LeaderboardRow {
+	rows := make([]ui.LeaderboardRow, len(board))
+	for i, e := range board {
+		rows[i] = ui.LeaderboardRow{Rank: i + 1, Name: e.Name, Score: e.Score, Keystrokes: e.Keystrokes, Time: e.Time, Date: e.Date}
+	}
+	return rows
+}
+
+// lastBoardRows is how many entries the level complete and game over
+// screens show.
+const lastBoardRows = 5
+
+// renderLastBoard renders the board the last level was ranked on, with the
+// player's entry highlighted, for the level complete and game over screens.
+// Only the entries around the player's are shown.
+func (m Model) renderLastBoard() string {
+	if m.Leaderboards == nil || m.LastBoard == "" {
+		return ""
+	}
+	var sb strings.Builder
+	if m.LastRank >= 0 {
+		sb.WriteString(fmt.Sprintf("New #%d on the leaderboard!\n\n", m.LastRank+1))
+	}
+	rows := boardRows(m.Leaderboards.Board(m.LastBoard))
+	first := min(max(m.LastRank-lastBoardRows/2, 0), max(len(rows)-lastBoardRows, 0))
+	rows = rows[first:min(first+lastBoardRows, len(rows))]
+	highlight := -1
+	if m.LastRank >= 0 {
+		highlight = m.LastRank - first
+	}
+	sb.WriteString(ui.RenderLeaderboard(BoardTitle(m.LastBoard), rows, highlight))
+	if m.TeamMsg != "" {
+		sb.WriteString("\n\n" + m.TeamMsg)
+	}
+	return sb.String()
+}
+
+// OpenLeaderboard shows the local leaderboards, starting at the board the
+// last level was ranked on.
+func (m *Model) OpenLeaderboard() {
+	m.State = StateLeaderboard
+	m.ShowTeam = false
+	m.BoardKeys = m.Leaderboards.Keys()
+	m.BoardCursor, m.BoardRow = 0, 0
+	if i := slices.Index(m.BoardKeys, m.LastBoard); i >= 0 {
+		m.BoardCursor, m.BoardRow = i, max(m.LastRank, 0)
+	}
+}
+
+// teamFetched shows boards fetched from the team server.
+func (m *Model) teamFetched(msg teamBoardsMsg) {
+	if !m.ShowTeam {
+		return
+	}
+	if msg.err != nil {
+		m.TeamMsg = "Team board: " + msg.err.Error()
+		return
+	}
+	m.TeamMsg = ""
+	m.TeamBoards = msg.boards
+	m.BoardKeys = m.BoardKeys[:0]
+	for k := range msg.boards {
+		m.BoardKeys = append(m.BoardKeys, k)
+	}
+	sort.Strings(m.BoardKeys)
+	m.BoardCursor, m.BoardRow = 0, 0
+}
+
+// shownBoard returns a board from the local or team leaderboards, whichever
+// the screen shows.
+func (m Model) shownBoard(key string) []LeaderboardEntry {
+	if m.ShowTeam {
+		return m.TeamBoards[key]
+	}
+	return m.Leaderboards.Board(key)
+}
+
+func (m Model) handleLeaderboardInput(key string) (tea.Model, tea.Cmd) {
+	rows := 0
+	if len(m.BoardKeys) > 0 {
+		rows = len(m.shownBoard(m.BoardKeys[m.BoardCursor]))
+	}
+	switch key {
+	case "t":
+		if m.Team == nil {
+			break
+		}
+		if m.ShowTeam {
+			m.OpenLeaderboard()
+			break
+		}
+		m.ShowTeam = true
+		m.TeamBoards = nil
+		m.BoardKeys = nil
+		m.BoardCursor, m.BoardRow = 0, 0
+		m.TeamMsg = "Loading team boards…"
+		return m, m.fetchTeamBoards()
+	case "esc", "enter":
+		m.State = StateMenu
+	case "g":
+		return m.raceGhost()
+	case "l", "right", "tab":
+		if m.BoardCursor < len(m.BoardKeys)-1 {
+			m.BoardCursor++
+			m.BoardRow = 0
+		}
+	case "h", "left", "shift+tab":
+		if m.BoardCursor > 0 {
+			m.BoardCursor--
+			m.BoardRow = 0
+		}
+	case "j", "down":
+		if m.BoardRow < rows-1 {
+			m.BoardRow++
+		}
+	case "k", "up":
+		if m.BoardRow > 0 {
+			m.BoardRow--
+		}
+	}
+	return m, nil
+}
+
+func (m Model) viewLeaderboard() string {
+	th := ui.CurrentTheme()
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(th.Title).
+		Padding(1, 2)
+
+	infoStyle := lipgloss.NewStyle().
+		Foreground(th.Dim)
+
+	help := "  h/l: board  •  j/k: entry  •  ESC: back"
+	title := "Leaderboards"
+	if m.Team != nil {
+		help = "  h/l: board  •  j/k: entry  •  t: team boards  •  ESC: back"
+		if m.ShowTeam {
+			title = "Team Leaderboards"
+			help = "  h/l: board  •  j/k: entry  •  t: local boards  •  ESC: back"
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render(title))
+	sb.WriteString("\n\n")
+	if len(m.BoardKeys) == 0 {
+		switch {
+		case m.ShowTeam && m.TeamMsg != "":
+			sb.WriteString(infoStyle.Render("  "+m.TeamMsg) + "\n\n")
+		case m.ShowTeam:
+			sb.WriteString(infoStyle.Render("  No runs submitted to the team yet.") + "\n\n")
+		default:
+			sb.WriteString(infoStyle.Render("  No ranked runs yet. Finish a challenge level to get on the board.") + "\n\n")
+		}
+		sb.WriteString(infoStyle.Render(help) + "\n")
+		return sb.String()
+	}
+
+	key := m.BoardKeys[m.BoardCursor]
+	board := m.shownBoard(key)
+	boardTitle := fmt.Sprintf("%s  (%d/%d)", BoardTitle(key), m.BoardCursor+1, len(m.BoardKeys))
+	table := ui.RenderLeaderboard(boardTitle, boardRows(board), m.BoardRow)
+	sb.WriteString(lipgloss.NewStyle().PaddingLeft(2).Render(table) + "\n\n")
+
+	if m.BoardRow < len(board) {
+		watch := m.Leaderboards.ReplayPath(board[m.BoardRow])
+		if m.ShowTeam {
+			watch = m.Team.ReplayURL(key, m.BoardRow)
+		}
+		if watch != "" {
+			sb.WriteString(infoStyle.Render("  Watch: vimgame replay "+watch+"  •  g: race its ghost") + "\n")
+		}
+	}
+	if m.GhostErr != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(th.Bad).Render("  Could not race the ghost: "+m.GhostErr.Error()) + "\n")
+	}
+	sb.WriteString(infoStyle.Render(help) + "\n")
+	if m.BoardErr != nil {
+		sb.WriteString("\n" + lipgloss.NewStyle().Foreground(th.Bad).Render("  Could not save leaderboards: "+m.BoardErr.Error()) + "\n")
+	}
+	return sb.String()
+}
@@ -27,6 +27,8 @@ const (
 	ActionInsertChar          // typing in insert mode
 	ActionInsertNewline       // Enter in insert mode
 	ActionInsertBackspace     // Backspace in insert mode
+	ActionInsertArrow         // arrow key in insert mode
+	ActionInsertTab           // Tab in insert mode, indents to the next shiftwidth stop
 )
 
 // GameModeType distinguishes between tutorial and challenge gameplay.
@@ -35,5 +37,8 @@ type GameModeType int
 const (
 	GameModeTutorial        GameModeType = iota
 	GameModeMotionChallenge              // existing motion-target game
-	GameModeEditChallenge                // future: timed editing challenges
+	GameModeEditChallenge                // timed editing challenges
+	GameModeEndless                      // endless run until out of lives
+	GameModeReview                       // spaced-repetition review of learned commands
+	GameModeRace                         // head-to-head race over the network
 )
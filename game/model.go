@@ -2,8 +2,15 @@ package game
 
 import (
 	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
+	"vimgame/race"
 	"vimgame/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -21,6 +28,11 @@ const (
 	StateExerciseComplete                   // single exercise done
 	StateLevelComplete                      // level/lesson complete
 	StateGameOver
+	StateProfileMenu // choose, create, rename or delete a profile
+	StateStats       // per-command stats for the current profile
+	StateLeaderboard // local leaderboards
+	StateRaceLobby   // waiting for a race to start
+	StateSettings    // the settings screen
 )
 
 // Model is the main Bubble Tea model.
@@ -32,10 +44,15 @@ type Model struct {
 	Lessons     []Lesson
 	LessonIndex int
 	ExIndex     int // exercise index within current lesson
+	MenuCursor  int // highlighted lesson in the tutorial menu
 
 	// Challenge fields (existing motion-target game)
-	Levels     []Level
-	LevelIndex int
+	Levels       []Level // levels of the current run
+	LevelIndex   int
+	LevelCatalog []Level         // built-in and pack levels played by Challenges
+	packs        []Pack          // packs added to the built-in content, for replaying runs
+	PackErr      error           // problems in pack files that were skipped
+	highlight    *ui.Highlighter // Buffer's syntax highlighting, cached by row
 
 	// Buffer and cursor
 	Buffer     Buffer
@@ -44,6 +61,7 @@ type Model struct {
 	Target     Position
 	StartPos   Position // cursor position when target was generated
 	GoalLines  []string // target buffer state for editing exercises
+	Diff       GoalDiff // differences between the buffer and GoalLines
 
 	// Vim mode
 	VimMode VimMode
@@ -56,8 +74,84 @@ type Model struct {
 	LastMedal  Medal
 	ShowMedal  bool
 
+	// Coaching
+	Coach   Coach
+	LastTip Tip
+	ShowTip bool
+
 	// Input
-	Parser InputParser
+	Parser    InputParser
+	lastKeyMs int64 // run time of the previous key, for Rules.KeyTimeout
+
+	// Run identity and recording
+	Seed      int64
+	FixedSeed int64            // if non-zero, every run uses this seed
+	Daily     string           // date of the daily challenge being played, if any
+	Practice  PracticeSpec     // practice file being played, if any
+	Endless   EndlessRun       // endless mode run state
+	EditRun   EditChallengeRun // timed edit challenge state
+	Review    ReviewRun        // review session state
+	Rng       *rand.Rand       // model-owned RNG, seeded per run
+	rngSource *runSource       // Rng's source
+	rngStart  int64            // random values drawn before the current level started
+	RunStart  time.Time
+	Elapsed   time.Duration // run time at the most recent key
+	Replay    Replay        // key stream of the current or most recent run
+	Recording bool
+	Playback  *Playback // non-nil when playing back a replay
+
+	// Profiles (nil when progress is not being saved)
+	Profiles        *ProfileStore
+	Profile         *Profile
+	ProfileErr      error    // last error saving the profile
+	ProfileNames    []string // profile menu entries
+	ProfileCursor   int      // highlighted profile menu entry
+	ProfileEdit     ProfileEditMode
+	ProfileInput    string        // text typed at the profile menu prompt
+	ProfileMsg      string        // result or error shown in the profile menu
+	LevelMedals     []Medal       // medals earned in the current lesson or level
+	LevelScoreStart int           // Score when the current lesson or level started
+	LevelTimeStart  time.Duration // Elapsed when the current lesson or level started
+	LevelKeyStart   int           // replay keys recorded when the current lesson or level started
+	LevelKeystrokes int           // keystrokes counted in the current lesson or level
+
+	// Leaderboards (nil when results are not being ranked)
+	Leaderboards *Leaderboards
+	BoardErr     error  // last error saving the leaderboards
+	LastBoard    string // board the last level was ranked on
+	LastRank     int    // rank on LastBoard, -1 if it did not place
+	BoardKeys    []string
+	BoardCursor  int            // board shown on the leaderboard screen
+	BoardRow     int            // highlighted entry on the leaderboard screen
+	verified     *[]LevelResult // collects level results when verifying a replay
+
+	// Team leaderboard server (nil when not configured)
+	Team        TeamBoard
+	TeamMsg     string                        // result of the last submission or fetch
+	TeamBoards  map[string][]LeaderboardEntry // boards fetched for the leaderboard screen
+	ShowTeam    bool                          // leaderboard screen shows the team boards
+	teamPending bool                          // a ranked run is waiting to be submitted
+
+	// Ghost of a previous run raced alongside this one (nil when none)
+	Ghost    *GhostRun
+	GhostErr error // why the last ghost could not be raced
+
+	// Race (nil RaceConn when not racing)
+	RaceConn race.Session
+	RaceHost *race.Host // non-nil when hosting
+	Race     RaceRun
+
+	// Settings (nil Config when they are not saved)
+	Config         *Config   // settings in effect: the profile's, or else machineConfig
+	machineConfig  *Config   // the config file, for profiles without settings of their own
+	keepTheme      bool      // a theme was chosen outside the settings
+	ConfigErr      error     // last error applying or saving the settings
+	SettingsCursor int       // highlighted entry on the settings screen
+	Rules          Rules     // rules of the current run
+	ScrollTop      int       // first buffer line in view
+	Bell           io.Writer // where the terminal bell rings; nil for silence
+	Theme          *ui.Theme // the model's own theme (see ui.Paint); nil restyles the ui package
+	NoColor        bool      // the terminal asked for no color, so the default theme is mono
 
 	// Terminal dimensions
 	Width  int
@@ -69,14 +163,38 @@ type Model struct {
 
 // NewModel creates a new game model.
 func NewModel() Model {
+	levels := AllLevels()
 	return Model{
-		State:   StateMenu,
-		Levels:  AllLevels(),
-		Lessons: AllLessons(),
+		State:        StateMenu,
+		Levels:       levels,
+		LevelCatalog: levels,
+		Lessons:      AllLessons(),
+		Rules:        NewConfig().Rules(),
+		highlight:    ui.NewHighlighter(),
+	}
+}
+
+// AddPack appends a pack's lessons and levels to the built-in content.
+func (m *Model) AddPack(p Pack) {
+	for _, lesson := range p.Lessons {
+		lesson.Number = len(m.Lessons) + 1
+		m.Lessons = append(m.Lessons, lesson)
 	}
+	m.LevelCatalog = append(m.LevelCatalog, p.Levels...)
+	m.Levels = m.LevelCatalog
+	m.packs = append(m.packs, p)
 }
 
 func (m Model) Init() tea.Cmd {
+	if m.Playback != nil {
+		return m.Playback.tick()
+	}
+	if m.RaceConn != nil {
+		return waitRace(m.RaceConn)
+	}
+	if m.Ghost != nil {
+		return m.ghostStarted()
+	}
 	return nil
 }
 
@@ -89,87 +207,229 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		key := msg.String()
+		if m.Playback != nil {
+			// Playback ignores the keyboard apart from quitting
+			if key == "ctrl+c" || key == "q" {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		m.Elapsed = time.Since(m.RunStart)
+		return m.handleKey(key)
+
+	case ReplayKeyMsg:
+		m.Elapsed = msg.At
+		return m.handleKey(msg.Key)
+
+	case replayTickMsg:
+		if m.Playback == nil || m.Playback.Done() {
+			return m, nil
+		}
+		k := m.Playback.Replay.Keys[m.Playback.Next]
+		m.Playback.Next++
+		m.Elapsed = time.Duration(k.At) * time.Millisecond
+		next, cmd := m.handleKey(k.Key)
+		return next, tea.Batch(cmd, m.Playback.tick())
+
+	case teamSubmitMsg:
+		m.teamSubmitted(msg)
+		return m, nil
+
+	case teamBoardsMsg:
+		m.teamFetched(msg)
+		return m, nil
+
+	case ghostTickMsg:
+		if m.Ghost == nil || m.Playback != nil {
+			return m, nil
+		}
+		if m.Recording {
+			m.Ghost.Now = time.Since(m.RunStart)
+		}
+		return m, ghostTick()
+
+	case ghostReplayMsg:
+		return m.ghostFetched(msg)
+
+	case raceMsg:
+		if m.RaceConn == nil {
+			return m, nil
+		}
+		m.raceEvent(msg)
+		return m, waitRace(m.RaceConn)
+
+	case raceClosedMsg:
+		m.RaceConn = nil
+		m.RaceHost = nil
+		m.Race.Msg = "Disconnected from the race"
+		return m, nil
+
+	case editTickMsg:
+		if m.GameMode != GameModeEditChallenge || m.State != StatePlaying || m.Playback != nil {
+			return m, nil
+		}
+		m.Elapsed = time.Since(m.RunStart)
+		if m.Elapsed >= m.EditRun.Deadline {
+			return m.handleKey(clockKey)
+		}
+		return m, editTick()
+	}
+	return m, nil
+}
+
+// handleKey records the key for the current run and dispatches it.
+func (m Model) handleKey(key string) (tea.Model, tea.Cmd) {
+	if m.Recording && key != "ctrl+c" {
+		m.Replay.Keys = append(m.Replay.Keys, ReplayKey{Key: key, At: m.Elapsed.Milliseconds()})
+	}
+	next, cmd := m.dispatchKey(key)
+	nm := next.(Model)
+	nm.lastKeyMs = m.Elapsed.Milliseconds()
+	nm.ScrollTop = ui.ScrollTop(nm.ScrollTop, nm.Cursor.Row, len(nm.Buffer.Lines), nm.bufferHeight(), nm.config().ScrollOff)
+	nm.trackProfile(m)
+	nm.afterGhostKey(m)
+	if nm.teamPending {
+		nm.teamPending = false
+		cmd = tea.Batch(cmd, nm.submitTeam())
+	}
+	if nm.GameMode == GameModeRace && nm.Recording && nm.State != StateRaceLobby {
+		nm.sendRaceProgress()
+	}
+	if nm.State == StateMenu || nm.State == StateTutorialMenu || nm.State == StateRaceLobby {
+		// Back at a menu: the run is over
+		nm.Recording = false
+		nm.Ghost = nil
+	}
+	return nm, cmd
+}
+
+func (m Model) dispatchKey(key string) (tea.Model, tea.Cmd) {
+	// Global quit
+	if key == "ctrl+c" || (key == "q" && m.State != StatePlaying && !m.typingProfileName()) {
+		return m, tea.Quit
+	}
+
+	switch m.State {
+	case StateMenu:
+		return m.handleMenuInput(key)
+
+	case StateTutorialMenu:
+		return m.handleTutorialMenuInput(key)
 
-		// Global quit
-		if key == "ctrl+c" || (key == "q" && m.State != StatePlaying) {
-			return m, tea.Quit
+	case StateProfileMenu:
+		return m.handleProfileMenuInput(key)
+
+	case StateStats:
+		if key == "esc" || key == "enter" {
+			m.State = StateMenu
 		}
 
-		switch m.State {
-		case StateMenu:
-			return m.handleMenuInput(key)
+	case StateLeaderboard:
+		return m.handleLeaderboardInput(key)
+
+	case StateRaceLobby:
+		return m.handleRaceLobbyInput(key)
 
-		case StateTutorialMenu:
-			return m.handleTutorialMenuInput(key)
+	case StateSettings:
+		return m.handleSettingsInput(key)
 
-		case StateLessonIntro:
-			if key == "enter" {
-				m.State = StatePlaying
-				m.startExercise()
-			} else if key == "esc" {
+	case StateLessonIntro:
+		if key == "enter" {
+			m.State = StatePlaying
+			m.startExercise()
+		} else if key == "esc" {
+			m.State = StateTutorialMenu
+		}
+
+	case StatePlaying:
+		if m.GameMode == GameModeEditChallenge && (m.editTimeUp() || key == clockKey) {
+			return m, nil
+		}
+		if key == "esc" && m.VimMode == ModeNormal {
+			if m.GameMode == GameModeTutorial {
 				m.State = StateTutorialMenu
+			} else if m.GameMode == GameModeRace {
+				m.leaveRace()
+			} else {
+				m.State = StateMenu
+			}
+			return m, nil
+		}
+		if m.GameMode == GameModeEndless {
+			prevTargets := m.TargetsHit
+			next, cmd := m.handlePlayingInput(key)
+			nm := next.(Model)
+			nm.afterEndlessKey(prevTargets)
+			if nm.Endless.LostLife && !m.Endless.LostLife {
+				cmd = tea.Batch(cmd, nm.ring())
 			}
+			return nm, cmd
+		}
+		if m.GameMode == GameModeEditChallenge {
+			next, cmd := m.handlePlayingInput(key)
+			nm := next.(Model)
+			nm.afterEditChallengeKey()
+			return nm, cmd
+		}
+		if m.GameMode == GameModeReview {
+			next, cmd := m.handlePlayingInput(key)
+			nm := next.(Model)
+			nm.afterReviewKey()
+			return nm, cmd
+		}
+		return m.handlePlayingInput(key)
 
-		case StatePlaying:
-			if key == "esc" && m.VimMode == ModeNormal {
-				if m.GameMode == GameModeTutorial {
-					m.State = StateTutorialMenu
+	case StateExerciseComplete:
+		if key == "enter" {
+			if m.GameMode != GameModeTutorial {
+				level := m.Levels[m.LevelIndex]
+				m.ExIndex++
+				if m.ExIndex >= len(level.Exercises) {
+					m.State = StateLevelComplete
 				} else {
-					m.State = StateMenu
+					m.State = StatePlaying
+					m.startChallengeLevel()
 				}
-				return m, nil
-			}
-			return m.handlePlayingInput(key)
-
-		case StateExerciseComplete:
-			if key == "enter" {
-				if m.GameMode == GameModeMotionChallenge {
-					level := m.Levels[m.LevelIndex]
-					m.ExIndex++
-					if m.ExIndex >= len(level.Exercises) {
-						m.State = StateLevelComplete
-					} else {
-						m.State = StatePlaying
-						m.startChallengeLevel()
-					}
+			} else {
+				lesson := m.Lessons[m.LessonIndex]
+				m.ExIndex++
+				if m.ExIndex >= len(lesson.Exercises) {
+					m.State = StateLevelComplete
 				} else {
-					lesson := m.Lessons[m.LessonIndex]
-					m.ExIndex++
-					if m.ExIndex >= len(lesson.Exercises) {
-						m.State = StateLevelComplete
-					} else {
-						m.State = StatePlaying
-						m.startExercise()
-					}
+					m.State = StatePlaying
+					m.startExercise()
 				}
 			}
+		}
 
-		case StateLevelComplete:
-			if key == "enter" {
-				if m.GameMode == GameModeTutorial {
-					m.LessonIndex++
-					if m.LessonIndex >= len(m.Lessons) {
-						m.State = StateGameOver
-					} else {
-						m.State = StateLessonIntro
-						m.ExIndex = 0
-					}
+	case StateLevelComplete:
+		if key == "enter" {
+			if m.GameMode == GameModeRace {
+				m.leaveRace()
+			} else if m.GameMode == GameModeTutorial {
+				m.LessonIndex++
+				if m.LessonIndex >= len(m.Lessons) {
+					m.State = StateGameOver
 				} else {
-					m.LevelIndex++
+					m.State = StateLessonIntro
 					m.ExIndex = 0
-					if m.LevelIndex >= len(m.Levels) {
-						m.State = StateGameOver
-					} else {
-						m.State = StatePlaying
-						m.startChallengeLevel()
-					}
+				}
+			} else {
+				m.LevelIndex++
+				m.ExIndex = 0
+				if m.LevelIndex >= len(m.Levels) {
+					m.State = StateGameOver
+				} else {
+					m.State = StatePlaying
+					m.rngStart = m.rngSource.drawn
+					m.startChallengeLevel()
 				}
 			}
+		}
 
-		case StateGameOver:
-			if key == "enter" {
-				m.State = StateMenu
-			}
+	case StateGameOver:
+		if key == "enter" {
+			m.State = StateMenu
 		}
 	}
 	return m, nil
@@ -179,19 +439,46 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m Model) handleMenuInput(key string) (tea.Model, tea.Cmd) {
 	switch key {
-	case "1", "t", "enter":
-		m.GameMode = GameModeTutorial
-		m.LessonIndex = 0
-		m.ExIndex = 0
-		m.Score = 0
-		m.State = StateLessonIntro
+	case "enter":
+		if !m.continueRun() {
+			m.beginRun(GameModeTutorial, 0, 0, m.runSeed())
+		}
+	case "1", "t":
+		m.beginRun(GameModeTutorial, 0, 0, m.runSeed())
 	case "2", "c":
-		m.GameMode = GameModeMotionChallenge
-		m.LevelIndex = 0
-		m.ExIndex = 0
-		m.Score = 0
-		m.State = StatePlaying
-		m.startChallengeLevel()
+		m.Levels = m.LevelCatalog
+		m.Daily = ""
+		m.Practice = PracticeSpec{}
+		m.beginRun(GameModeMotionChallenge, 0, 0, m.runSeed())
+	case "3", "d":
+		m.startDaily(time.Now().UTC())
+	case "4", "e":
+		m.Daily = ""
+		m.Practice = PracticeSpec{}
+		m.beginRun(GameModeEndless, 0, 0, m.runSeed())
+	case "5", "f":
+		m.Daily = ""
+		m.Practice = PracticeSpec{}
+		m.beginRun(GameModeEditChallenge, 0, 0, m.runSeed())
+		return m, editTick()
+	case "6", "i":
+		if m.Profile != nil {
+			m.State = StateStats
+		}
+	case "7", "r":
+		m.StartReview(time.Now())
+	case "8", "l":
+		if m.Leaderboards != nil {
+			m.OpenLeaderboard()
+		}
+	case "9", "o":
+		if m.Config != nil {
+			m.OpenSettings()
+		}
+	case "p":
+		if m.Profiles != nil {
+			m.OpenProfileMenu()
+		}
 	}
 	return m, nil
 }
@@ -201,26 +488,121 @@ func (m Model) handleTutorialMenuInput(key string) (tea.Model, tea.Cmd) {
 		m.State = StateMenu
 		return m, nil
 	}
+	// j/k to move the highlight, Enter to start the highlighted lesson
+	switch key {
+	case "j", "down":
+		if m.MenuCursor < len(m.Lessons)-1 {
+			m.MenuCursor++
+		}
+		return m, nil
+	case "k", "up":
+		if m.MenuCursor > 0 {
+			m.MenuCursor--
+		}
+		return m, nil
+	case "enter":
+		m.beginRun(GameModeTutorial, m.MenuCursor, 0, m.runSeed())
+		return m, nil
+	}
 	// Number keys 1-9 to select lesson, or 0 for lesson 10
 	if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
 		idx := int(key[0]-'0') - 1
 		if idx < len(m.Lessons) {
-			m.LessonIndex = idx
-			m.ExIndex = 0
-			m.Score = 0
-			m.State = StateLessonIntro
+			m.beginRun(GameModeTutorial, idx, 0, m.runSeed())
 		}
 	} else if key == "0" && len(m.Lessons) >= 10 {
-		m.LessonIndex = 9
-		m.ExIndex = 0
-		m.Score = 0
-		m.State = StateLessonIntro
+		m.beginRun(GameModeTutorial, 9, 0, m.runSeed())
 	}
 	return m, nil
 }
 
 // --- Level/Exercise start ---
 
+// newSeed returns a fresh seed for an unseeded run.
+func newSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// runSeed returns the seed for a new run: the fixed seed if one was given.
+func (m *Model) runSeed() int64 {
+	if m.FixedSeed != 0 {
+		return m.FixedSeed
+	}
+	return newSeed()
+}
+
+// startDaily starts the daily challenge for the given date. Dailies are
+// identified by the UTC date so teammates in every time zone share one.
+func (m *Model) startDaily(date time.Time) {
+	level := DailyLevel(date)
+	m.Levels = []Level{level}
+	m.Daily = date.Format(DailyDateFormat)
+	m.Practice = PracticeSpec{}
+	m.beginRun(GameModeMotionChallenge, 0, 0, level.Seed)
+}
+
+// beginRun starts a tutorial or challenge run at the given lesson or level,
+// seeding the model's RNG and starting a new replay recording. A level with
+// its own seed overrides the given one.
+func (m *Model) beginRun(mode GameModeType, lessonIndex, levelIndex int, seed int64) {
+	if mode == GameModeMotionChallenge && m.Levels[levelIndex].Seed != 0 {
+		seed = m.Levels[levelIndex].Seed
+	}
+	m.GameMode = mode
+	m.LessonIndex = lessonIndex
+	m.LevelIndex = levelIndex
+	m.ExIndex = 0
+	m.Score = 0
+
+	m.Coach.Counts = nil
+	m.Seed = seed
+	m.rngSource = newRunSource(seed, 0)
+	m.Rng = rand.New(m.rngSource)
+	m.rngStart = 0
+	m.RunStart = time.Now()
+	m.Elapsed = 0
+	m.Replay = Replay{
+		Version:     ReplayVersion,
+		Mode:        mode,
+		LessonIndex: lessonIndex,
+		LevelIndex:  levelIndex,
+		Seed:        seed,
+		Daily:       m.Daily,
+		Practice:    m.Practice,
+		Review:      m.Review.Commands,
+		Rules:       m.Rules,
+		Recorded:    m.RunStart,
+	}
+	switch mode {
+	case GameModeTutorial:
+		m.Replay.Lesson = m.Lessons[lessonIndex].Name
+	case GameModeMotionChallenge, GameModeRace:
+		m.Replay.Level = m.Levels[levelIndex].Name
+	}
+	m.Recording = true
+
+	if mode == GameModeTutorial {
+		m.State = StateLessonIntro
+	} else if mode == GameModeEditChallenge {
+		m.State = StatePlaying
+		m.Levels = []Level{editChallengeLevel()}
+		m.startChallengeLevel()
+		m.startEditChallenge()
+	} else if mode == GameModeEndless {
+		m.State = StatePlaying
+		m.startEndless()
+		m.startChallengeLevel()
+		m.setEndlessBudget()
+	} else if mode == GameModeReview {
+		m.State = StatePlaying
+		m.startReview()
+		m.startChallengeLevel()
+	} else {
+		m.State = StatePlaying
+		m.startChallengeLevel()
+	}
+}
+
 func (m *Model) startChallengeLevel() {
 	level := m.Levels[m.LevelIndex]
 	ex := level.Exercises[m.ExIndex]
@@ -234,16 +616,21 @@ func (m *Model) startChallengeLevel() {
 	m.VimMode = ModeNormal
 	m.Parser.Reset()
 	m.Undo.Reset()
+	m.Coach.Reset()
+	m.Coach.Allowed = MotionsForCommands(m.allowedCommands())
+	m.ShowTip = false
+	m.ScrollTop = 0
 
 	if ex.Type == ExerciseMotion {
 		m.GoalLines = nil
 		m.TargetsHit = 0
-		m.Target = GenerateTarget(m.Buffer.Lines, m.Cursor, 3)
+		m.Target = m.nextTarget(ex)
 		m.StartPos = m.Cursor
 	} else {
 		m.GoalLines = ex.GoalBuffer
 		m.Target = Position{-1, -1}
 	}
+	m.updateDiff()
 }
 
 func (m *Model) startExercise() {
@@ -259,28 +646,40 @@ func (m *Model) startExercise() {
 	m.VimMode = ModeNormal
 	m.Parser.Reset()
 	m.Undo.Reset()
+	m.Coach.Reset()
+	m.Coach.Allowed = MotionsForCommands(m.allowedCommands())
+	m.ShowTip = false
+	m.ScrollTop = 0
 
 	if ex.Type == ExerciseMotion {
 		m.GoalLines = nil
 		m.TargetsHit = 0
-		m.Target = GenerateTarget(m.Buffer.Lines, m.Cursor, 3)
+		m.Target = m.nextTarget(ex)
 		m.StartPos = m.Cursor
 	} else {
 		m.GoalLines = ex.GoalBuffer
 		m.Target = Position{-1, -1} // no target highlight for edit exercises
 	}
+	m.updateDiff()
 }
 
 // --- Playing input handling ---
 
 func (m Model) handlePlayingInput(key string) (tea.Model, tea.Cmd) {
+	if t := m.Rules.KeyTimeout; t > 0 && m.Parser.Pending() != "" && m.Elapsed.Milliseconds()-m.lastKeyMs > int64(t) {
+		m.Parser.State, m.Parser.Count = InputReady, 0 // the half-typed command timed out
+	}
 	result := m.Parser.Feed(key)
 	if !result.Consumed {
-		return m, nil
+		return m, m.ring()
+	}
+	m.Coach.Record(result)
+	if m.Profile != nil {
+		m.Profile.Stats.Record(result, time.Now())
 	}
 
 	// Handle insert mode actions
-	if result.Action == ActionInsertChar || result.Action == ActionInsertBackspace || result.Action == ActionInsertNewline {
+	if result.Action == ActionInsertChar || result.Action == ActionInsertBackspace || result.Action == ActionInsertNewline || result.Action == ActionInsertArrow || result.Action == ActionInsertTab {
 		return m.handleInsertAction(result)
 	}
 
@@ -319,16 +718,23 @@ func (m Model) handlePlayingInput(key string) (tea.Model, tea.Cmd) {
 
 	// Partial input (e.g., first 'g', 'f', 'r')
 	if result.Motion == MotionNone && result.Action == ActionNone {
-		m.Keystrokes++
+		m.countKeystroke()
 		return m, nil
 	}
 
 	return m, nil
 }
 
+// countKeystroke counts a keystroke towards the current target or exercise
+// and the level.
+func (m *Model) countKeystroke() {
+	m.Keystrokes++
+	m.LevelKeystrokes++
+}
+
 // handleMotion processes cursor motion (existing behavior preserved).
 func (m Model) handleMotion(result ParseResult) (tea.Model, tea.Cmd) {
-	m.Keystrokes++
+	m.countKeystroke()
 
 	count := result.Count
 	if count == 0 {
@@ -379,13 +785,21 @@ func (m Model) handleMotion(result ParseResult) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleTargetReached() (tea.Model, tea.Cmd) {
-	m.LastMedal = ComputeMedal(m.Keystrokes)
+	// One solve serves both the medal and the stats.
+	var path []SolverStep
+	if m.Profile != nil || m.Rules.Scoring == ScoringSolver {
+		path = OptimalPath(m.Buffer.Lines, m.StartPos, m.Target, MotionsForCommands(m.allowedCommands()))
+	}
+	m.LastMedal = m.targetMedal(path)
 	m.Score += ScoreForMedal(m.LastMedal)
+	m.LevelMedals = append(m.LevelMedals, m.LastMedal)
 	m.ShowMedal = true
 	m.TargetsHit++
+	m.recordTargetStats(path)
+	m.finishCoaching()
 
 	var totalTargets int
-	if m.GameMode == GameModeMotionChallenge {
+	if m.GameMode != GameModeTutorial {
 		ex := m.Levels[m.LevelIndex].Exercises[m.ExIndex]
 		totalTargets = ex.NumTargets
 	} else {
@@ -399,18 +813,84 @@ func (m Model) handleTargetReached() (tea.Model, tea.Cmd) {
 		m.Keystrokes = 0
 		m.ShowMedal = false
 		m.StartPos = m.Cursor
-		m.Target = GenerateTarget(m.Buffer.Lines, m.Cursor, 3)
+		m.Target = m.nextTarget(m.currentExercise())
 	}
 
 	return m, nil
 }
 
+// targetMedal grades the keystrokes spent on the target just reached, given
+// an optimal path to it.
+func (m Model) targetMedal(path []SolverStep) Medal {
+	if m.Rules.Scoring == ScoringSolver && path != nil {
+		return parMedal(m.Keystrokes, PathKeystrokes(path))
+	}
+	return ComputeMedal(m.Keystrokes)
+}
+
+// ring rings the terminal bell if the player wants it.
+func (m Model) ring() tea.Cmd {
+	if m.Bell == nil || !m.config().Bell {
+		return nil
+	}
+	w := m.Bell
+	return func() tea.Msg {
+		w.Write([]byte("\a"))
+		return nil
+	}
+}
+
+// currentExercise returns the exercise being played.
+func (m Model) currentExercise() Exercise {
+	if m.GameMode == GameModeTutorial {
+		return m.Lessons[m.LessonIndex].Exercises[m.ExIndex]
+	}
+	return m.Levels[m.LevelIndex].Exercises[m.ExIndex]
+}
+
+// allowedCommands returns the commands the player is expected to know: the
+// exercise's own commands if it declares them, otherwise every command
+// taught up to the current lesson, or the current level's commands.
+func (m Model) allowedCommands() []string {
+	if ex := m.currentExercise(); ex.Commands != nil {
+		return ex.Commands
+	}
+	if m.GameMode != GameModeTutorial {
+		return m.Levels[m.LevelIndex].Commands
+	}
+	var cmds []string
+	for i := 0; i <= m.LessonIndex; i++ {
+		cmds = append(cmds, m.Lessons[i].NewCommands...)
+	}
+	return cmds
+}
+
+// editCommands returns the commands of a list that are not motions.
+func editCommands(commands []string) []string {
+	var edits []string
+	for _, cmd := range commands {
+		if len(MotionsForCommands([]string{cmd})) == 0 {
+			edits = append(edits, cmd)
+		}
+	}
+	return edits
+}
+
+// nextTarget chooses the next motion target for the exercise from the cursor.
+func (m Model) nextTarget(ex Exercise) Position {
+	return ChooseTarget(m.Rng, m.Buffer.Lines, m.Cursor, TargetSpec{
+		Strategy:   ex.Targets,
+		Difficulty: ex.Difficulty,
+		Allowed:    MotionsForCommands(m.allowedCommands()),
+	})
+}
+
 // --- Editing action handlers ---
 
 func (m Model) handleEnterInsert(result ParseResult) (tea.Model, tea.Cmd) {
 	// Save undo snapshot before entering insert mode
 	m.Undo.Save(m.Buffer.Clone(), m.Cursor)
-	m.Keystrokes++
+	m.countKeystroke()
 
 	switch result.Action {
 	case ActionInsertBefore:
@@ -437,6 +917,7 @@ func (m Model) handleEnterInsert(result ParseResult) (tea.Model, tea.Cmd) {
 
 	m.VimMode = ModeInsert
 	m.Parser.Mode = ModeInsert
+	m.updateDiff()
 	return m, nil
 }
 
@@ -448,14 +929,51 @@ func (m Model) handleInsertAction(result ParseResult) (tea.Model, tea.Cmd) {
 		m.Cursor = m.Buffer.DeleteCharBefore(m.Cursor.Row, m.Cursor.Col)
 	case ActionInsertNewline:
 		m.Cursor = m.Buffer.SplitLine(m.Cursor.Row, m.Cursor.Col)
+	case ActionInsertArrow:
+		m.Cursor = insertModeMove(m.Buffer.Lines, m.Cursor, result.Motion)
+	case ActionInsertTab:
+		if sw := m.Rules.ShiftWidth; sw > 0 {
+			for n := sw - m.Cursor.Col%sw; n > 0; n-- {
+				m.Cursor = m.Buffer.InsertChar(m.Cursor.Row, m.Cursor.Col, ' ')
+			}
+		}
 	}
 	m.Lines = m.Buffer.Lines
+	m.updateDiff()
 	return m, nil
 }
 
+// insertModeMove moves the cursor for an arrow key in insert mode, where the
+// cursor may sit one past the last character of the line.
+func insertModeMove(lines []string, pos Position, motion Motion) Position {
+	switch motion {
+	case MotionH:
+		pos.Col--
+	case MotionL:
+		pos.Col++
+	case MotionK:
+		pos.Row--
+	case MotionJ:
+		pos.Row++
+	}
+	if pos.Row < 0 {
+		pos.Row = 0
+	}
+	if pos.Row >= len(lines) {
+		pos.Row = len(lines) - 1
+	}
+	if pos.Col > len(lines[pos.Row]) {
+		pos.Col = len(lines[pos.Row])
+	}
+	if pos.Col < 0 {
+		pos.Col = 0
+	}
+	return pos
+}
+
 func (m Model) handleDeleteChar(result ParseResult) (tea.Model, tea.Cmd) {
 	m.Undo.Save(m.Buffer.Clone(), m.Cursor)
-	m.Keystrokes++
+	m.countKeystroke()
 
 	count := result.Count
 	if count == 0 {
@@ -471,7 +989,7 @@ func (m Model) handleDeleteChar(result ParseResult) (tea.Model, tea.Cmd) {
 
 func (m Model) handleReplaceChar(result ParseResult) (tea.Model, tea.Cmd) {
 	m.Undo.Save(m.Buffer.Clone(), m.Cursor)
-	m.Keystrokes++
+	m.countKeystroke()
 	m.Cursor = m.Buffer.ReplaceChar(m.Cursor.Row, m.Cursor.Col, result.Char)
 	m.Lines = m.Buffer.Lines
 	m.checkGoalReached()
@@ -508,8 +1026,18 @@ func (m Model) handleRedo() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateDiff recomputes the live diff against the goal after an edit.
+func (m *Model) updateDiff() {
+	if m.GoalLines == nil {
+		m.Diff = GoalDiff{}
+		return
+	}
+	m.Diff = diffGoal(m.Buffer.Lines, m.GoalLines)
+}
+
 // checkGoalReached checks if the buffer matches the goal (for edit exercises).
 func (m *Model) checkGoalReached() {
+	m.updateDiff()
 	if m.GoalLines == nil {
 		return
 	}
@@ -523,16 +1051,45 @@ func (m *Model) checkGoalReached() {
 	}
 	// Goal reached!
 	m.State = StateExerciseComplete
+	m.finishCoaching()
+}
+
+// finishCoaching analyzes the input for the target or exercise just completed
+// and keeps the most useful tip for display.
+func (m *Model) finishCoaching() {
+	m.LastTip, m.ShowTip = m.Coach.Finish()
 }
 
 // --- View methods ---
 
 func (m Model) View() string {
+	if m.Playback != nil {
+		status := "▶ Replay"
+		if m.Playback.Done() {
+			status = "■ Replay finished"
+		}
+		banner := lipgloss.NewStyle().Foreground(ui.CurrentTheme().Dim).Render("  " + status + "  •  q to quit")
+		return m.viewState() + "\n" + banner + "\n"
+	}
+	return m.viewState()
+}
+
+func (m Model) viewState() string {
 	switch m.State {
 	case StateMenu:
 		return m.viewMenu()
 	case StateTutorialMenu:
 		return m.viewTutorialMenu()
+	case StateProfileMenu:
+		return m.viewProfileMenu()
+	case StateStats:
+		return m.viewStats()
+	case StateLeaderboard:
+		return m.viewLeaderboard()
+	case StateRaceLobby:
+		return m.viewRaceLobby()
+	case StateSettings:
+		return m.viewSettings()
 	case StateLessonIntro:
 		return m.viewLessonIntro()
 	case StatePlaying:
@@ -548,19 +1105,20 @@ func (m Model) View() string {
 }
 
 func (m Model) viewMenu() string {
+	th := ui.CurrentTheme()
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("75")).
+		Foreground(th.Title).
 		Padding(1, 0)
 
 	subtitleStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241"))
+		Foreground(th.Dim)
 
 	optionStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252"))
+		Foreground(th.Text)
 
 	optionKeyStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("226")).
+		Foreground(th.Highlight).
 		Bold(true)
 
 	title := titleStyle.Render(`
@@ -571,64 +1129,114 @@ func (m Model) viewMenu() string {
    |_| |_|_| |_| |_|\____|\__,_|_| |_| |_|\___|`)
 
 	sub := subtitleStyle.Render("Learn Vim — Step by Step")
+	if m.Profile != nil {
+		sub += subtitleStyle.Render("  •  Profile: ") + optionStyle.Render(m.Profile.Name) + subtitleStyle.Render(" (p to switch)")
+	}
 
-	options := "\n" +
-		"  " + optionKeyStyle.Render("1") + optionStyle.Render("  Tutorial       — Learn vim commands step by step") + "\n" +
-		"  " + optionKeyStyle.Render("2") + optionStyle.Render("  Challenges     — Practice all commands") + "\n\n" +
-		subtitleStyle.Render("  Press number to select  •  q to quit") + "\n"
+	options := "\n"
+	if label := m.resumeLabel(); label != "" {
+		options += "  " + optionKeyStyle.Render("↵") + optionStyle.Render("  Continue       — "+label) + "\n"
+	}
+	options += "  " + optionKeyStyle.Render("1") + optionStyle.Render("  Tutorial       — Learn vim commands step by step") + "\n" +
+		"  " + optionKeyStyle.Render("2") + optionStyle.Render("  Challenges     — Practice all commands") + "\n" +
+		"  " + optionKeyStyle.Render("3") + optionStyle.Render("  Daily          — Same targets for everyone today") + "\n" +
+		"  " + optionKeyStyle.Render("4") + optionStyle.Render("  Endless        — Survive as long as you can") + "\n" +
+		"  " + optionKeyStyle.Render("5") + optionStyle.Render("  Edit Challenge — Fix code against the clock") + "\n"
+	if m.Profile != nil {
+		options += "  " + optionKeyStyle.Render("6") + optionStyle.Render("  Stats          — Your commands, accuracy and trends") + "\n"
+		if due := m.reviewDue(time.Now()); due > 0 {
+			options += "  " + optionKeyStyle.Render("7") + optionStyle.Render(fmt.Sprintf("  Review         — %d commands due", due)) + "\n"
+		} else if len(m.Profile.Review) > 0 {
+			options += "  " + optionKeyStyle.Render("7") + optionStyle.Render("  Review         — Nothing due, practice ahead") + "\n"
+		}
+	}
+	if m.Leaderboards != nil {
+		options += "  " + optionKeyStyle.Render("8") + optionStyle.Render("  Leaderboards   — Best runs per level, seed and day") + "\n"
+	}
+	if m.Config != nil {
+		options += "  " + optionKeyStyle.Render("9") + optionStyle.Render("  Settings       — Theme, scoring, scrolling and keys") + "\n"
+	}
+	options += "\n" + subtitleStyle.Render("  Press number to select  •  q to quit") + "\n"
+	if m.ProfileErr != nil {
+		options += "\n" + lipgloss.NewStyle().Foreground(th.Bad).Render("  Could not save progress: "+m.ProfileErr.Error()) + "\n"
+	}
+	if m.PackErr != nil {
+		first, _, _ := strings.Cut(m.PackErr.Error(), "\n")
+		options += "\n" + lipgloss.NewStyle().Foreground(th.Bad).Render("  Skipped a broken pack: "+first) + "\n"
+		options += subtitleStyle.Render("  Run vimgame validate for every problem") + "\n"
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, title, "", "  "+sub, options)
 }
 
 func (m Model) viewTutorialMenu() string {
+	th := ui.CurrentTheme()
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("75")).
+		Foreground(th.Title).
 		Padding(1, 2)
 
 	lessonStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252"))
+		Foreground(th.Text)
 
 	numStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("226")).
+		Foreground(th.Highlight).
 		Bold(true).
 		Width(3).
 		Align(lipgloss.Right)
 
 	cmdStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241"))
+		Foreground(th.Dim)
 
 	var sb strings.Builder
 	sb.WriteString(titleStyle.Render("Tutorial — Select a Lesson"))
 	sb.WriteString("\n\n")
 
+	selectedStyle := lessonStyle.Bold(true).Foreground(th.Title)
+	doneStyle := lipgloss.NewStyle().Foreground(th.Good)
+
 	for i, lesson := range m.Lessons {
-		num := fmt.Sprintf("%d", (i+1)%10) // 1-9, 0 for 10
+		num := ""
+		if i < 10 {
+			num = fmt.Sprintf("%d", (i+1)%10) // 1-9, 0 for 10
+		}
 		cmds := ""
 		if len(lesson.NewCommands) > 0 {
 			cmds = "  " + cmdStyle.Render("("+strings.Join(lesson.NewCommands, ", ")+")")
 		}
-		sb.WriteString("  " + numStyle.Render(num) + "  " + lessonStyle.Render(lesson.Name) + cmds + "\n")
+		marker, name := "  ", lessonStyle.Render(lesson.Name)
+		if i == m.MenuCursor {
+			marker, name = "▸ ", selectedStyle.Render(lesson.Name)
+		}
+		done := "  "
+		if m.Profile != nil && m.Profile.LessonDone(lesson.Name) {
+			done = doneStyle.Render("✓ ")
+			if medal := m.Profile.Lessons[lesson.Name].BestMedal; medal != MedalNone {
+				cmds += "  " + ui.RenderMedal(int(medal), strings.TrimSuffix(medal.String(), "!"))
+			}
+		}
+		sb.WriteString(marker + numStyle.Render(num) + " " + done + name + cmds + "\n")
 	}
 
 	sb.WriteString("\n")
-	sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("  Press number to select  •  ESC: back"))
+	sb.WriteString(lipgloss.NewStyle().Foreground(th.Dim).Render("  Press number or j/k + Enter to select  •  ESC: back"))
 	sb.WriteString("\n")
 
 	return sb.String()
 }
 
 func (m Model) viewLessonIntro() string {
+	th := ui.CurrentTheme()
 	lesson := m.Lessons[m.LessonIndex]
 
 	titleStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("75")).
+		Foreground(th.Title).
 		Padding(1, 0)
 
 	boxStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
+		BorderForeground(th.Border).
 		Padding(1, 2).
 		MaxWidth(60)
 
@@ -638,26 +1246,45 @@ func (m Model) viewLessonIntro() string {
 	return lipgloss.JoinVertical(lipgloss.Left, title, "", body, "")
 }
 
+// syntax returns how the buffer is highlighted: all built-in content is Go,
+// practice files by their extension.
+func (m Model) syntax() ui.Syntax {
+	if m.Practice.File != "" && filepath.Ext(m.Practice.File) != ".go" {
+		return ui.SyntaxPlain
+	}
+	return ui.SyntaxGo
+}
+
+// bufferView returns how the buffer is drawn.
+func (m Model) bufferView() ui.BufferView {
+	c := m.config()
+	return ui.BufferView{Syntax: m.syntax(), Diff: m.Diff.Buffer, Top: m.ScrollTop, ScrollOff: c.ScrollOff, Numbers: c.LineNumbers(), Highlight: m.highlight}
+}
+
+// bufferHeight returns how many buffer lines fit on screen, or 0 before the
+// terminal size is known.
+func (m Model) bufferHeight() int {
+	if m.Height == 0 {
+		return 0
+	}
+	overhead := 9 // instruction + HUD + mode + medal + footer + borders + margin
+	return max(m.Height-overhead, 3)
+}
+
 func (m Model) viewPlaying() string {
-	if m.GameMode == GameModeMotionChallenge {
+	if m.GameMode != GameModeTutorial {
 		return m.viewPlayingChallenge()
 	}
 	return m.viewPlayingTutorial()
 }
 
 func (m Model) viewPlayingChallenge() string {
+	th := ui.CurrentTheme()
 	level := m.Levels[m.LevelIndex]
 	ex := level.Exercises[m.ExIndex]
 
-	bufferMaxHeight := 0
+	bufferMaxHeight := m.bufferHeight()
 	bufferMaxWidth := 0
-	if m.Height > 0 {
-		overhead := 9
-		bufferMaxHeight = m.Height - overhead
-		if bufferMaxHeight < 3 {
-			bufferMaxHeight = 3
-		}
-	}
 
 	isEditExercise := ex.Type == ExerciseEdit
 
@@ -674,7 +1301,7 @@ func (m Model) viewPlayingChallenge() string {
 
 	// Instruction line
 	instrStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252")).
+		Foreground(th.Text).
 		Bold(true).
 		Padding(0, 1)
 	instruction := instrStyle.Render(ex.Instruction)
@@ -684,7 +1311,11 @@ func (m Model) viewPlayingChallenge() string {
 	if isEditExercise {
 		targetRow, targetCol = -1, -1
 	}
-	buffer := ui.RenderBuffer(m.Buffer.Lines, m.Cursor.Row, m.Cursor.Col, targetRow, targetCol, bufferMaxHeight, bufferMaxWidth)
+	ghosts := m.ghostMarkers()
+	if m.GameMode == GameModeRace && m.RaceConn != nil {
+		ghosts = m.raceGhosts()
+	}
+	buffer := ui.RenderBuffer(m.Buffer.Lines, m.bufferView(), m.Cursor.Row, m.Cursor.Col, targetRow, targetCol, bufferMaxHeight, bufferMaxWidth, ghosts...)
 
 	// Medal line
 	var medalLine string
@@ -692,19 +1323,30 @@ func (m Model) viewPlayingChallenge() string {
 		medalLine = "  " + ui.RenderMedal(int(m.LastMedal), m.LastMedal.String())
 	}
 
+	// Coaching tip from the previous target
+	var tipLine string
+	if m.ShowTip {
+		tipLine = "  " + ui.RenderTip(m.LastTip.String())
+	}
+
 	// Mode indicator
 	modeIndicator := ""
 	if m.VimMode == ModeInsert {
 		modeIndicator = ui.RenderModeIndicator("INSERT")
 	}
+	if keys := m.Parser.Pending(); keys != "" && m.config().ShowCmd {
+		modeIndicator += ui.RenderShowCmd(keys)
+	}
 
-	// Build hints from level commands
-	hints := make([]ui.HintItem, len(level.Commands))
-	for i, cmd := range level.Commands {
+	// Build hints from level commands; a review highlights the commands
+	// the exercise drills
+	commands := m.allowedCommands()
+	hints := make([]ui.HintItem, len(commands))
+	for i, cmd := range commands {
 		hints[i] = ui.HintItem{
 			Key:         cmd,
 			Description: commandDesc(cmd),
-			IsNew:       true,
+			IsNew:       m.GameMode != GameModeReview || slices.Contains(m.Review.Focus[m.ExIndex], canonicalCommand(cmd)),
 		}
 	}
 
@@ -712,19 +1354,40 @@ func (m Model) viewPlayingChallenge() string {
 	var targetInfo string
 	if ex.Type == ExerciseMotion {
 		targetInfo = ui.RenderTargetProgress(m.TargetsHit, ex.NumTargets, m.Keystrokes)
+	} else if m.GoalLines != nil {
+		targetInfo = ui.RenderDiffCount(m.Diff.Remaining)
+		if edits := editCommands(ex.Commands); len(edits) > 0 {
+			targetInfo += "    " + ui.RenderPractising(edits)
+		}
 	}
 
 	// Exercise progress within level
 	totalEx := len(level.Exercises)
 	progress := ui.RenderChallengeProgress(m.LevelIndex+1, level.Name, m.ExIndex+1, totalEx, m.Score)
+	if m.GameMode == GameModeEndless {
+		used := m.Keystrokes
+		if isEditExercise {
+			used = m.Endless.Keys
+		}
+		progress = ui.RenderEndlessStatus(m.Endless.Round, m.Endless.Lives, EndlessLives, used, m.Endless.Budget, m.Score)
+		if m.Endless.LostLife {
+			medalLine = "  " + ui.RenderLifeLost()
+		}
+	}
+	if m.GameMode == GameModeEditChallenge {
+		progress = ui.RenderEditChallengeStatus(m.ExIndex+1, totalEx, m.EditRun.Remaining(m.Elapsed), m.Score)
+		if medalLine != "" && m.EditRun.Bonus > 0 {
+			medalLine += "  " + ui.RenderBonusTime(m.EditRun.Bonus)
+		}
+	}
 
 	var mainContent string
 
 	if isEditExercise && m.GoalLines != nil && (m.Width == 0 || m.Width >= 70) {
-		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, bufferMaxHeight, bufferMaxWidth)
+		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, m.Diff.Goal, bufferMaxHeight, bufferMaxWidth)
 		mainContent = lipgloss.JoinHorizontal(lipgloss.Top, buffer, "  ", goalBuffer)
 	} else if isEditExercise && m.GoalLines != nil {
-		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, bufferMaxHeight, bufferMaxWidth)
+		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, m.Diff.Goal, bufferMaxHeight, bufferMaxWidth)
 		mainContent = lipgloss.JoinVertical(lipgloss.Left, buffer, goalBuffer)
 	} else {
 		// Motion exercise — show hints panel
@@ -740,6 +1403,9 @@ func (m Model) viewPlayingChallenge() string {
 	if medalLine != "" {
 		parts = append(parts, medalLine)
 	}
+	if tipLine != "" {
+		parts = append(parts, tipLine)
+	}
 	if targetInfo != "" {
 		parts = append(parts, targetInfo)
 	}
@@ -747,27 +1413,31 @@ func (m Model) viewPlayingChallenge() string {
 		parts = append(parts, modeIndicator)
 	}
 	parts = append(parts, progress)
+	if m.GameMode == GameModeRace && m.RaceConn != nil {
+		parts = append(parts, ui.RenderRaceStatus(m.raceRows()))
+	}
+	if m.Ghost != nil {
+		parts = append(parts, m.ghostStatus())
+	}
 
-	footer := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("  ESC: menu")
+	escLabel := "menu"
+	if m.GameMode == GameModeRace {
+		escLabel = "leave race"
+	}
+	footer := lipgloss.NewStyle().Foreground(th.Dim).Render("  ESC: " + escLabel)
 	parts = append(parts, footer)
 
 	return lipgloss.JoinVertical(lipgloss.Left, parts...) + "\n"
 }
 
 func (m Model) viewPlayingTutorial() string {
+	th := ui.CurrentTheme()
 	lesson := m.Lessons[m.LessonIndex]
 	ex := lesson.Exercises[m.ExIndex]
 
 	// Compute available height
-	bufferMaxHeight := 0
+	bufferMaxHeight := m.bufferHeight()
 	bufferMaxWidth := 0
-	if m.Height > 0 {
-		overhead := 9 // instruction + HUD + mode + medal + footer + borders + margin
-		bufferMaxHeight = m.Height - overhead
-		if bufferMaxHeight < 3 {
-			bufferMaxHeight = 3
-		}
-	}
 
 	isEditExercise := ex.Type == ExerciseEdit
 
@@ -785,7 +1455,7 @@ func (m Model) viewPlayingTutorial() string {
 
 	// Instruction line
 	instrStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("252")).
+		Foreground(th.Text).
 		Bold(true).
 		Padding(0, 1)
 	instruction := instrStyle.Render(ex.Instruction)
@@ -795,7 +1465,7 @@ func (m Model) viewPlayingTutorial() string {
 	if isEditExercise {
 		targetRow, targetCol = -1, -1
 	}
-	buffer := ui.RenderBuffer(m.Buffer.Lines, m.Cursor.Row, m.Cursor.Col, targetRow, targetCol, bufferMaxHeight, bufferMaxWidth)
+	buffer := ui.RenderBuffer(m.Buffer.Lines, m.bufferView(), m.Cursor.Row, m.Cursor.Col, targetRow, targetCol, bufferMaxHeight, bufferMaxWidth)
 
 	// Medal line
 	var medalLine string
@@ -803,11 +1473,20 @@ func (m Model) viewPlayingTutorial() string {
 		medalLine = "  " + ui.RenderMedal(int(m.LastMedal), m.LastMedal.String())
 	}
 
+	// Coaching tip from the previous target
+	var tipLine string
+	if m.ShowTip {
+		tipLine = "  " + ui.RenderTip(m.LastTip.String())
+	}
+
 	// Mode indicator
 	modeIndicator := ""
 	if m.VimMode == ModeInsert {
 		modeIndicator = ui.RenderModeIndicator("INSERT")
 	}
+	if keys := m.Parser.Pending(); keys != "" && m.config().ShowCmd {
+		modeIndicator += ui.RenderShowCmd(keys)
+	}
 
 	// Progress line
 	totalEx := len(lesson.Exercises)
@@ -817,17 +1496,19 @@ func (m Model) viewPlayingTutorial() string {
 	var targetInfo string
 	if ex.Type == ExerciseMotion {
 		targetInfo = ui.RenderTargetProgress(m.TargetsHit, ex.NumTargets, m.Keystrokes)
+	} else if m.GoalLines != nil {
+		targetInfo = ui.RenderDiffCount(m.Diff.Remaining)
 	}
 
 	var mainContent string
 
 	if isEditExercise && m.GoalLines != nil && (m.Width == 0 || m.Width >= 70) {
 		// Side-by-side: your buffer | goal buffer
-		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, bufferMaxHeight, bufferMaxWidth)
+		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, m.Diff.Goal, bufferMaxHeight, bufferMaxWidth)
 		mainContent = lipgloss.JoinHorizontal(lipgloss.Top, buffer, "  ", goalBuffer)
 	} else if isEditExercise && m.GoalLines != nil {
 		// Stacked vertically if too narrow
-		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, bufferMaxHeight, bufferMaxWidth)
+		goalBuffer := ui.RenderGoalBuffer(m.GoalLines, m.Diff.Goal, bufferMaxHeight, bufferMaxWidth)
 		mainContent = lipgloss.JoinVertical(lipgloss.Left, buffer, goalBuffer)
 	} else {
 		// Motion exercise — show hints panel
@@ -844,6 +1525,9 @@ func (m Model) viewPlayingTutorial() string {
 	if medalLine != "" {
 		parts = append(parts, medalLine)
 	}
+	if tipLine != "" {
+		parts = append(parts, tipLine)
+	}
 	if targetInfo != "" {
 		parts = append(parts, targetInfo)
 	}
@@ -852,7 +1536,7 @@ func (m Model) viewPlayingTutorial() string {
 	}
 	parts = append(parts, progress)
 
-	footer := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("  ESC: back to lessons")
+	footer := lipgloss.NewStyle().Foreground(th.Dim).Render("  ESC: back to lessons")
 	parts = append(parts, footer)
 
 	return lipgloss.JoinVertical(lipgloss.Left, parts...) + "\n"
@@ -887,12 +1571,12 @@ func (m Model) buildTutorialHints() []ui.HintItem {
 func (m Model) viewExerciseComplete() string {
 	style := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("46")).
+		Foreground(ui.CurrentTheme().Good).
 		Padding(1, 2)
 
 	var totalEx int
 	var completeLabel string
-	if m.GameMode == GameModeMotionChallenge {
+	if m.GameMode != GameModeTutorial {
 		totalEx = len(m.Levels[m.LevelIndex].Exercises)
 		completeLabel = "complete the level"
 	} else {
@@ -902,6 +1586,9 @@ func (m Model) viewExerciseComplete() string {
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Exercise %d/%d Complete!\n\n", m.ExIndex+1, totalEx))
+	if m.ShowTip {
+		sb.WriteString(ui.RenderTip(m.LastTip.String()) + "\n\n")
+	}
 	if m.ExIndex+1 < totalEx {
 		sb.WriteString("Press Enter for next exercise")
 	} else {
@@ -914,7 +1601,7 @@ func (m Model) viewExerciseComplete() string {
 func (m Model) viewLevelComplete() string {
 	style := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("46")).
+		Foreground(ui.CurrentTheme().Good).
 		Padding(1, 2)
 
 	var sb strings.Builder
@@ -929,8 +1616,19 @@ func (m Model) viewLevelComplete() string {
 	} else {
 		level := m.Levels[m.LevelIndex]
 		sb.WriteString(fmt.Sprintf("Level %d Complete — %s\n\n", m.LevelIndex+1, level.Name))
-		sb.WriteString(fmt.Sprintf("Exercises: %d  |  Score: %d\n\n", len(level.Exercises), m.Score))
-		if m.LevelIndex+1 < len(m.Levels) {
+		sb.WriteString(fmt.Sprintf("Exercises: %d  |  Score: %d  |  Seed: %d\n\n", len(level.Exercises), m.Score, m.Seed))
+		if board := m.renderLastBoard(); board != "" {
+			sb.WriteString(board + "\n\n")
+		}
+		if m.GameMode == GameModeRace {
+			if m.RaceConn != nil {
+				if place := m.Race.Places[m.RaceConn.ID()]; place > 0 {
+					sb.WriteString(fmt.Sprintf("You finished #%d in %.1fs\n\n", place, m.Elapsed.Seconds()))
+				}
+				sb.WriteString(ui.RenderRaceStatus(m.raceRows()) + "\n\n")
+			}
+			sb.WriteString("Press Enter to return to the lobby")
+		} else if m.LevelIndex+1 < len(m.Levels) {
 			sb.WriteString("Press Enter for next level")
 		} else {
 			sb.WriteString("Press Enter to see final results")
@@ -943,7 +1641,7 @@ func (m Model) viewLevelComplete() string {
 func (m Model) viewGameOver() string {
 	style := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("226")).
+		Foreground(ui.CurrentTheme().Highlight).
 		Padding(1, 2)
 
 	var sb strings.Builder
@@ -951,9 +1649,58 @@ func (m Model) viewGameOver() string {
 		sb.WriteString("Tutorial Complete!\n\n")
 		sb.WriteString("You've learned the fundamentals of Vim navigation and editing.\n")
 		sb.WriteString("Try the Challenges mode to put your skills to the test!\n\n")
+	} else if m.GameMode == GameModeEditChallenge {
+		if m.EditRun.TimedOut {
+			sb.WriteString("Time's Up!\n\n")
+		} else {
+			sb.WriteString("Edit Challenge Complete!\n\n")
+			sb.WriteString(fmt.Sprintf("Time left: %.1fs\n", m.EditRun.Remaining(m.Elapsed).Seconds()))
+		}
+		sb.WriteString(fmt.Sprintf("Exercises: %d/%d\n", len(m.EditRun.Medals), len(m.Levels[0].Exercises)))
+		counts := make([]int, MedalBronze+1)
+		for _, medal := range m.EditRun.Medals {
+			counts[medal]++
+		}
+		sb.WriteString(fmt.Sprintf("Medals: %d Diamond  %d Gold  %d Silver  %d Bronze\n", counts[MedalDiamond], counts[MedalGold], counts[MedalSilver], counts[MedalBronze]))
+		sb.WriteString(fmt.Sprintf("Final Score: %d\n\n", m.Score))
+		if board := m.renderLastBoard(); board != "" && m.EditRun.TimedOut {
+			sb.WriteString(board + "\n\n")
+		}
+	} else if m.GameMode == GameModeReview {
+		sb.WriteString("Review Complete!\n\n")
+		cmds := make([]string, 0, len(m.Review.Graded))
+		for cmd := range m.Review.Graded {
+			cmds = append(cmds, cmd)
+		}
+		sort.Strings(cmds)
+		for _, cmd := range cmds {
+			card := m.Review.Graded[cmd]
+			next := fmt.Sprintf("in %d days", card.Interval)
+			if card.Interval == 1 {
+				next = "tomorrow"
+			}
+			sb.WriteString(fmt.Sprintf("  %-9s %-8s next review %s\n", cmd, card.Last, next))
+		}
+		sb.WriteString(fmt.Sprintf("\nFinal Score: %d\n\n", m.Score))
+	} else if m.GameMode == GameModeEndless {
+		sb.WriteString("Out of Lives!\n\n")
+		sb.WriteString(fmt.Sprintf("Rounds: %d\n", m.Endless.Round))
+		sb.WriteString(fmt.Sprintf("Final Score: %d\n", m.Score))
+		sb.WriteString(fmt.Sprintf("Seed: %d\n\n", m.Seed))
+		if board := m.renderLastBoard(); board != "" {
+			sb.WriteString(board + "\n\n")
+		}
 	} else {
 		sb.WriteString("Game Over!\n\n")
-		sb.WriteString(fmt.Sprintf("Final Score: %d\n\n", m.Score))
+		sb.WriteString(fmt.Sprintf("Final Score: %d\n", m.Score))
+		sb.WriteString(fmt.Sprintf("Seed: %d\n\n", m.Seed))
+	}
+	if top := m.Coach.TopPatterns(3); len(top) > 0 {
+		sb.WriteString("Habits to work on:\n")
+		for _, pc := range top {
+			sb.WriteString(fmt.Sprintf("  %-36s ×%d\n", pc.Pattern.String(), pc.Count))
+		}
+		sb.WriteString("\n")
 	}
 	sb.WriteString("Press Enter to return to menu")
 
@@ -0,0 +1,368 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// MutationKind names a way of breaking Go source to make an edit exercise.
+type MutationKind int
+
+const (
+	MutateTypo       MutationKind = iota // misspell an identifier
+	MutateReturnType                     // drop a function's result type
+	MutateSwapArgs                       // swap the first two call arguments
+	MutateBrace                          // drop the opening brace of a block
+	MutateStructTag                      // remove a struct field tag
+	numMutationKinds
+)
+
+var mutationNames = [...]string{"typo", "return-type", "swap-args", "brace", "struct-tag"}
+
+func (k MutationKind) String() string {
+	if k >= 0 && int(k) < len(mutationNames) {
+		return mutationNames[k]
+	}
+	return fmt.Sprintf("MutationKind(%d)", int(k))
+}
+
+// GeneratedExercise is an edit exercise made by mutating Go source. The
+// mutated snippet is the starting buffer and the original is the goal; the
+// exercise's Commands are those the fix is meant to practise, motions
+// included.
+type GeneratedExercise struct {
+	Exercise
+	Kind MutationKind
+}
+
+// maxSnippetLines caps how much of the surrounding declaration is shown.
+const maxSnippetLines = 7
+
+// mutationMotions are the motions every generated exercise allows.
+var mutationMotions = []string{"h", "j", "k", "l", "w", "b", "e", "0", "$", "^", "f{c}", "F{c}"}
+
+// mutation replaces src[start:end] with repl. Mutations never span lines, so
+// the mutated source has the same lines as the original.
+type mutation struct {
+	kind        MutationKind
+	start, end  int
+	repl        string
+	instruction string
+	commands    []string // edit commands for the fix
+	declStart   int      // line range of the enclosing declaration, 0-based
+	declEnd     int
+}
+
+// GenerateEditExercises parses Go source and returns up to n edit exercises,
+// each made by one mutation of the code. Mutation kinds are taken in turn
+// so a set mixes kinds; sites are picked with rng. Every exercise is
+// checked with SolveEdit against its declared commands and dropped if the
+// fix is not possible with them. Tabs are expanded to tabWidth columns.
+func GenerateEditExercises(src []byte, rng *rand.Rand, n, tabWidth int) ([]GeneratedExercise, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	if tabWidth < 1 {
+		tabWidth = DefaultTabWidth
+	}
+
+	cands := make([][]mutation, numMutationKinds)
+	for _, decl := range file.Decls {
+		c := mutationCollector{
+			src:       src,
+			fset:      fset,
+			rng:       rng,
+			declStart: fset.Position(decl.Pos()).Line - 1,
+			declEnd:   fset.Position(decl.End()).Line - 1,
+		}
+		ast.Inspect(decl, c.visit)
+		for _, mu := range c.found {
+			cands[mu.kind] = append(cands[mu.kind], mu)
+		}
+	}
+	for _, list := range cands {
+		rng.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+	}
+
+	orig := sourceLines(src, tabWidth)
+	var out []GeneratedExercise
+	for kind := MutationKind(rng.Intn(int(numMutationKinds))); len(out) < n; kind = (kind + 1) % numMutationKinds {
+		remaining := false
+		for _, list := range cands {
+			remaining = remaining || len(list) > 0
+		}
+		if !remaining {
+			break
+		}
+		if len(cands[kind]) == 0 {
+			continue
+		}
+		mu := cands[kind][0]
+		cands[kind] = cands[kind][1:]
+		if ex, ok := buildMutation(src, orig, mu, tabWidth); ok {
+			out = append(out, ex)
+		}
+	}
+	return out, nil
+}
+
+// sourceLines splits source into sanitized buffer lines.
+func sourceLines(src []byte, tabWidth int) []string {
+	raw := strings.Split(string(src), "\n")
+	lines := make([]string, len(raw))
+	for i, line := range raw {
+		lines[i] = sanitizeLine(strings.TrimSuffix(line, "\r"), tabWidth)
+	}
+	return lines
+}
+
+// buildMutation applies mu and cuts the exercise snippet out of the source.
+func buildMutation(src []byte, orig []string, mu mutation, tabWidth int) (GeneratedExercise, bool) {
+	mutated := make([]byte, 0, len(src)+len(mu.repl))
+	mutated = append(mutated, src[:mu.start]...)
+	mutated = append(mutated, mu.repl...)
+	mutated = append(mutated, src[mu.end:]...)
+	broken := sourceLines(mutated, tabWidth)
+	if len(broken) != len(orig) {
+		return GeneratedExercise{}, false
+	}
+
+	row := bytes.Count(src[:mu.start], []byte("\n"))
+	lo := max(mu.declStart, row-maxSnippetLines/2)
+	hi := min(mu.declEnd, lo+maxSnippetLines-1, len(orig)-1)
+	lo = max(mu.declStart, hi-maxSnippetLines+1)
+
+	goal := dedent(orig[lo : hi+1])
+	init := dedent(broken[lo : hi+1])
+	if slices.Equal(goal, init) {
+		return GeneratedExercise{}, false
+	}
+
+	// Start on whichever end of the snippet is further from the mistake.
+	startRow := 0
+	if row-lo < hi-row {
+		startRow = hi - lo
+	}
+	start := Position{startRow, len(init[startRow]) - len(strings.TrimLeft(init[startRow], " "))}
+	if start.Col >= len(init[startRow]) {
+		start.Col = 0
+	}
+
+	commands := append(append([]string(nil), mutationMotions...), mu.commands...)
+	if !SolveEdit(init, goal, start, commands).Reachable() {
+		return GeneratedExercise{}, false
+	}
+	return GeneratedExercise{
+		Exercise: Exercise{
+			Type:        ExerciseEdit,
+			Instruction: mu.instruction,
+			InitBuffer:  init,
+			GoalBuffer:  goal,
+			StartCursor: start,
+			Commands:    commands,
+		},
+		Kind: mu.kind,
+	}, true
+}
+
+// dedent strips the indentation common to all non-blank lines.
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		out[i] = strings.TrimRight(line, " ")
+	}
+	return out
+}
+
+// mutationCollector finds mutation sites within one declaration.
+type mutationCollector struct {
+	src                []byte
+	fset               *token.FileSet
+	rng                *rand.Rand
+	declStart, declEnd int
+	found              []mutation
+}
+
+func (c *mutationCollector) offset(p token.Pos) int {
+	return c.fset.Position(p).Offset
+}
+
+func (c *mutationCollector) sameLine(a, b token.Pos) bool {
+	return c.fset.Position(a).Line == c.fset.Position(b).Line
+}
+
+func (c *mutationCollector) text(n ast.Node) string {
+	return string(c.src[c.offset(n.Pos()):c.offset(n.End())])
+}
+
+func (c *mutationCollector) add(mu mutation) {
+	mu.declStart, mu.declEnd = c.declStart, c.declEnd
+	c.found = append(c.found, mu)
+}
+
+func (c *mutationCollector) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.Ident:
+		c.typo(n)
+	case *ast.FuncDecl:
+		c.returnType(n.Name.Name, n.Type)
+	case *ast.CallExpr:
+		c.swapArgs(n)
+	case *ast.BlockStmt:
+		c.brace(n)
+	case *ast.Field:
+		c.structTag(n)
+	}
+	return true
+}
+
+// typo misspells an identifier by changing, doubling or dropping a letter.
+func (c *mutationCollector) typo(id *ast.Ident) {
+	name := id.Name
+	if len(name) < 3 || name == "_" {
+		return
+	}
+	var letters []int
+	for i := 1; i < len(name); i++ {
+		if name[i] >= 'a' && name[i] <= 'z' {
+			letters = append(letters, i)
+		}
+	}
+	if len(letters) == 0 {
+		return
+	}
+	i := letters[c.rng.Intn(len(letters))]
+	start := c.offset(id.Pos())
+	mu := mutation{kind: MutateTypo, instruction: fmt.Sprintf("Fix the misspelled %s.", name)}
+	switch c.rng.Intn(3) {
+	case 0:
+		ch := 'a' + (name[i]-'a'+1+byte(c.rng.Intn(24)))%26
+		mu.start, mu.end, mu.repl = start+i, start+i+1, string(ch)
+		mu.commands = []string{"r"}
+	case 1:
+		mu.start, mu.end, mu.repl = start+i, start+i, name[i:i+1]
+		mu.commands = []string{"x"}
+	default:
+		mu.start, mu.end = start+i, start+i+1
+		mu.commands = []string{"i", "a"}
+	}
+	c.add(mu)
+}
+
+// returnType drops a single unnamed result type.
+func (c *mutationCollector) returnType(name string, ft *ast.FuncType) {
+	res := ft.Results
+	if res == nil || len(res.List) != 1 || len(res.List[0].Names) != 0 || res.Opening.IsValid() {
+		return
+	}
+	if !c.sameLine(ft.Params.Closing, res.End()) {
+		return
+	}
+	c.add(mutation{
+		kind:        MutateReturnType,
+		start:       c.offset(ft.Params.Closing) + 1,
+		end:         c.offset(res.End()),
+		instruction: fmt.Sprintf("Restore the return type of %s.", name),
+		commands:    []string{"a"},
+	})
+}
+
+// swapArgs swaps the first two arguments of a call when both are simple.
+func (c *mutationCollector) swapArgs(call *ast.CallExpr) {
+	if len(call.Args) < 2 {
+		return
+	}
+	a, b := call.Args[0], call.Args[1]
+	if !simpleExpr(a) || !simpleExpr(b) || !c.sameLine(a.Pos(), b.End()) {
+		return
+	}
+	at, bt := c.text(a), c.text(b)
+	if at == bt {
+		return
+	}
+	sep := string(c.src[c.offset(a.End()):c.offset(b.Pos())])
+	fun := c.text(call.Fun)
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok && strings.Contains(fun, "\n") {
+		fun = sel.Sel.Name
+	}
+	c.add(mutation{
+		kind:        MutateSwapArgs,
+		start:       c.offset(a.Pos()),
+		end:         c.offset(b.End()),
+		repl:        bt + sep + at,
+		instruction: fmt.Sprintf("Put the arguments to %s back in order.", fun),
+		commands:    []string{"x", "i"},
+	})
+}
+
+func simpleExpr(e ast.Expr) bool {
+	switch e.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return true
+	}
+	return false
+}
+
+// brace drops the " {" that ends a line opening a block.
+func (c *mutationCollector) brace(b *ast.BlockStmt) {
+	if !b.Lbrace.IsValid() || c.sameLine(b.Lbrace, b.Rbrace) {
+		return
+	}
+	off := c.offset(b.Lbrace)
+	if off == 0 || c.src[off-1] != ' ' {
+		return
+	}
+	rest := c.src[off+1:]
+	if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		return
+	}
+	c.add(mutation{
+		kind:        MutateBrace,
+		start:       off - 1,
+		end:         off + 1,
+		instruction: "Put back the missing brace.",
+		commands:    []string{"A"},
+	})
+}
+
+// structTag removes a struct field's tag.
+func (c *mutationCollector) structTag(f *ast.Field) {
+	if f.Tag == nil || !c.sameLine(f.Type.End(), f.Tag.End()) {
+		return
+	}
+	field := "the field"
+	if len(f.Names) > 0 {
+		field = f.Names[0].Name
+	}
+	c.add(mutation{
+		kind:        MutateStructTag,
+		start:       c.offset(f.Type.End()),
+		end:         c.offset(f.Tag.End()),
+		instruction: fmt.Sprintf("Restore the struct tag on %s.", field),
+		commands:    []string{"A"},
+	})
+}
@@ -0,0 +1,76 @@
+package game
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const mutateSource = "package p\n\n" +
+	"type User struct {\n" +
+	"	Name string `json:\"name\"`\n" +
+	"}\n\n" +
+	"func greeting(name string) string {\n" +
+	"	return fmt.Sprintf(\"%s %s\", prefix, name)\n" +
+	"}\n"
+
+func TestGenerateEditExercises(t *testing.T) {
+	exercises, err := GenerateEditExercises([]byte(mutateSource), rand.New(rand.NewSource(1)), 100, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKind := make(map[MutationKind][]GeneratedExercise)
+	for _, ex := range exercises {
+		byKind[ex.Kind] = append(byKind[ex.Kind], ex)
+	}
+
+	for _, tc := range []struct {
+		kind       MutationKind
+		goal, init string // text of the changed goal and starting lines
+		command    string // edit command the fix is meant to practise
+	}{
+		{MutateReturnType, "func greeting(name string) string {", "func greeting(name string) {", "a"},
+		{MutateSwapArgs, `return fmt.Sprintf("%s %s", prefix, name)`, `return fmt.Sprintf(prefix, "%s %s", name)`, "x"},
+		{MutateBrace, "func greeting(name string) string {", "func greeting(name string) string", "A"},
+		{MutateStructTag, "Name string `json:\"name\"`", "Name string", "A"},
+		{MutateTypo, "", "", ""},
+	} {
+		t.Run(tc.kind.String(), func(t *testing.T) {
+			list := byKind[tc.kind]
+			if len(list) == 0 {
+				t.Fatalf("no %v exercises among %d", tc.kind, len(exercises))
+			}
+			for _, ex := range list {
+				if ex.Type != ExerciseEdit || ex.Instruction == "" {
+					t.Errorf("exercise %+v is not an instructed edit", ex.Exercise)
+				}
+				if !SolveEdit(ex.InitBuffer, ex.GoalBuffer, ex.StartCursor, ex.Commands).Reachable() {
+					t.Errorf("fix is not reachable with %q", ex.Commands)
+				}
+				var changed []int
+				for i := range ex.GoalBuffer {
+					if ex.InitBuffer[i] != ex.GoalBuffer[i] {
+						changed = append(changed, i)
+					}
+				}
+				if len(changed) != 1 {
+					t.Fatalf("changed lines %v, want one\n%q\n%q", changed, ex.InitBuffer, ex.GoalBuffer)
+				}
+				goal, init := ex.GoalBuffer[changed[0]], ex.InitBuffer[changed[0]]
+				if tc.kind == MutateTypo {
+					if n := len(goal) - len(init); n < -1 || n > 1 {
+						t.Errorf("typo turned %q into %q", goal, init)
+					}
+					continue
+				}
+				if strings.TrimSpace(goal) != tc.goal || strings.TrimSpace(init) != tc.init {
+					t.Errorf("changed %q to %q, want %q to %q", goal, init, tc.goal, tc.init)
+				}
+				if !slices.Contains(ex.Commands, tc.command) {
+					t.Errorf("commands %q lack %q", ex.Commands, tc.command)
+				}
+			}
+		})
+	}
+}
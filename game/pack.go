@@ -0,0 +1,431 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A pack file declares lessons and challenge levels in a line-based format:
+//
+//	# comments and blank lines are ignored outside blocks
+//	lesson Moving Around          start a tutorial lesson
+//	level Quick Motions           start a challenge level
+//	commands h j k l              lesson NewCommands / level Commands, or
+//	                              the exercise's own Commands after exercise
+//	seed 42                       level only: fixed RNG seed
+//	explanation                   lesson only: intro text block
+//	exercise motion|edit          start an exercise in the current lesson/level
+//	instruction Use h and l.      text shown above the buffer
+//	cursor 1 1                    start cursor as 1-based line and column
+//	targets 5                     motion: number of targets
+//	strategy word                 motion: any, word, line-edge or unique-char
+//	difficulty 3                  motion: desired optimal keystrokes per target
+//	buffer                        block: the starting buffer
+//	goal                          block: edit only, the buffer to reach
+//
+// Blocks (explanation, buffer, goal) take every following line verbatim up to
+// a line that is exactly "end". Inside a block a leading backslash is removed,
+// so "\end" is a literal "end" line and "\\" keeps one backslash.
+
+// PackExt is the file extension of pack files.
+const PackExt = ".pack"
+
+// Pack is a set of lessons and levels loaded from a pack file.
+type Pack struct {
+	File    string
+	Lessons []Lesson
+	Levels  []Level
+}
+
+// srcPos records where a lesson, level or exercise was declared.
+type srcPos struct {
+	file string
+	line int
+}
+
+// PackError is a problem found at a specific line of a pack file.
+type PackError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e PackError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// PackErrors collects every problem found in a pack.
+type PackErrors []PackError
+
+func (e PackErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, pe := range e {
+		msgs[i] = pe.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// packParser holds the state while reading a pack file.
+type packParser struct {
+	pack   Pack
+	errs   PackErrors
+	line   int
+	lesson *Lesson
+	level  *Level
+	ex     *Exercise
+}
+
+func (p *packParser) errorf(format string, args ...any) {
+	p.errs = append(p.errs, PackError{File: p.pack.File, Line: p.line, Msg: fmt.Sprintf(format, args...)})
+}
+
+// finish appends the lesson or level being built to the pack.
+func (p *packParser) finish() {
+	p.ex = nil
+	if p.lesson != nil {
+		p.pack.Lessons = append(p.pack.Lessons, *p.lesson)
+		p.lesson = nil
+	}
+	if p.level != nil {
+		p.pack.Levels = append(p.pack.Levels, *p.level)
+		p.level = nil
+	}
+}
+
+// exercises returns the exercise list of the current lesson or level.
+func (p *packParser) exercises() *[]Exercise {
+	if p.lesson != nil {
+		return &p.lesson.Exercises
+	}
+	if p.level != nil {
+		return &p.level.Exercises
+	}
+	return nil
+}
+
+// ParsePack reads a pack file. name is used in error messages. Syntax and
+// validation problems are returned together as PackErrors.
+func ParsePack(name string, r io.Reader) (Pack, error) {
+	p := &packParser{pack: Pack{File: name}}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	// readBlock consumes lines up to "end" and returns them.
+	readBlock := func() ([]string, bool) {
+		start := p.line
+		var lines []string
+		for sc.Scan() {
+			p.line++
+			text := strings.TrimSuffix(sc.Text(), "\r")
+			if text == "end" {
+				return lines, true
+			}
+			lines = append(lines, strings.TrimPrefix(text, `\`))
+		}
+		p.line = start
+		p.errorf("block is missing its closing \"end\"")
+		return lines, false
+	}
+
+	for sc.Scan() {
+		p.line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		keyword, arg, _ := strings.Cut(text, " ")
+		arg = strings.TrimSpace(arg)
+
+		switch keyword {
+		case "lesson":
+			p.finish()
+			p.lesson = &Lesson{Number: len(p.pack.Lessons) + 1, Name: arg, src: srcPos{name, p.line}}
+		case "level":
+			p.finish()
+			p.level = &Level{Name: arg, src: srcPos{name, p.line}}
+		case "commands":
+			switch {
+			case p.ex != nil:
+				p.ex.Commands = strings.Fields(arg)
+			case p.lesson != nil:
+				p.lesson.NewCommands = strings.Fields(arg)
+			case p.level != nil:
+				p.level.Commands = strings.Fields(arg)
+			default:
+				p.errorf("commands outside a lesson or level")
+			}
+		case "seed":
+			if p.level == nil {
+				p.errorf("seed is only valid in a level")
+				continue
+			}
+			seed, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				p.errorf("invalid seed %q", arg)
+				continue
+			}
+			p.level.Seed = seed
+		case "explanation":
+			lines, ok := readBlock()
+			if !ok {
+				return p.pack, p.errs
+			}
+			if p.lesson == nil {
+				p.errorf("explanation is only valid in a lesson")
+				continue
+			}
+			p.lesson.Explanation = strings.Join(lines, "\n")
+		case "exercise":
+			list := p.exercises()
+			if list == nil {
+				p.errorf("exercise outside a lesson or level")
+				continue
+			}
+			ex := Exercise{src: srcPos{name, p.line}}
+			switch arg {
+			case "motion":
+				ex.Type = ExerciseMotion
+			case "edit":
+				ex.Type = ExerciseEdit
+			default:
+				p.errorf("unknown exercise type %q (want motion or edit)", arg)
+			}
+			*list = append(*list, ex)
+			p.ex = &(*list)[len(*list)-1]
+		case "instruction", "cursor", "targets", "strategy", "difficulty", "buffer", "goal":
+			var block []string
+			if keyword == "buffer" || keyword == "goal" {
+				lines, ok := readBlock()
+				if !ok {
+					return p.pack, p.errs
+				}
+				block = lines
+			}
+			if p.ex == nil {
+				p.errorf("%s outside an exercise", keyword)
+				continue
+			}
+			p.exerciseField(keyword, arg, block)
+		default:
+			p.errorf("unknown directive %q", keyword)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		p.errorf("%v", err)
+	}
+	p.finish()
+
+	p.errs = append(p.errs, ValidatePack(p.pack)...)
+	sort.SliceStable(p.errs, func(i, j int) bool { return p.errs[i].Line < p.errs[j].Line })
+	if len(p.errs) > 0 {
+		return p.pack, p.errs
+	}
+	return p.pack, nil
+}
+
+// exerciseField sets one exercise directive on the current exercise.
+func (p *packParser) exerciseField(keyword, arg string, block []string) {
+	ex := p.ex
+	switch keyword {
+	case "instruction":
+		ex.Instruction = arg
+	case "cursor":
+		var line, col int
+		if _, err := fmt.Sscanf(arg, "%d %d", &line, &col); err != nil {
+			p.errorf("invalid cursor %q (want \"line col\")", arg)
+			return
+		}
+		ex.StartCursor = Position{line - 1, col - 1}
+	case "targets", "difficulty":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			p.errorf("invalid %s %q", keyword, arg)
+			return
+		}
+		if keyword == "targets" {
+			ex.NumTargets = n
+		} else {
+			ex.Difficulty = n
+		}
+	case "strategy":
+		s, ok := ParseTargetStrategy(arg)
+		if !ok {
+			p.errorf("unknown target strategy %q", arg)
+			return
+		}
+		ex.Targets = s
+	case "buffer":
+		ex.InitBuffer = block
+	case "goal":
+		ex.GoalBuffer = block
+	}
+}
+
+// ValidatePack checks lessons, levels and exercises for content that cannot
+// be played, such as a start cursor outside the buffer or an edit exercise
+// without a goal.
+func ValidatePack(pack Pack) PackErrors {
+	var errs PackErrors
+	at := func(pos srcPos, format string, args ...any) {
+		errs = append(errs, PackError{File: pos.file, Line: pos.line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	checkExercises := func(kind, name string, pos srcPos, exercises []Exercise) {
+		if name == "" {
+			at(pos, "%s has no name", kind)
+		}
+		if len(exercises) == 0 {
+			at(pos, "%s %q has no exercises", kind, name)
+		}
+		for i, ex := range exercises {
+			where := fmt.Sprintf("%s %q exercise %d", kind, name, i+1)
+			if len(ex.InitBuffer) == 0 {
+				at(ex.src, "%s: empty buffer", where)
+				continue
+			}
+			cur := ex.StartCursor
+			if cur.Row < 0 || cur.Row >= len(ex.InitBuffer) {
+				at(ex.src, "%s: start cursor line %d is outside the buffer (%d lines)", where, cur.Row+1, len(ex.InitBuffer))
+			} else if maxCol := max(len(ex.InitBuffer[cur.Row]), 1); cur.Col < 0 || cur.Col >= maxCol {
+				at(ex.src, "%s: start cursor column %d is outside line %d (%d columns)", where, cur.Col+1, cur.Row+1, maxCol)
+			}
+			switch ex.Type {
+			case ExerciseMotion:
+				if ex.NumTargets < 1 {
+					at(ex.src, "%s: motion exercise needs at least one target", where)
+				}
+				if ex.GoalBuffer != nil {
+					at(ex.src, "%s: goal is only used by edit exercises", where)
+				}
+			case ExerciseEdit:
+				if len(ex.GoalBuffer) == 0 {
+					at(ex.src, "%s: edit exercise has an empty goal", where)
+				} else if strings.Join(ex.GoalBuffer, "\n") == strings.Join(ex.InitBuffer, "\n") {
+					at(ex.src, "%s: goal is identical to the buffer", where)
+				}
+			}
+		}
+	}
+
+	for _, l := range pack.Lessons {
+		checkExercises("lesson", l.Name, l.src, l.Exercises)
+	}
+	for _, l := range pack.Levels {
+		checkExercises("level", l.Name, l.src, l.Exercises)
+	}
+	return errs
+}
+
+// LoadPackFile reads and validates a single pack file.
+func LoadPackFile(path string) (Pack, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Pack{File: path}, err
+	}
+	defer f.Close()
+	return ParsePack(path, f)
+}
+
+// LoadPackDir loads every pack file in dir, in name order, and merges them.
+// A missing directory is not an error. A file with problems is left out of
+// the merged pack and its PackErrors are returned alongside it.
+func LoadPackDir(dir string) (Pack, error) {
+	merged := Pack{File: dir}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+PackExt))
+	if err != nil {
+		return merged, err
+	}
+	sort.Strings(paths)
+	var errs PackErrors
+	for _, path := range paths {
+		pack, err := LoadPackFile(path)
+		if err != nil {
+			if pe, ok := err.(PackErrors); ok {
+				errs = append(errs, pe...)
+				continue
+			}
+			return merged, err
+		}
+		merged.Lessons = append(merged.Lessons, pack.Lessons...)
+		merged.Levels = append(merged.Levels, pack.Levels...)
+	}
+	if len(errs) > 0 {
+		return merged, errs
+	}
+	return merged, nil
+}
+
+// WritePack writes a pack in the pack file format.
+func WritePack(w io.Writer, pack Pack) error {
+	bw := bufio.NewWriter(w)
+	block := func(keyword string, lines []string) {
+		fmt.Fprintln(bw, keyword)
+		for _, line := range lines {
+			if line == "end" || strings.HasPrefix(line, `\`) {
+				line = `\` + line
+			}
+			fmt.Fprintln(bw, line)
+		}
+		fmt.Fprintln(bw, "end")
+	}
+	writeExercises := func(exercises []Exercise) {
+		for _, ex := range exercises {
+			fmt.Fprintln(bw)
+			if ex.Type == ExerciseEdit {
+				fmt.Fprintln(bw, "exercise edit")
+			} else {
+				fmt.Fprintln(bw, "exercise motion")
+			}
+			fmt.Fprintln(bw, "instruction", ex.Instruction)
+			if len(ex.Commands) > 0 {
+				fmt.Fprintln(bw, "commands", strings.Join(ex.Commands, " "))
+			}
+			fmt.Fprintln(bw, "cursor", ex.StartCursor.Row+1, ex.StartCursor.Col+1)
+			if ex.Type == ExerciseMotion {
+				fmt.Fprintln(bw, "targets", ex.NumTargets)
+				if ex.Targets != TargetAny {
+					fmt.Fprintln(bw, "strategy", ex.Targets)
+				}
+				if ex.Difficulty > 0 {
+					fmt.Fprintln(bw, "difficulty", ex.Difficulty)
+				}
+			}
+			block("buffer", ex.InitBuffer)
+			if ex.Type == ExerciseEdit {
+				block("goal", ex.GoalBuffer)
+			}
+		}
+	}
+
+	for i, l := range pack.Lessons {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintln(bw, "lesson", l.Name)
+		if len(l.NewCommands) > 0 {
+			fmt.Fprintln(bw, "commands", strings.Join(l.NewCommands, " "))
+		}
+		block("explanation", strings.Split(l.Explanation, "\n"))
+		writeExercises(l.Exercises)
+	}
+	for i, l := range pack.Levels {
+		if i > 0 || len(pack.Lessons) > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintln(bw, "level", l.Name)
+		if len(l.Commands) > 0 {
+			fmt.Fprintln(bw, "commands", strings.Join(l.Commands, " "))
+		}
+		if l.Seed != 0 {
+			fmt.Fprintln(bw, "seed", l.Seed)
+		}
+		writeExercises(l.Exercises)
+	}
+	return bw.Flush()
+}
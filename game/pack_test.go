@@ -0,0 +1,105 @@
+package game
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePackErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		errs []string // expected errors, in order
+	}{
+		{"unknown directive", "level L\nbogus 1\n", []string{
+			"test.pack:1: level \"L\" has no exercises",
+			"test.pack:2: unknown directive \"bogus\"",
+		}},
+		{"exercise outside", "exercise motion\n", []string{
+			"test.pack:1: exercise outside a lesson or level",
+		}},
+		{"field outside exercise", "level L\ntargets 3\n", []string{
+			"test.pack:1: level \"L\" has no exercises",
+			"test.pack:2: targets outside an exercise",
+		}},
+		{"bad cursor", "level L\nexercise motion\ncursor one\ntargets 1\nbuffer\nab\nend\n", []string{
+			"test.pack:3: invalid cursor \"one\" (want \"line col\")",
+		}},
+		{"cursor outside buffer", "level L\nexercise motion\ncursor 3 1\ntargets 1\nbuffer\nab\nend\n", []string{
+			"test.pack:2: level \"L\" exercise 1: start cursor line 3 is outside the buffer (1 lines)",
+		}},
+		{"unterminated block", "level L\nexercise edit\nbuffer\nab\n", []string{
+			"test.pack:3: block is missing its closing \"end\"",
+		}},
+		{"edit without goal", "level L\nexercise edit\nbuffer\nab\nend\n", []string{
+			"test.pack:2: level \"L\" exercise 1: edit exercise has an empty goal",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParsePack("test.pack", strings.NewReader(tc.text))
+			errs, ok := err.(PackErrors)
+			if !ok {
+				t.Fatalf("error %v, want PackErrors", err)
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if !reflect.DeepEqual(got, tc.errs) {
+				t.Errorf("errors\n%q\nwant\n%q", got, tc.errs)
+			}
+		})
+	}
+}
+
+// TestWritePackExerciseCommands checks that an exercise's own commands
+// survive a round trip and count towards the level's hash.
+func TestWritePackExerciseCommands(t *testing.T) {
+	pack := parseTestPack(t, "test.pack", strings.Replace(editLevel, "exercise edit\n", "exercise edit\ncommands w x\n", 1))
+	level := pack.Levels[0]
+	if got := level.Exercises[0].Commands; !reflect.DeepEqual(got, []string{"w", "x"}) {
+		t.Fatalf("exercise commands %q, want [w x]", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePack(&buf, pack); err != nil {
+		t.Fatal(err)
+	}
+	again := parseTestPack(t, "again.pack", buf.String())
+	if got := again.Levels[0].Exercises[0].Commands; !reflect.DeepEqual(got, []string{"w", "x"}) {
+		t.Errorf("written exercise commands %q, want [w x]\n%s", got, buf.String())
+	}
+
+	plain := level
+	plain.Exercises = []Exercise{level.Exercises[0]}
+	plain.Exercises[0].Commands = nil
+	if level.Hash() == plain.Hash() {
+		t.Error("hash ignores the exercise's commands")
+	}
+}
+
+func TestLoadPackDirSkipsBrokenPacks(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.pack", editLevel)
+	write("b.pack", "level Broken\nbogus\n")
+
+	pack, err := LoadPackDir(dir)
+	if _, ok := err.(PackErrors); !ok {
+		t.Fatalf("error %v, want PackErrors", err)
+	}
+	if !strings.Contains(err.Error(), "b.pack:2: unknown directive") {
+		t.Errorf("error %q does not name the broken line", err)
+	}
+	if len(pack.Levels) != 1 || pack.Levels[0].Name != "Edits" {
+		t.Errorf("levels %v, want only the good pack's", pack.Levels)
+	}
+}
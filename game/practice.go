@@ -0,0 +1,143 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Limits applied to practice files so huge or minified files stay playable.
+const (
+	MaxPracticeLines       = 5000
+	MaxPracticeLineLen     = 1000
+	DefaultTabWidth        = 4
+	DefaultPracticeTargets = 10
+)
+
+// LoadPracticeFile reads a source file into buffer lines. Tabs are expanded
+// to tabWidth columns, carriage returns are dropped and bytes the engine
+// cannot address as single columns (non-ASCII, control characters) become
+// '?'. Lines longer than MaxPracticeLineLen are cut off.
+func LoadPracticeFile(path string, tabWidth int) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if tabWidth < 1 {
+		tabWidth = DefaultTabWidth
+	}
+	raw := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	if len(raw) > MaxPracticeLines {
+		return nil, fmt.Errorf("%s: %d lines is too long to practice on (max %d)", path, len(raw), MaxPracticeLines)
+	}
+
+	lines := make([]string, len(raw))
+	hasText := false
+	for i, line := range raw {
+		lines[i] = sanitizeLine(strings.TrimSuffix(line, "\r"), tabWidth)
+		if strings.TrimSpace(lines[i]) != "" {
+			hasText = true
+		}
+	}
+	if !hasText {
+		return nil, fmt.Errorf("%s: file has no text to practice on", path)
+	}
+	return lines, nil
+}
+
+// sanitizeLine expands tabs and replaces characters the buffer cannot hold.
+func sanitizeLine(line string, tabWidth int) string {
+	var sb strings.Builder
+	col := 0
+	for _, r := range line {
+		if col >= MaxPracticeLineLen {
+			break
+		}
+		switch {
+		case r == '\t':
+			n := tabWidth - col%tabWidth
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+		case r < 0x20 || r > 0x7e:
+			sb.WriteByte('?')
+			col++
+		default:
+			sb.WriteRune(r)
+			col++
+		}
+	}
+	out := sb.String()
+	if len(out) > MaxPracticeLineLen {
+		out = out[:MaxPracticeLineLen]
+	}
+	return out
+}
+
+// PracticeSpec identifies a practice session so it can be rebuilt, e.g.
+// when a replay of it is played back.
+type PracticeSpec struct {
+	File       string `json:"file"`
+	TabWidth   int    `json:"tab_width"`
+	Targets    int    `json:"targets"`
+	Difficulty int    `json:"difficulty"`
+	Edits      int    `json:"edits,omitempty"` // Go files: generated edit exercises
+}
+
+// Level loads the practice file and builds a challenge level over it: a
+// motion exercise on the whole file, then for Go files up to Edits edit
+// exercises generated from it with the given seed.
+func (s PracticeSpec) Level(seed int64) (Level, error) {
+	lines, err := LoadPracticeFile(s.File, s.TabWidth)
+	if err != nil {
+		return Level{}, err
+	}
+	var edits []GeneratedExercise
+	if s.Edits > 0 && filepath.Ext(s.File) == ".go" {
+		src, err := os.ReadFile(s.File)
+		if err != nil {
+			return Level{}, err
+		}
+		edits, err = GenerateEditExercises(src, rand.New(rand.NewSource(seed)), s.Edits, s.TabWidth)
+		if err != nil {
+			return Level{}, fmt.Errorf("generate edit exercises: %w", err)
+		}
+	}
+	targets := s.Targets
+	if targets < 1 {
+		targets = DefaultPracticeTargets
+	}
+	level := Level{
+		Name:     "Practice: " + filepath.Base(s.File),
+		Commands: allCommands(),
+		Exercises: []Exercise{
+			{
+				Type:        ExerciseMotion,
+				Instruction: "Navigate your own code — hit each target.",
+				InitBuffer:  lines,
+				StartCursor: Position{0, 0},
+				NumTargets:  targets,
+				Difficulty:  s.Difficulty,
+			},
+		},
+	}
+	for _, g := range edits {
+		level.Exercises = append(level.Exercises, g.Exercise)
+	}
+	return level, nil
+}
+
+// StartPractice starts a scored challenge run over a practice file.
+func (m *Model) StartPractice(spec PracticeSpec) error {
+	seed := m.runSeed()
+	level, err := spec.Level(seed)
+	if err != nil {
+		return err
+	}
+	m.Levels = []Level{level}
+	m.Daily = ""
+	m.Practice = spec
+	m.beginRun(GameModeMotionChallenge, 0, 0, seed)
+	return nil
+}
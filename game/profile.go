@@ -0,0 +1,334 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"vimgame/store"
+)
+
+// ProfileVersion is the current profile schema version. Bump it when the
+// schema changes and teach migrate to upgrade older files.
+//
+//	1: single unnamed profile
+//	2: named profiles
+//	3: command stats
+//	4: review schedule
+//	5: settings
+//	6: coaching habits
+const ProfileVersion = 6
+
+// Profile is one player's persistent progress and settings.
+type Profile struct {
+	Version  int                      `json:"version"`
+	Name     string                   `json:"name"`
+	Lessons  map[string]*LessonRecord `json:"lessons"` // keyed by lesson name
+	Levels   map[string]*LevelRecord  `json:"levels"`  // keyed by level name
+	Totals   ProfileTotals            `json:"totals"`
+	Resume   *ResumePoint             `json:"resume,omitempty"`
+	Stats    *Stats                   `json:"stats"`
+	Review   map[string]*ReviewCard   `json:"review"`             // keyed by command
+	Settings *Config                  `json:"settings,omitempty"` // nil until changed; the config file applies until then
+	Updated  time.Time                `json:"updated"`
+}
+
+// LessonRecord is the player's record for one tutorial lesson.
+type LessonRecord struct {
+	Completed   bool      `json:"completed"`
+	CompletedAt time.Time `json:"completed_at,omitzero"`
+	BestMedal   Medal     `json:"best_medal"`
+	Completions int       `json:"completions"`
+}
+
+// LevelRecord is the player's record for one challenge level or mode.
+type LevelRecord struct {
+	BestScore   int           `json:"best_score"`
+	BestTime    time.Duration `json:"best_time,omitempty"` // fastest completion
+	BestMedal   Medal         `json:"best_medal"`
+	Completions int           `json:"completions"`
+	LastPlayed  time.Time     `json:"last_played,omitzero"`
+}
+
+// ProfileTotals are lifetime totals across all modes.
+type ProfileTotals struct {
+	LessonsCompleted int           `json:"lessons_completed"`
+	LevelsCompleted  int           `json:"levels_completed"`
+	TargetsHit       int           `json:"targets_hit"`
+	Score            int           `json:"score"`
+	PlayTime         time.Duration `json:"play_time"`
+}
+
+// ResumePoint is where "continue" picks up: the lesson or challenge level
+// the player was last on.
+type ResumePoint struct {
+	Mode GameModeType `json:"mode"`
+	Name string       `json:"name"`
+}
+
+// NewProfile returns an empty profile.
+func NewProfile(name string) *Profile {
+	return &Profile{
+		Version: ProfileVersion,
+		Name:    name,
+		Lessons: make(map[string]*LessonRecord),
+		Levels:  make(map[string]*LevelRecord),
+		Stats:   NewStats(),
+		Review:  make(map[string]*ReviewCard),
+	}
+}
+
+// SchemaVersion implements store.Versioned.
+func (p *Profile) SchemaVersion() int { return p.Version }
+
+// LoadProfile reads a profile file.
+func LoadProfile(path string) (*Profile, error) {
+	p := NewProfile("")
+	if err := store.LoadJSON(path, p); err != nil {
+		return nil, err
+	}
+	if err := store.CheckVersion(path, p, ProfileVersion); err != nil {
+		return nil, err
+	}
+	p.migrate()
+	return p, nil
+}
+
+// migrate upgrades a profile loaded from an older schema version.
+func (p *Profile) migrate() {
+	if p.Version < 2 && p.Name == "" {
+		p.Name = DefaultProfileName
+	}
+	if p.Lessons == nil {
+		p.Lessons = make(map[string]*LessonRecord)
+	}
+	if p.Levels == nil {
+		p.Levels = make(map[string]*LevelRecord)
+	}
+	if p.Stats == nil {
+		p.Stats = NewStats()
+	}
+	if p.Stats.Commands == nil {
+		p.Stats.Commands = make(map[string]*CommandStat)
+	}
+	if p.Stats.Habits == nil {
+		p.Stats.Habits = make(map[AntiPattern]int)
+	}
+	if p.Review == nil {
+		p.Review = make(map[string]*ReviewCard)
+	}
+	p.Version = ProfileVersion
+}
+
+// Save writes the profile atomically.
+func (p *Profile) Save(path string) error {
+	p.Updated = time.Now()
+	return store.SaveJSON(path, p)
+}
+
+func (p *Profile) lesson(name string) *LessonRecord {
+	rec := p.Lessons[name]
+	if rec == nil {
+		rec = &LessonRecord{BestMedal: MedalNone}
+		p.Lessons[name] = rec
+	}
+	return rec
+}
+
+func (p *Profile) level(name string) *LevelRecord {
+	rec := p.Levels[name]
+	if rec == nil {
+		rec = &LevelRecord{BestMedal: MedalNone}
+		p.Levels[name] = rec
+	}
+	return rec
+}
+
+// LessonDone reports whether the named lesson has been completed.
+func (p *Profile) LessonDone(name string) bool {
+	rec := p.Lessons[name]
+	return rec != nil && rec.Completed
+}
+
+// overallMedal grades a lesson or level by the average score of its medals.
+func overallMedal(medals []Medal) Medal {
+	if len(medals) == 0 {
+		return MedalNone
+	}
+	total := 0
+	for _, m := range medals {
+		total += ScoreForMedal(m)
+	}
+	avg := total / len(medals)
+	for _, m := range []Medal{MedalDiamond, MedalGold, MedalSilver} {
+		if avg >= ScoreForMedal(m) {
+			return m
+		}
+	}
+	return MedalBronze
+}
+
+// --- Model integration ---
+
+// trackProfile updates the profile and leaderboards after a key moved the
+// model from prev to m: it remembers where to resume when a lesson or level
+// starts and records results when one is finished.
+func (m *Model) trackProfile(prev Model) {
+	switch {
+	case m.GameMode == GameModeTutorial && m.State == StateLessonIntro &&
+		(prev.State != StateLessonIntro || prev.LessonIndex != m.LessonIndex):
+		m.startLevelTracking()
+		if m.Profile != nil {
+			m.Profile.Resume = &ResumePoint{Mode: GameModeTutorial, Name: m.Lessons[m.LessonIndex].Name}
+			m.saveProfile()
+		}
+
+	case m.GameMode != GameModeTutorial && m.State == StatePlaying && m.ExIndex == 0 &&
+		(prev.State != StatePlaying || prev.LevelIndex != m.LevelIndex || prev.GameMode != m.GameMode):
+		m.startLevelTracking()
+		if m.Profile != nil && m.catalogRun() {
+			m.Profile.Resume = &ResumePoint{Mode: GameModeMotionChallenge, Name: m.Levels[m.LevelIndex].Name}
+			m.saveProfile()
+		}
+
+	case m.State == StateLevelComplete && prev.State != StateLevelComplete:
+		if m.GameMode == GameModeReview && m.Profile != nil {
+			m.gradeReview(time.Now())
+		}
+		m.recordLevel(true)
+
+	case m.State == StateGameOver && prev.State == StatePlaying:
+		// Endless runs and timed-out edit challenges end without completing
+		m.recordLevel(false)
+
+	case prev.State == StatePlaying && (m.State == StateMenu || m.State == StateTutorialMenu):
+		// Keep the command stats of an abandoned run
+		m.saveProfile()
+	}
+}
+
+// catalogRun reports whether the run plays the regular challenge levels.
+func (m *Model) catalogRun() bool {
+	return m.GameMode == GameModeMotionChallenge && m.Daily == "" && m.Practice.File == ""
+}
+
+// startLevelTracking resets the per-level figures recorded in the profile.
+func (m *Model) startLevelTracking() {
+	m.LevelMedals = nil
+	m.LevelScoreStart = m.Score
+	m.LevelTimeStart = m.Elapsed
+	m.LevelKeyStart = len(m.Replay.Keys)
+	m.LevelKeystrokes = 0
+}
+
+// recordLevel records the lesson or level just played.
+func (m *Model) recordLevel(completed bool) {
+	score := m.Score - m.LevelScoreStart
+	elapsed := m.Elapsed - m.LevelTimeStart
+	m.recordLeaderboard(completed, score, elapsed)
+
+	p := m.Profile
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	medal := overallMedal(m.LevelMedals)
+
+	p.Totals.TargetsHit += len(m.LevelMedals)
+	p.Totals.Score += score
+	p.Totals.PlayTime += elapsed
+
+	if m.GameMode == GameModeTutorial {
+		lesson := m.Lessons[m.LessonIndex]
+		rec := p.lesson(lesson.Name)
+		if !rec.Completed {
+			p.Totals.LessonsCompleted++
+		}
+		rec.Completed = true
+		rec.CompletedAt = now
+		rec.Completions++
+		rec.BestMedal = min(rec.BestMedal, medal)
+		p.scheduleLesson(lesson, reviewDate(now, 1))
+		p.Resume = nil
+		if m.LessonIndex+1 < len(m.Lessons) {
+			p.Resume = &ResumePoint{Mode: GameModeTutorial, Name: m.Lessons[m.LessonIndex+1].Name}
+		}
+	} else {
+		level := m.Levels[m.LevelIndex]
+		rec := p.level(level.Name)
+		rec.LastPlayed = now
+		rec.BestScore = max(rec.BestScore, score)
+		rec.BestMedal = min(rec.BestMedal, medal)
+		if completed {
+			p.Totals.LevelsCompleted++
+			rec.Completions++
+			if rec.BestTime == 0 || elapsed < rec.BestTime {
+				rec.BestTime = elapsed
+			}
+		}
+		if m.catalogRun() && completed {
+			p.Resume = nil
+			if m.LevelIndex+1 < len(m.Levels) {
+				p.Resume = &ResumePoint{Mode: GameModeMotionChallenge, Name: m.Levels[m.LevelIndex+1].Name}
+			}
+		}
+	}
+	m.saveProfile()
+}
+
+func (m *Model) saveProfile() {
+	if m.Profiles == nil || m.Profile == nil {
+		return
+	}
+	m.ProfileErr = m.Profiles.Save(m.Profile)
+}
+
+// resumeLabel describes the resume point for the menu, or "" if there is
+// nothing to continue.
+func (m Model) resumeLabel() string {
+	if m.Profile == nil || m.Profile.Resume == nil {
+		return ""
+	}
+	r := m.Profile.Resume
+	if r.Mode == GameModeTutorial {
+		for _, l := range m.Lessons {
+			if l.Name == r.Name {
+				return fmt.Sprintf("Lesson %d: %s", l.Number, l.Name)
+			}
+		}
+		return ""
+	}
+	for i, l := range m.LevelCatalog {
+		if l.Name == r.Name {
+			return fmt.Sprintf("Level %d: %s", i+1, l.Name)
+		}
+	}
+	return ""
+}
+
+// continueRun starts the lesson or level the profile says to resume.
+func (m *Model) continueRun() bool {
+	if m.resumeLabel() == "" {
+		return false
+	}
+	r := m.Profile.Resume
+	if r.Mode == GameModeTutorial {
+		for i, l := range m.Lessons {
+			if l.Name == r.Name {
+				m.MenuCursor = i
+				m.beginRun(GameModeTutorial, i, 0, m.runSeed())
+				return true
+			}
+		}
+		return false
+	}
+	for i, l := range m.LevelCatalog {
+		if l.Name == r.Name {
+			m.Levels = m.LevelCatalog
+			m.Daily = ""
+			m.Practice = PracticeSpec{}
+			m.beginRun(GameModeMotionChallenge, 0, i, m.runSeed())
+			return true
+		}
+	}
+	return false
+}
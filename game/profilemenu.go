@@ -0,0 +1,242 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ProfileEditMode is what the profile menu's text prompt is for.
+type ProfileEditMode int
+
+const (
+	ProfileEditNone   ProfileEditMode = iota
+	ProfileEditNew                    // typing a new profile's name
+	ProfileEditRename                 // typing a new name for the highlighted profile
+	ProfileEditDelete                 // confirming deletion of the highlighted profile
+)
+
+// UseProfile makes the named profile current, creating it if needed, and
+// puts its settings into effect.
+func (m *Model) UseProfile(name string) error {
+	p, err := m.Profiles.LoadOrCreate(name)
+	if err != nil {
+		return err
+	}
+	m.setProfile(p)
+	return nil
+}
+
+// setProfile makes p, which may be nil, the current profile and puts its
+// settings into effect.
+func (m *Model) setProfile(p *Profile) {
+	m.Profile = p
+	m.ProfileErr = nil
+	m.Coach.Totals = nil
+	if p != nil {
+		m.Coach.Totals = p.Stats.Habits
+	}
+	m.ConfigErr = m.applyConfig()
+}
+
+// OpenProfileMenu shows the profile menu. With no profiles yet it starts
+// by asking for a name.
+func (m *Model) OpenProfileMenu() {
+	m.State = StateProfileMenu
+	m.ProfileEdit = ProfileEditNone
+	m.ProfileInput = ""
+	m.ProfileMsg = ""
+	m.refreshProfiles()
+	if len(m.ProfileNames) == 0 {
+		m.ProfileEdit = ProfileEditNew
+	}
+}
+
+func (m *Model) refreshProfiles() {
+	names, err := m.Profiles.List()
+	if err != nil {
+		m.ProfileMsg = err.Error()
+	}
+	m.ProfileNames = names
+	m.ProfileCursor = min(m.ProfileCursor, max(len(names)-1, 0))
+	if m.Profile != nil {
+		for i, name := range names {
+			if name == m.Profile.Name {
+				m.ProfileCursor = i
+			}
+		}
+	}
+}
+
+// typingProfileName reports whether keys are going to the name prompt.
+func (m Model) typingProfileName() bool {
+	return m.State == StateProfileMenu && (m.ProfileEdit == ProfileEditNew || m.ProfileEdit == ProfileEditRename)
+}
+
+func (m Model) handleProfileMenuInput(key string) (tea.Model, tea.Cmd) {
+	switch m.ProfileEdit {
+	case ProfileEditNew, ProfileEditRename:
+		return m.handleProfileNameInput(key)
+	case ProfileEditDelete:
+		if key == "y" {
+			name := m.ProfileNames[m.ProfileCursor]
+			if err := m.Profiles.Delete(name); err != nil {
+				m.ProfileMsg = err.Error()
+			} else {
+				m.ProfileMsg = fmt.Sprintf("Deleted %s", name)
+				if m.Profile != nil && m.Profile.Name == name {
+					m.setProfile(nil)
+				}
+			}
+			m.refreshProfiles()
+		}
+		m.ProfileEdit = ProfileEditNone
+		return m, nil
+	}
+
+	m.ProfileMsg = ""
+	switch key {
+	case "j", "down":
+		if m.ProfileCursor < len(m.ProfileNames)-1 {
+			m.ProfileCursor++
+		}
+	case "k", "up":
+		if m.ProfileCursor > 0 {
+			m.ProfileCursor--
+		}
+	case "enter":
+		if len(m.ProfileNames) > 0 {
+			if err := m.UseProfile(m.ProfileNames[m.ProfileCursor]); err != nil {
+				m.ProfileMsg = err.Error()
+				return m, nil
+			}
+			m.State = StateMenu
+		}
+	case "n":
+		m.ProfileEdit, m.ProfileInput = ProfileEditNew, ""
+	case "r":
+		if len(m.ProfileNames) > 0 {
+			m.ProfileEdit, m.ProfileInput = ProfileEditRename, m.ProfileNames[m.ProfileCursor]
+		}
+	case "d":
+		if len(m.ProfileNames) > 0 {
+			m.ProfileEdit = ProfileEditDelete
+		}
+	case "esc":
+		if m.Profile != nil {
+			m.State = StateMenu
+		}
+	}
+	return m, nil
+}
+
+func (m Model) handleProfileNameInput(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "esc":
+		m.ProfileEdit = ProfileEditNone
+		return m, nil
+	case "backspace":
+		if m.ProfileInput != "" {
+			m.ProfileInput = m.ProfileInput[:len(m.ProfileInput)-1]
+		}
+		return m, nil
+	case "enter":
+	default:
+		if len(key) == 1 && key[0] >= ' ' && key[0] <= '~' && len(m.ProfileInput) < maxProfileName {
+			m.ProfileInput += key
+		}
+		return m, nil
+	}
+
+	if m.ProfileEdit == ProfileEditNew {
+		p, err := m.Profiles.Create(m.ProfileInput)
+		if err != nil {
+			m.ProfileMsg = err.Error()
+			return m, nil
+		}
+		m.setProfile(p)
+		m.ProfileEdit = ProfileEditNone
+		m.State = StateMenu
+		return m, nil
+	}
+
+	oldName := m.ProfileNames[m.ProfileCursor]
+	p, err := m.Profiles.Rename(oldName, m.ProfileInput)
+	if err != nil {
+		m.ProfileMsg = err.Error()
+		return m, nil
+	}
+	if m.Profile != nil && m.Profile.Name == oldName {
+		m.setProfile(p)
+	}
+	m.ProfileEdit = ProfileEditNone
+	m.ProfileMsg = fmt.Sprintf("Renamed %s to %s", oldName, p.Name)
+	m.refreshProfiles()
+	return m, nil
+}
+
+func (m Model) viewProfileMenu() string {
+	th := ui.CurrentTheme()
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(th.Title).
+		Padding(1, 2)
+
+	nameStyle := lipgloss.NewStyle().
+		Foreground(th.Text)
+
+	selectedStyle := nameStyle.Bold(true).Foreground(th.Title)
+
+	infoStyle := lipgloss.NewStyle().
+		Foreground(th.Dim)
+
+	promptStyle := lipgloss.NewStyle().
+		Foreground(th.Highlight).
+		Bold(true)
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Who's Playing?"))
+	sb.WriteString("\n\n")
+
+	for i, name := range m.ProfileNames {
+		marker, label := "  ", nameStyle.Render(name)
+		if i == m.ProfileCursor {
+			marker, label = "▸ ", selectedStyle.Render(name)
+		}
+		current := ""
+		if m.Profile != nil && m.Profile.Name == name {
+			current = infoStyle.Render("  (current)")
+		}
+		sb.WriteString("  " + marker + label + current + "\n")
+	}
+	if len(m.ProfileNames) == 0 {
+		sb.WriteString(infoStyle.Render("    No profiles yet.") + "\n")
+	}
+	sb.WriteString("\n")
+
+	switch m.ProfileEdit {
+	case ProfileEditNew:
+		sb.WriteString("  " + promptStyle.Render("New profile name: ") + m.ProfileInput + "█\n")
+		sb.WriteString(infoStyle.Render("  Enter: create  •  ESC: cancel"))
+	case ProfileEditRename:
+		sb.WriteString("  " + promptStyle.Render("Rename to: ") + m.ProfileInput + "█\n")
+		sb.WriteString(infoStyle.Render("  Enter: rename  •  ESC: cancel"))
+	case ProfileEditDelete:
+		sb.WriteString("  " + promptStyle.Render(fmt.Sprintf("Delete %s and all its progress? (y/n)", m.ProfileNames[m.ProfileCursor])))
+	default:
+		help := "  j/k + Enter: select  •  n: new  •  r: rename  •  d: delete  •  q: quit"
+		if m.Profile != nil {
+			help += "  •  ESC: back"
+		}
+		sb.WriteString(infoStyle.Render(help))
+	}
+	sb.WriteString("\n")
+	if m.ProfileMsg != "" {
+		sb.WriteString("\n" + infoStyle.Render("  "+m.ProfileMsg) + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,187 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"vimgame/store"
+)
+
+// DefaultProfileName names the profile used when none is chosen.
+const DefaultProfileName = "default"
+
+// maxProfileName caps the length of a profile name.
+const maxProfileName = 32
+
+// legacyProfileFile is where version 1 kept its single profile, relative to
+// the data directory.
+const legacyProfileFile = "profile.json"
+
+// ProfileStore keeps one JSON file per profile in a directory, so profiles
+// can be copied between machines by copying files.
+type ProfileStore struct {
+	Dir string
+}
+
+// OpenProfileStore returns the profile store under a data directory. A
+// single-profile file from an older version becomes the default profile.
+func OpenProfileStore(dataDir string) (*ProfileStore, error) {
+	s := &ProfileStore{Dir: filepath.Join(dataDir, "profiles")}
+	legacy := filepath.Join(dataDir, legacyProfileFile)
+	if _, err := os.Stat(legacy); err == nil {
+		dst := s.Path(DefaultProfileName)
+		if _, err := os.Stat(dst); errors.Is(err, fs.ErrNotExist) {
+			if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+				return nil, err
+			}
+			if err := os.Rename(legacy, dst); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// profileSlug turns a profile name into a file name stem.
+func profileSlug(name string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			sb.WriteRune(r)
+			dash = false
+		case !dash && sb.Len() > 0:
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
+
+// checkProfileName reports why a name cannot be used for a profile.
+func checkProfileName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("profile name is empty")
+	case len(name) > maxProfileName:
+		return fmt.Errorf("profile name is longer than %d characters", maxProfileName)
+	case profileSlug(name) == "":
+		return errors.New("profile name needs a letter or digit")
+	}
+	return nil
+}
+
+// Path returns the file holding the named profile.
+func (s *ProfileStore) Path(name string) string {
+	return filepath.Join(s.Dir, profileSlug(name)+".json")
+}
+
+// List returns the names of all stored profiles, sorted. A profile file
+// that cannot be read is skipped and reported in the error, which comes
+// with the names of the others.
+func (s *ProfileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		p, err := LoadProfile(filepath.Join(s.Dir, e.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("skipped profile: %w", err))
+			continue
+		}
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names, errors.Join(errs...)
+}
+
+// Load reads the named profile.
+func (s *ProfileStore) Load(name string) (*Profile, error) {
+	return LoadProfile(s.Path(name))
+}
+
+// Save writes a profile to its file.
+func (s *ProfileStore) Save(p *Profile) error {
+	return p.Save(s.Path(p.Name))
+}
+
+// exists reports whether a profile file for name exists.
+func (s *ProfileStore) exists(name string) bool {
+	_, err := os.Stat(s.Path(name))
+	return err == nil
+}
+
+// Create makes and saves a new, empty profile.
+func (s *ProfileStore) Create(name string) (*Profile, error) {
+	name = strings.TrimSpace(name)
+	if err := checkProfileName(name); err != nil {
+		return nil, err
+	}
+	if s.exists(name) {
+		return nil, fmt.Errorf("profile %q already exists", name)
+	}
+	p := NewProfile(name)
+	if err := s.Save(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadOrCreate reads the named profile, creating it if it does not exist.
+func (s *ProfileStore) LoadOrCreate(name string) (*Profile, error) {
+	if !s.exists(name) {
+		return s.Create(name)
+	}
+	return s.Load(name)
+}
+
+// Rename gives a profile a new name and moves its file.
+func (s *ProfileStore) Rename(oldName, newName string) (*Profile, error) {
+	newName = strings.TrimSpace(newName)
+	if err := checkProfileName(newName); err != nil {
+		return nil, err
+	}
+	p, err := s.Load(oldName)
+	if err != nil {
+		return nil, err
+	}
+	sameFile := s.Path(oldName) == s.Path(newName)
+	if !sameFile && s.exists(newName) {
+		return nil, fmt.Errorf("profile %q already exists", newName)
+	}
+	p.Name = newName
+	if err := s.Save(p); err != nil {
+		return nil, err
+	}
+	if !sameFile {
+		if err := os.Remove(s.Path(oldName)); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Delete removes a profile's file.
+func (s *ProfileStore) Delete(name string) error {
+	return os.Remove(s.Path(name))
+}
+
+// DefaultProfileStore opens the profile store in the user's data directory.
+func DefaultProfileStore() (*ProfileStore, error) {
+	return OpenProfileStore(store.DataDir())
+}
@@ -0,0 +1,282 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"vimgame/race"
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RaceRun tracks the players of a race session and how the current race
+// stands. Players race one catalog level on a shared seed, so everyone gets
+// the same buffers and target sequence.
+type RaceRun struct {
+	Players  []race.Player
+	Progress map[int]race.Progress // latest progress by player id
+	Places   map[int]int           // finishing place by player id
+	Left     map[int]bool          // players who disconnected
+	Level    int                   // level the host will start next
+	Sent     int                   // replay keys already sent with progress
+	Msg      string                // connection problems, shown in the lobby
+}
+
+// raceMsg is a message from the other players.
+type raceMsg race.Message
+
+// raceClosedMsg reports that the race connection ended.
+type raceClosedMsg struct{}
+
+// JoinRace attaches the model to a race session and opens the lobby. Pass
+// the host when hosting so the lobby can start races.
+func (m *Model) JoinRace(s race.Session, host *race.Host, players []race.Player) {
+	m.RaceConn = s
+	m.RaceHost = host
+	m.Race = RaceRun{Players: players}
+	m.resetRace()
+	m.State = StateRaceLobby
+}
+
+func (m *Model) resetRace() {
+	m.Race.Progress = make(map[int]race.Progress)
+	m.Race.Places = make(map[int]int)
+	m.Race.Left = make(map[int]bool)
+}
+
+// waitRace delivers the next message from the race session.
+func waitRace(s race.Session) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-s.Events()
+		if !ok {
+			return raceClosedMsg{}
+		}
+		return raceMsg(msg)
+	}
+}
+
+// raceEvent applies a message from the race session.
+func (m *Model) raceEvent(msg raceMsg) {
+	switch msg.Type {
+	case race.TypeLobby:
+		m.Race.Players = msg.Players
+	case race.TypeStart:
+		m.startRace(msg.Seed, msg.LevelName, msg.LevelHash, msg.Players)
+	case race.TypeProgress:
+		if msg.Progress != nil {
+			m.Race.Progress[msg.ID] = *msg.Progress
+		}
+	case race.TypeResult:
+		m.Race.Places[msg.ID] = msg.Place
+		if msg.Progress != nil {
+			m.Race.Progress[msg.ID] = *msg.Progress
+		}
+	case race.TypeBye:
+		m.Race.Left[msg.ID] = true
+	}
+}
+
+// raceLevel returns the index of the catalog level a race was started on,
+// refusing one whose content differs from the host's.
+func raceLevel(catalog []Level, name, hash string) (int, error) {
+	i := slices.IndexFunc(catalog, func(l Level) bool { return l.Name == name })
+	switch {
+	case i < 0:
+		return -1, fmt.Errorf("the host started %q, which is not installed here", name)
+	case catalog[i].Hash() != hash:
+		return -1, fmt.Errorf("the host's %q differs from the one installed here", name)
+	}
+	return i, nil
+}
+
+// RaceRules returns the rules the player races by, as they are sent to the
+// other players and spectators (see race.Join).
+func (m Model) RaceRules() json.RawMessage {
+	data, _ := json.Marshal(m.Rules)
+	return data
+}
+
+// playerRules returns the rules a racer plays by; racers that sent none
+// play by the default ones.
+func playerRules(p race.Player) Rules {
+	rules := NewConfig().Rules()
+	if len(p.Rules) > 0 {
+		json.Unmarshal(p.Rules, &rules)
+	}
+	return rules
+}
+
+// startRace begins a race on a catalog level with the host's seed.
+func (m *Model) startRace(seed int64, name, hash string, players []race.Player) {
+	level, err := raceLevel(m.LevelCatalog, name, hash)
+	if err != nil {
+		m.Race.Msg = "Cannot race: " + err.Error()
+		return
+	}
+	m.Race.Players = players
+	m.Race.Msg = ""
+	m.resetRace()
+	m.Levels = m.LevelCatalog
+	m.Daily = ""
+	m.Practice = PracticeSpec{}
+	m.beginRun(GameModeRace, 0, level, seed)
+	m.Race.Sent = 0
+	m.startLevelTracking()
+	m.sendRaceProgress()
+}
+
+// raceProgress returns the player's position in the race: targets hit and
+// edits finished out of all of the level's.
+func (m Model) raceProgress() race.Progress {
+	level := m.Levels[m.LevelIndex]
+	p := race.Progress{
+		Exercise: m.ExIndex,
+		Row:      m.Cursor.Row,
+		Col:      m.Cursor.Col,
+		TimeMs:   m.Elapsed.Milliseconds(),
+	}
+	for i, ex := range level.Exercises {
+		units := 1
+		if ex.Type == ExerciseMotion {
+			units = ex.NumTargets
+		}
+		p.Total += units
+		switch {
+		case i < m.ExIndex:
+			p.Done += units
+		case i == m.ExIndex && m.State == StateExerciseComplete:
+			p.Done += units
+		case i == m.ExIndex && ex.Type == ExerciseMotion:
+			p.Done += m.TargetsHit
+		}
+	}
+	if m.State == StateLevelComplete {
+		p.Done = p.Total
+		p.Finished = true
+	}
+	return p
+}
+
+// sendRaceProgress reports the player's progress to the other racers.
+func (m *Model) sendRaceProgress() {
+	if m.RaceConn == nil || m.GameMode != GameModeRace || m.Playback != nil {
+		return
+	}
+	p := m.raceProgress()
+	for _, k := range m.Replay.Keys[m.Race.Sent:] {
+		p.Keys = append(p.Keys, race.Key{Key: k.Key, At: k.At})
+	}
+	m.Race.Sent = len(m.Replay.Keys)
+	if err := m.RaceConn.Send(p); err != nil {
+		m.Race.Msg = "Could not reach the race: " + err.Error()
+	}
+}
+
+// leaveRace returns to the lobby; the host opens it to new players.
+func (m *Model) leaveRace() {
+	m.State = StateRaceLobby
+	if m.RaceHost != nil {
+		m.RaceHost.Reset()
+	}
+}
+
+func (m Model) handleRaceLobbyInput(key string) (tea.Model, tea.Cmd) {
+	if m.RaceHost == nil || m.RaceConn == nil {
+		return m, nil
+	}
+	switch key {
+	case "j", "down":
+		if m.Race.Level < len(m.LevelCatalog)-1 {
+			m.Race.Level++
+		}
+	case "k", "up":
+		if m.Race.Level > 0 {
+			m.Race.Level--
+		}
+	case "enter":
+		level := m.LevelCatalog[m.Race.Level]
+		if err := m.RaceHost.Start(m.runSeed(), m.Race.Level, level.Name, level.Hash()); err != nil {
+			m.Race.Msg = err.Error()
+		}
+	}
+	return m, nil
+}
+
+// raceColor returns the ghost color of a player.
+func (m Model) raceColor(id int) int {
+	for i, p := range m.Race.Players {
+		if p.ID == id {
+			return i
+		}
+	}
+	return id
+}
+
+// raceRows returns the standings for the race status line and lobby.
+func (m Model) raceRows() []ui.RaceRow {
+	self := m.raceProgress()
+	rows := make([]ui.RaceRow, 0, len(m.Race.Players))
+	for i, p := range m.Race.Players {
+		row := ui.RaceRow{Name: p.Name, Color: i, Place: m.Race.Places[p.ID], Left: m.Race.Left[p.ID]}
+		if p.ID == m.RaceConn.ID() {
+			row.You = true
+			row.Done, row.Total = self.Done, self.Total
+		} else {
+			pr := m.Race.Progress[p.ID]
+			row.Done, row.Total = pr.Done, pr.Total
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// raceGhosts returns the cursors of the rivals on the player's exercise.
+func (m Model) raceGhosts() []ui.Marker {
+	var ghosts []ui.Marker
+	for id, p := range m.Race.Progress {
+		if id == m.RaceConn.ID() || m.Race.Left[id] || m.Race.Places[id] > 0 || p.Exercise != m.ExIndex {
+			continue
+		}
+		ghosts = append(ghosts, ui.Marker{Row: p.Row, Col: p.Col, Color: m.raceColor(id)})
+	}
+	return ghosts
+}
+
+func (m Model) viewRaceLobby() string {
+	th := ui.CurrentTheme()
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Title).Padding(1, 0, 0, 2)
+	dimStyle := lipgloss.NewStyle().Foreground(th.Dim)
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Race Lobby") + "\n\n")
+	if m.RaceHost != nil {
+		sb.WriteString(dimStyle.Render(fmt.Sprintf("  Hosting on %s — others join with: vimgame join <your address>", m.RaceHost.Addr())) + "\n\n")
+	}
+	if m.RaceConn != nil {
+		sb.WriteString(ui.RenderRaceLobby(m.raceRows()) + "\n")
+	}
+
+	switch {
+	case m.RaceConn == nil:
+	case m.RaceHost != nil:
+		start := max(0, m.Race.Level-4)
+		for i := start; i < min(len(m.LevelCatalog), start+9); i++ {
+			marker := "   "
+			if i == m.Race.Level {
+				marker = " ▸ "
+			}
+			sb.WriteString(fmt.Sprintf(" %s%2d. %s\n", marker, i+1, m.LevelCatalog[i].Name))
+		}
+		sb.WriteString("\n" + dimStyle.Render("  j/k: choose level  •  Enter: start the race  •  q to quit") + "\n")
+	default:
+		sb.WriteString(dimStyle.Render("  Waiting for the host to start the race  •  q to quit") + "\n")
+	}
+	if m.Race.Msg != "" {
+		sb.WriteString("\n" + lipgloss.NewStyle().Foreground(th.Bad).Render("  "+m.Race.Msg) + "\n")
+	}
+	return sb.String()
+}
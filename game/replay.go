@@ -0,0 +1,291 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ReplayVersion is the current replay file format version.
+const ReplayVersion = 1
+
+// ReplayKey is a single recorded keypress.
+type ReplayKey struct {
+	Key string `json:"key"`   // tea.KeyMsg string, e.g. "j", "esc", "ctrl+r"
+	At  int64  `json:"at_ms"` // milliseconds since the start of the run
+}
+
+// Replay is the full key stream of one run together with everything needed
+// to reproduce it: the RNG seed and the lesson or level it started on. The
+// lesson or level is found by name, so that a run on pack content plays
+// back with the same packs loaded; the index is for replays without one.
+// A leaderboard entry keeps only its level's part of a run, which starts
+// with Draws values already drawn from the RNG.
+type Replay struct {
+	Version     int          `json:"version"`
+	Mode        GameModeType `json:"mode"`
+	LessonIndex int          `json:"lesson_index,omitempty"`
+	LevelIndex  int          `json:"level_index,omitempty"`
+	Lesson      string       `json:"lesson,omitempty"` // name of the starting lesson
+	Level       string       `json:"level,omitempty"`  // name of the starting level
+	Seed        int64        `json:"seed"`
+	Draws       int64        `json:"draws,omitempty"` // RNG values drawn before the starting level
+	Daily       string       `json:"daily,omitempty"` // daily challenge date
+	Practice    PracticeSpec `json:"practice,omitzero"`
+	Review      []string     `json:"review,omitempty"` // commands reviewed
+	Rules       Rules        `json:"rules,omitzero"`
+	Recorded    time.Time    `json:"recorded"`
+	Keys        []ReplayKey  `json:"keys"`
+}
+
+// Duration returns the time of the last recorded key.
+func (r Replay) Duration() time.Duration {
+	if len(r.Keys) == 0 {
+		return 0
+	}
+	return time.Duration(r.Keys[len(r.Keys)-1].At) * time.Millisecond
+}
+
+// ClientTimed reports whether the replay's result rests on key times only
+// the recording machine saw: edit challenges race a clock, endless runs
+// are ranked on how long they lasted, and a key timeout drops half-typed
+// commands. A team server cannot check such times, so these runs are only
+// ranked locally.
+func (r Replay) ClientTimed() bool {
+	return r.Mode == GameModeEditChallenge || r.Mode == GameModeEndless || r.Rules.KeyTimeout > 0
+}
+
+// LoadReplay reads a replay file.
+func LoadReplay(path string) (Replay, error) {
+	var r Replay
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.Version != ReplayVersion {
+		return r, fmt.Errorf("%s: unsupported replay version %d", path, r.Version)
+	}
+	return r, nil
+}
+
+// SaveReplay writes a replay file.
+func SaveReplay(path string, r Replay) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// runSource is a run's random source. It counts the values drawn, so that a
+// replay of a later level can start with the RNG where that level found it.
+type runSource struct {
+	rand.Source64
+	drawn int64
+}
+
+// newRunSource returns a source seeded with seed that has already drawn
+// skip values.
+func newRunSource(seed, skip int64) *runSource {
+	s := &runSource{Source64: rand.NewSource(seed).(rand.Source64)}
+	for range skip {
+		s.Uint64()
+	}
+	return s
+}
+
+func (s *runSource) Int63() int64 {
+	s.drawn++
+	return s.Source64.Int63()
+}
+
+func (s *runSource) Uint64() uint64 {
+	s.drawn++
+	return s.Source64.Uint64()
+}
+
+// ReplayKeyMsg feeds a recorded key into Model.Update. At replaces the wall
+// clock so that replayed runs reproduce the recorded timings.
+type ReplayKeyMsg struct {
+	Key string
+	At  time.Duration
+}
+
+// replayTickMsg advances a playback to its next recorded key.
+type replayTickMsg struct{}
+
+// Playback drives a model through a replay in real time.
+type Playback struct {
+	Replay Replay
+	Next   int     // index of the next key to feed
+	Speed  float64 // 1 = recorded speed
+}
+
+// Done reports whether every key has been played.
+func (p *Playback) Done() bool {
+	return p.Next >= len(p.Replay.Keys)
+}
+
+// tick schedules the next recorded key relative to the previous one.
+func (p *Playback) tick() tea.Cmd {
+	if p.Done() {
+		return nil
+	}
+	var prev int64
+	if p.Next > 0 {
+		prev = p.Replay.Keys[p.Next-1].At
+	}
+	delay := time.Duration(float64(p.Replay.Keys[p.Next].At-prev)/p.Speed) * time.Millisecond
+	return tea.Tick(delay, func(time.Time) tea.Msg { return replayTickMsg{} })
+}
+
+// NewReplayModel returns a model positioned at the start of the replayed run,
+// with packs added to the built-in content as they were when it was
+// recorded. Feed it the replay's keys as ReplayKeyMsg, or use
+// NewPlaybackModel to play it back in real time.
+func NewReplayModel(r Replay, packs ...Pack) (Model, error) {
+	m := NewModel()
+	for _, p := range packs {
+		m.AddPack(p)
+	}
+	err := m.replayRun(r)
+	return m, err
+}
+
+// replayRun starts a run on the same content, level and seed as the
+// replayed one.
+func (m *Model) replayRun(r Replay) error {
+	m.Levels = m.LevelCatalog
+	m.Daily = ""
+	m.Practice = PracticeSpec{}
+	if r.Daily != "" {
+		date, err := time.Parse(DailyDateFormat, r.Daily)
+		if err != nil {
+			return fmt.Errorf("replay daily date: %w", err)
+		}
+		m.Levels = []Level{DailyLevel(date)}
+		m.Daily = r.Daily
+	}
+	if r.Practice.File != "" {
+		level, err := r.Practice.Level(r.Seed)
+		if err != nil {
+			return fmt.Errorf("replay practice file: %w", err)
+		}
+		m.Levels = []Level{level}
+		m.Practice = r.Practice
+	}
+	m.Review = ReviewRun{Commands: r.Review}
+	m.Rules = r.Rules
+	switch r.Mode {
+	case GameModeTutorial:
+		if r.Lesson != "" {
+			r.LessonIndex = slices.IndexFunc(m.Lessons, func(l Lesson) bool { return l.Name == r.Lesson })
+			if r.LessonIndex < 0 {
+				return fmt.Errorf("replay lesson %q is not loaded; is its pack missing?", r.Lesson)
+			}
+		}
+		if r.LessonIndex < 0 || r.LessonIndex >= len(m.Lessons) {
+			return fmt.Errorf("replay lesson %d out of range", r.LessonIndex+1)
+		}
+	case GameModeEndless, GameModeEditChallenge, GameModeReview:
+	case GameModeMotionChallenge, GameModeRace:
+		if r.Level != "" {
+			r.LevelIndex = slices.IndexFunc(m.Levels, func(l Level) bool { return l.Name == r.Level })
+			if r.LevelIndex < 0 {
+				return fmt.Errorf("replay level %q is not loaded; is its pack missing?", r.Level)
+			}
+		}
+		if r.LevelIndex < 0 || r.LevelIndex >= len(m.Levels) {
+			return fmt.Errorf("replay level %d out of range", r.LevelIndex+1)
+		}
+	default:
+		return fmt.Errorf("replay mode %d cannot be played back", r.Mode)
+	}
+	m.beginRun(r.Mode, r.LessonIndex, r.LevelIndex, r.Seed)
+	if r.Draws > 0 {
+		// A level played after others: its targets come from later in the
+		// run's random sequence, and a level's own seed did not apply.
+		m.Seed = r.Seed
+		m.rngSource = newRunSource(r.Seed, r.Draws)
+		m.Rng = rand.New(m.rngSource)
+		m.rngStart = r.Draws
+		m.Replay.Seed, m.Replay.Draws = r.Seed, r.Draws
+		m.startChallengeLevel()
+	}
+	return nil
+}
+
+// levelReplay returns the part of the run's replay that plays the current
+// level, with times from when the level started.
+func (m Model) levelReplay() Replay {
+	r := m.Replay
+	start := m.LevelTimeStart.Milliseconds()
+	r.Keys = make([]ReplayKey, 0, len(m.Replay.Keys)-m.LevelKeyStart)
+	for _, k := range m.Replay.Keys[m.LevelKeyStart:] {
+		r.Keys = append(r.Keys, ReplayKey{Key: k.Key, At: k.At - start})
+	}
+	if m.GameMode == GameModeMotionChallenge {
+		r.LevelIndex = m.LevelIndex
+		r.Level = m.Levels[m.LevelIndex].Name
+		r.Draws = m.rngStart
+	}
+	return r
+}
+
+// NewPlaybackModel returns a model that plays the replay back in real time.
+func NewPlaybackModel(r Replay, speed float64, packs ...Pack) (Model, error) {
+	m, err := NewReplayModel(r, packs...)
+	if err != nil {
+		return m, err
+	}
+	if speed <= 0 {
+		speed = 1
+	}
+	m.Playback = &Playback{Replay: r, Speed: speed}
+	return m, nil
+}
+
+// Simulate replays every key of r through Model.Update without a terminal
+// and returns the resulting model.
+func Simulate(r Replay, packs ...Pack) (Model, error) {
+	m, err := NewReplayModel(r, packs...)
+	if err != nil {
+		return m, err
+	}
+	for _, k := range r.Keys {
+		next, _ := m.Update(ReplayKeyMsg{Key: k.Key, At: time.Duration(k.At) * time.Millisecond})
+		m = next.(Model)
+	}
+	return m, nil
+}
+
+// VerifyReplay re-simulates a replay and returns the ranked level results
+// it reproduces, on every board they qualify for including the board of
+// the replay's seed. Results reported by a player can be checked against
+// these, since the keys alone determine the score. Simulation stops when
+// the run returns to a menu, so a replay cannot start another mode.
+func VerifyReplay(r Replay, packs ...Pack) ([]LevelResult, error) {
+	m, err := NewReplayModel(r, packs...)
+	if err != nil {
+		return nil, err
+	}
+	var results []LevelResult
+	m.verified = &results
+	m.FixedSeed = r.Seed
+	for _, k := range r.Keys {
+		next, _ := m.Update(ReplayKeyMsg{Key: k.Key, At: time.Duration(k.At) * time.Millisecond})
+		m = next.(Model)
+		if m.State == StateMenu || m.State == StateTutorialMenu {
+			break // the run is over; later keys would start another
+		}
+	}
+	return results, nil
+}
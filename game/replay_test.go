@@ -0,0 +1,190 @@
+package game
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"vimgame/race"
+)
+
+const testPack = `level Test Pack Level
+commands h j k l
+
+exercise motion
+instruction Reach the targets.
+cursor 1 1
+targets 3
+difficulty 2
+buffer
+alpha beta gamma
+delta epsilon
+zeta eta theta iota
+kappa lambda
+end
+`
+
+func parseTestPack(t *testing.T, name, text string) Pack {
+	t.Helper()
+	pack, err := ParsePack(name, strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pack
+}
+
+// playTargets steers the model to its next n targets along the solver's
+// paths, one key every 150ms.
+func playTargets(t *testing.T, m Model, n int) Model {
+	t.Helper()
+	allowed := MotionSet{MotionH: true, MotionJ: true, MotionK: true, MotionL: true}
+	for range n {
+		path := OptimalPath(m.Buffer.Lines, m.Cursor, m.Target, allowed)
+		if path == nil {
+			t.Fatalf("no path from %v to %v", m.Cursor, m.Target)
+		}
+		for _, s := range path {
+			var keys []string
+			if s.Count > 0 {
+				keys = append(keys, strconv.Itoa(s.Count))
+			}
+			for _, key := range append(keys, MotionName(s.Motion)) {
+				at := m.Elapsed + 150*time.Millisecond
+				next, _ := m.Update(ReplayKeyMsg{Key: key, At: at})
+				m = next.(Model)
+			}
+		}
+	}
+	return m
+}
+
+func checkSameRun(t *testing.T, got, want Model) {
+	t.Helper()
+	if got.Cursor != want.Cursor || got.Target != want.Target {
+		t.Errorf("cursor/target = %v/%v, want %v/%v", got.Cursor, got.Target, want.Cursor, want.Target)
+	}
+	if got.Score != want.Score || got.TargetsHit != want.TargetsHit || got.Keystrokes != want.Keystrokes {
+		t.Errorf("score/targets/keystrokes = %d/%d/%d, want %d/%d/%d",
+			got.Score, got.TargetsHit, got.Keystrokes, want.Score, want.TargetsHit, want.Keystrokes)
+	}
+	if got.LevelIndex != want.LevelIndex || got.ExIndex != want.ExIndex {
+		t.Errorf("level/exercise = %d/%d, want %d/%d", got.LevelIndex, got.ExIndex, want.LevelIndex, want.ExIndex)
+	}
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	m := NewModel()
+	m.beginRun(GameModeMotionChallenge, 0, 0, 42)
+	m = playTargets(t, m, 3)
+	if m.TargetsHit != 3 {
+		t.Fatalf("hit %d targets, want 3", m.TargetsHit)
+	}
+
+	got, err := Simulate(m.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameRun(t, got, m)
+}
+
+func TestReplayPackLevel(t *testing.T) {
+	pack := parseTestPack(t, "test.pack", testPack)
+	m := NewModel()
+	m.AddPack(pack)
+	level := len(m.LevelCatalog) - 1
+	m.beginRun(GameModeMotionChallenge, 0, level, 7)
+	m = playTargets(t, m, 2)
+	if m.TargetsHit != 2 {
+		t.Fatalf("hit %d targets, want 2", m.TargetsHit)
+	}
+	r := m.Replay
+	if r.Level != "Test Pack Level" {
+		t.Fatalf("replay level = %q, want the pack level's name", r.Level)
+	}
+
+	if _, err := Simulate(r); err == nil || !strings.Contains(err.Error(), "Test Pack Level") {
+		t.Errorf("Simulate without the pack: err = %v, want the missing level named", err)
+	}
+
+	// Another pack loaded first moves the level; it is still found by name
+	other := parseTestPack(t, "other.pack", strings.Replace(testPack, "Test Pack Level", "Other Level", 1))
+	got, err := Simulate(r, other, pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Levels[got.LevelIndex].Name != "Test Pack Level" {
+		t.Errorf("replayed level %q, want Test Pack Level", got.Levels[got.LevelIndex].Name)
+	}
+	got.LevelIndex = m.LevelIndex
+	checkSameRun(t, got, m)
+}
+
+// press feeds keys to the model 150ms apart.
+func press(m Model, keys ...string) Model {
+	for _, key := range keys {
+		next, _ := m.Update(ReplayKeyMsg{Key: key, At: m.Elapsed + 150*time.Millisecond})
+		m = next.(Model)
+	}
+	return m
+}
+
+func TestLevelReplay(t *testing.T) {
+	pack := parseTestPack(t, "test.pack", testPack+"\n"+strings.Replace(testPack, "Test Pack Level", "Second Level", 1))
+	m := NewModel()
+	m.AddPack(pack)
+	var results []LevelResult
+	m.verified = &results
+	m.beginRun(GameModeMotionChallenge, 0, len(m.LevelCatalog)-2, 7)
+	m.trackProfile(Model{})
+	m = playTargets(t, m, 3)
+	m = press(m, "enter", "enter")
+	if m.Levels[m.LevelIndex].Name != "Second Level" || m.State != StatePlaying {
+		t.Fatalf("playing %q in state %d, want the second level", m.Levels[m.LevelIndex].Name, m.State)
+	}
+	m = playTargets(t, m, 3)
+	m = press(m, "enter")
+	if len(results) != 2 {
+		t.Fatalf("%d level results, want 2", len(results))
+	}
+
+	// The second level's replay starts at that level, on the same targets
+	want := results[1]
+	if want.Replay.Level != "Second Level" || want.Replay.Draws == 0 {
+		t.Fatalf("level replay starts at %q after %d draws", want.Replay.Level, want.Replay.Draws)
+	}
+	got, err := VerifyReplay(want.Replay, pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("verified %d results, want 1", len(got))
+	}
+	e, w := got[0].Entry, want.Entry
+	if e.Score != w.Score || e.Keystrokes != w.Keystrokes || e.Time != w.Time {
+		t.Errorf("verified score/keys/time = %d/%d/%v, want %d/%d/%v", e.Score, e.Keystrokes, e.Time, w.Score, w.Keystrokes, w.Time)
+	}
+	if w.Keystrokes >= len(want.Replay.Keys) {
+		t.Errorf("entry counts %d keystrokes of the level's %d keys, want only motions", w.Keystrokes, len(want.Replay.Keys))
+	}
+}
+
+func TestSpectatorRaceRules(t *testing.T) {
+	pack := parseTestPack(t, "test.pack", testPack)
+	m := NewModel()
+	m.AddPack(pack)
+	level := m.LevelCatalog[len(m.LevelCatalog)-1]
+	players := []race.Player{
+		{ID: 0, Name: "host", Rules: json.RawMessage(`{"shiftwidth":2,"timeoutlen":500}`)},
+		{ID: 1, Name: "ada"},
+	}
+	s := NewSpectator("race", pack)
+	s.raceStart(7, level.Name, level.Hash(), players)
+	if got := s.player(0, "").Run.Rules; got.ShiftWidth != 2 || got.KeyTimeout != 500 {
+		t.Errorf("host's run plays by %+v", got)
+	}
+	if got, want := s.player(1, "").Run.Rules, NewConfig().Rules(); got != want {
+		t.Errorf("ada's run plays by %+v, want the defaults %+v", got, want)
+	}
+}
@@ -0,0 +1,329 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Review settings.
+const (
+	reviewMaxCommands   = 4   // commands drilled per session
+	reviewExPerLesson   = 2   // exercises drawn from each reviewed lesson
+	reviewMaxExercises  = 6   // exercises per session
+	reviewMotionTargets = 3   // targets per motion exercise, fewer than the lesson's
+	reviewStartEase     = 2.5 // SM-2 starting ease factor
+	reviewMinEase       = 1.3
+)
+
+// ReviewCard is the SM-2 schedule of one command taught by a lesson.
+type ReviewCard struct {
+	Ease     float64 `json:"ease"`
+	Interval int     `json:"interval"` // days until the next review
+	Reps     int     `json:"reps"`     // successful reviews in a row
+	Lapses   int     `json:"lapses"`   // reviews failed
+	Due      string  `json:"due"`      // DailyDateFormat
+	Last     Medal   `json:"last"`     // medal at the last review
+}
+
+// reviewDate returns the date days after now in DailyDateFormat.
+func reviewDate(now time.Time, days int) string {
+	return now.AddDate(0, 0, days).Format(DailyDateFormat)
+}
+
+// reviewQuality maps a medal to an SM-2 quality grade, 0-5. Bronze counts
+// as a failed recall.
+func reviewQuality(medal Medal) int {
+	switch medal {
+	case MedalDiamond:
+		return 5
+	case MedalGold:
+		return 4
+	case MedalSilver:
+		return 3
+	case MedalBronze:
+		return 2
+	}
+	return 0
+}
+
+// Grade reschedules the card after a review earning the given medal.
+func (c *ReviewCard) Grade(medal Medal, now time.Time) {
+	q := reviewQuality(medal)
+	if q < 3 {
+		c.Reps = 0
+		c.Interval = 1
+		c.Lapses++
+	} else {
+		c.Reps++
+		switch c.Reps {
+		case 1:
+			c.Interval = 1
+		case 2:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+	}
+	d := float64(5 - q)
+	c.Ease = max(c.Ease+0.1-d*(0.08+d*0.02), reviewMinEase)
+	c.Due = reviewDate(now, c.Interval)
+	c.Last = medal
+}
+
+// scheduleLesson adds review cards, due on the given date, for a completed
+// lesson's commands. Existing cards keep their schedule.
+func (p *Profile) scheduleLesson(l Lesson, due string) {
+	for _, cmd := range l.NewCommands {
+		cmd = canonicalCommand(cmd)
+		if p.Review[cmd] == nil {
+			p.Review[cmd] = &ReviewCard{Ease: reviewStartEase, Interval: 1, Due: due, Last: MedalNone}
+		}
+	}
+}
+
+// unscheduled returns the commands of completed lessons that have no review
+// card yet, as in profiles from before reviews were scheduled.
+func (p *Profile) unscheduled(lessons []Lesson) []string {
+	var cmds []string
+	for _, l := range lessons {
+		if !p.LessonDone(l.Name) {
+			continue
+		}
+		for _, cmd := range l.NewCommands {
+			if cmd = canonicalCommand(cmd); p.Review[cmd] == nil && !slices.Contains(cmds, cmd) {
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	return cmds
+}
+
+// scheduleCompleted makes the commands of every completed lesson without a
+// review card due today.
+func (p *Profile) scheduleCompleted(lessons []Lesson, now time.Time) {
+	for _, l := range lessons {
+		if p.LessonDone(l.Name) {
+			p.scheduleLesson(l, now.Format(DailyDateFormat))
+		}
+	}
+}
+
+// DueCommands returns the commands due for review on now's date, most
+// overdue first.
+func (p *Profile) DueCommands(now time.Time) []string {
+	today := now.Format(DailyDateFormat)
+	var due []string
+	for cmd, c := range p.Review {
+		if c.Due <= today {
+			due = append(due, cmd)
+		}
+	}
+	p.sortByDue(due)
+	return due
+}
+
+func (p *Profile) sortByDue(cmds []string) {
+	sort.Slice(cmds, func(i, j int) bool {
+		a, b := p.Review[cmds[i]], p.Review[cmds[j]]
+		if a.Due != b.Due {
+			return a.Due < b.Due
+		}
+		return cmds[i] < cmds[j]
+	})
+}
+
+// ReviewCommands picks the commands for a review session: those due, then
+// the scheduled commands the stats report as weak, then, if nothing is due,
+// the ones due soonest.
+func (p *Profile) ReviewCommands(now time.Time) []string {
+	cmds := p.DueCommands(now)
+	seen := make(map[string]bool)
+	for _, c := range cmds {
+		seen[c] = true
+	}
+	for _, c := range p.Stats.Report(p.Name, nil).Weak {
+		if p.Review[c] != nil && !seen[c] {
+			cmds = append(cmds, c)
+			seen[c] = true
+		}
+	}
+	if len(cmds) == 0 {
+		for c := range p.Review {
+			cmds = append(cmds, c)
+		}
+		p.sortByDue(cmds)
+	}
+	return cmds[:min(len(cmds), reviewMaxCommands)]
+}
+
+// ReviewLevel builds a review session for the given commands: a shuffled
+// mix of exercises from the lessons that taught them. It also returns the
+// reviewed commands each exercise drills.
+func ReviewLevel(lessons []Lesson, commands []string, seed int64) (Level, [][]string, error) {
+	rng := rand.New(rand.NewSource(seed))
+	level := Level{Name: "Review", Seed: seed}
+
+	// Group the commands by the lesson that introduced them.
+	byLesson := make(map[int][]string)
+	var order []int
+	last := -1
+	for _, cmd := range commands {
+		li := slices.IndexFunc(lessons, func(l Lesson) bool { return l.teaches(cmd) })
+		if li < 0 {
+			continue
+		}
+		if byLesson[li] == nil {
+			order = append(order, li)
+		}
+		byLesson[li] = append(byLesson[li], cmd)
+		last = max(last, li)
+	}
+	if len(order) == 0 {
+		return level, nil, fmt.Errorf("no lesson teaches %v", commands)
+	}
+
+	type reviewEx struct {
+		ex    Exercise
+		focus []string
+	}
+	var picks []reviewEx
+	for _, li := range order {
+		// Lessons with a single exercise repeat it; targets differ each time
+		exs := lessons[li].Exercises
+		perm := rng.Perm(len(exs))
+		for i := 0; i < reviewExPerLesson && len(perm) > 0; i++ {
+			ex := exs[perm[i%len(perm)]]
+			if ex.Type == ExerciseMotion {
+				ex.NumTargets = min(ex.NumTargets, reviewMotionTargets)
+			}
+			picks = append(picks, reviewEx{ex, byLesson[li]})
+		}
+	}
+	if len(picks) == 0 {
+		return level, nil, fmt.Errorf("no review exercises for %v", commands)
+	}
+	rng.Shuffle(len(picks), func(i, j int) { picks[i], picks[j] = picks[j], picks[i] })
+	picks = picks[:min(len(picks), reviewMaxExercises)]
+
+	for i := 0; i <= last; i++ {
+		level.Commands = append(level.Commands, lessons[i].NewCommands...)
+	}
+	var focus [][]string
+	for _, p := range picks {
+		level.Exercises = append(level.Exercises, p.ex)
+		focus = append(focus, p.focus)
+	}
+	return level, focus, nil
+}
+
+// --- Model integration ---
+
+// ReviewRun tracks a review session.
+type ReviewRun struct {
+	Commands   []string         // commands being reviewed
+	Focus      [][]string       // reviewed commands drilled by each exercise
+	Keys       int              // keys pressed on the current exercise
+	MedalStart int              // index into LevelMedals where the exercise's targets start
+	Medals     map[string]Medal // worst medal per command so far
+	Graded     map[string]*ReviewCard
+}
+
+// StartReview starts a review session for the current profile.
+func (m *Model) StartReview(now time.Time) bool {
+	if m.Profile == nil {
+		return false
+	}
+	m.Profile.scheduleCompleted(m.Lessons, now)
+	cmds := m.Profile.ReviewCommands(now)
+	if _, _, err := ReviewLevel(m.Lessons, cmds, 0); err != nil {
+		return false
+	}
+	m.Review = ReviewRun{Commands: cmds}
+	m.Daily = ""
+	m.Practice = PracticeSpec{}
+	m.beginRun(GameModeReview, 0, 0, m.runSeed())
+	return true
+}
+
+// startReview builds the review level for m.Review.Commands.
+func (m *Model) startReview() {
+	level, focus, err := ReviewLevel(m.Lessons, m.Review.Commands, m.Seed)
+	if err != nil {
+		// A replay of commands from a pack that is no longer installed:
+		// review everything instead
+		level, focus, _ = ReviewLevel(m.Lessons, TaughtCommands(m.Lessons, nil), m.Seed)
+	}
+	m.Levels = []Level{level}
+	m.LevelIndex = 0
+	m.Review.Focus = focus
+	m.Review.Keys = 0
+	m.Review.MedalStart = 0
+	m.Review.Medals = make(map[string]Medal)
+	m.LevelMedals = nil
+	m.Review.Graded = nil
+}
+
+// afterReviewKey grades an exercise once it is complete. Motion exercises
+// are graded by their target medals, edits by keys against par. Each
+// command keeps the worst medal of the session.
+func (m *Model) afterReviewKey() {
+	m.Review.Keys++
+	if m.State != StateExerciseComplete {
+		return
+	}
+	level := m.Levels[m.LevelIndex]
+	ex := level.Exercises[m.ExIndex]
+	var medal Medal
+	if ex.Type == ExerciseMotion {
+		medal = overallMedal(m.LevelMedals[min(m.Review.MedalStart, len(m.LevelMedals)):])
+	} else {
+		par := SolveEdit(ex.InitBuffer, ex.GoalBuffer, ex.StartCursor, level.Commands).Par
+		medal = parMedal(m.Review.Keys, par)
+		m.Score += ScoreForMedal(medal)
+		m.LevelMedals = append(m.LevelMedals, medal)
+	}
+	m.LastMedal = medal
+	m.ShowMedal = true
+	for _, cmd := range m.Review.Focus[m.ExIndex] {
+		if prev, ok := m.Review.Medals[cmd]; !ok || medal > prev {
+			m.Review.Medals[cmd] = medal
+		}
+	}
+	m.Review.Keys = 0
+	m.Review.MedalStart = len(m.LevelMedals)
+}
+
+// reviewDue returns how many commands are due for review.
+func (m Model) reviewDue(now time.Time) int {
+	if m.Profile == nil {
+		return 0
+	}
+	return len(m.Profile.DueCommands(now)) + len(m.Profile.unscheduled(m.Lessons))
+}
+
+// gradeReview feeds a finished session's medals back into the schedule.
+func (m *Model) gradeReview(now time.Time) {
+	m.Review.Graded = make(map[string]*ReviewCard)
+	for cmd, medal := range m.Review.Medals {
+		card := m.Profile.Review[cmd]
+		if card == nil {
+			continue
+		}
+		card.Grade(medal, now)
+		m.Review.Graded[cmd] = card
+	}
+}
+
+// teaches reports whether the lesson introduces cmd.
+func (l Lesson) teaches(cmd string) bool {
+	for _, c := range l.NewCommands {
+		if canonicalCommand(c) == cmd {
+			return true
+		}
+	}
+	return false
+}
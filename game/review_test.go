@@ -0,0 +1,49 @@
+package game
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReviewCardGrade(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name      string
+		medals    []Medal
+		intervals []int // interval after each review
+		ease      float64
+		lapses    int
+	}{
+		{"gold keeps the ease", []Medal{MedalGold, MedalGold, MedalGold}, []int{1, 6, 15}, 2.5, 0},
+		{"diamond raises the ease", []Medal{MedalDiamond, MedalDiamond, MedalDiamond}, []int{1, 6, 16}, 2.8, 0},
+		{"silver lowers the ease", []Medal{MedalSilver, MedalSilver, MedalSilver}, []int{1, 6, 13}, 2.08, 0},
+		{"bronze is a lapse", []Medal{MedalGold, MedalGold, MedalBronze, MedalGold}, []int{1, 6, 1, 1}, 2.18, 1},
+		{"ease has a floor", []Medal{MedalNone, MedalNone, MedalNone}, []int{1, 1, 1}, reviewMinEase, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := ReviewCard{Ease: reviewStartEase}
+			var intervals []int
+			for _, medal := range tc.medals {
+				c.Grade(medal, now)
+				intervals = append(intervals, c.Interval)
+			}
+			if !reflect.DeepEqual(intervals, tc.intervals) {
+				t.Errorf("intervals %v, want %v", intervals, tc.intervals)
+			}
+			if math.Abs(c.Ease-tc.ease) > 1e-9 {
+				t.Errorf("ease %.2f, want %.2f", c.Ease, tc.ease)
+			}
+			if c.Lapses != tc.lapses {
+				t.Errorf("lapses %d, want %d", c.Lapses, tc.lapses)
+			}
+			if want := reviewDate(now, c.Interval); c.Due != want {
+				t.Errorf("due %s, want %s", c.Due, want)
+			}
+			if c.Last != tc.medals[len(tc.medals)-1] {
+				t.Errorf("last medal %v, want %v", c.Last, tc.medals[len(tc.medals)-1])
+			}
+		})
+	}
+}
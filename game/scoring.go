@@ -1,5 +1,7 @@
 package game
 
+import "fmt"
+
 // Medal represents the player's performance on reaching a target.
 type Medal int
 
@@ -8,6 +10,7 @@ const (
 	MedalGold
 	MedalSilver
 	MedalBronze
+	MedalNone // no medal earned yet
 )
 
 // Medal keystroke thresholds (exclusive upper bounds).
@@ -32,6 +35,27 @@ func (m Medal) String() string {
 	}
 }
 
+var medalNames = [...]string{"diamond", "gold", "silver", "bronze", "none"}
+
+// MarshalText encodes a medal by name so stored profiles stay readable.
+func (m Medal) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(medalNames) {
+		return nil, fmt.Errorf("invalid medal %d", int(m))
+	}
+	return []byte(medalNames[m]), nil
+}
+
+// UnmarshalText decodes a medal name written by MarshalText.
+func (m *Medal) UnmarshalText(text []byte) error {
+	for i, name := range medalNames {
+		if string(text) == name {
+			*m = Medal(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown medal %q", text)
+}
+
 // ScoreForMedal returns the score awarded for a given medal.
 func ScoreForMedal(m Medal) int {
 	switch m {
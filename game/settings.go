@@ -0,0 +1,291 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// UseConfig makes c the settings of profiles that have none of their own
+// and applies the settings in effect. The theme is applied too unless
+// keepTheme, e.g. when one was given on the command line.
+func (m *Model) UseConfig(c *Config, keepTheme bool) error {
+	m.machineConfig = c
+	m.keepTheme = keepTheme
+	return m.applyConfig()
+}
+
+// applyConfig puts the current profile's settings into effect, or the
+// config file's if the profile has none.
+func (m *Model) applyConfig() error {
+	c := m.machineConfig
+	if c == nil {
+		return nil
+	}
+	if m.Profile != nil && m.Profile.Settings != nil {
+		c = m.Profile.Settings
+	}
+	m.Config = c
+	m.Rules = c.Rules()
+	if m.keepTheme {
+		return nil
+	}
+	t, err := c.LoadTheme(m.defaultTheme())
+	if err != nil {
+		return err
+	}
+	m.setTheme(t)
+	return nil
+}
+
+// defaultTheme is the theme used when the settings name none.
+func (m Model) defaultTheme() ui.Theme {
+	switch {
+	case m.NoColor:
+		return ui.MonoTheme
+	case m.Theme != nil:
+		return ui.DarkTheme // the process's NO_COLOR is not the model's terminal's
+	}
+	return ui.DefaultTheme()
+}
+
+// setTheme switches the model to t: its own theme if it has one, or else
+// the ui package's.
+func (m *Model) setTheme(t ui.Theme) {
+	if m.Theme != nil {
+		*m.Theme = t
+		return
+	}
+	ui.SetTheme(t)
+}
+
+// config returns the settings in effect: the config file's, or the
+// defaults when there is none.
+func (m Model) config() Config {
+	if m.Config == nil {
+		return *NewConfig()
+	}
+	return *m.Config
+}
+
+// setting is one line of the settings screen.
+type setting struct {
+	name   string
+	help   string
+	value  func(c *Config) string
+	change func(c *Config, dir int) // dir is +1 or -1
+}
+
+var (
+	scrollOffChoices  = []int{0, 1, 2, 3, 5, 8, DefaultScrollOff}
+	tabWidthChoices   = []int{2, 4, 8}
+	shiftWidthChoices = []int{0, 2, 4, 8}
+	timeoutChoices    = []int{0, 500, 1000, 1500, 2000, 3000}
+)
+
+var lineNumberNames = map[ui.LineNumbers]string{
+	ui.NumberAbsolute: "absolute",
+	ui.NumberRelative: "relative",
+	ui.NumberHybrid:   "hybrid",
+}
+
+var settings = []setting{
+	{
+		name: "Theme",
+		help: "Colors; NO_COLOR picks mono by default",
+		value: func(c *Config) string {
+			if c.Theme == "" {
+				return "default (" + ui.CurrentTheme().Name + ")"
+			}
+			return c.Theme
+		},
+		change: func(c *Config, dir int) {
+			names := []string{""}
+			for _, t := range ui.Themes {
+				names = append(names, t.Name)
+			}
+			if !slices.Contains(names, c.Theme) {
+				names = append(names, c.Theme) // a custom theme file
+			}
+			c.Theme = cycle(names, c.Theme, dir)
+		},
+	},
+	{
+		name:  "Scoring",
+		help:  "Medals by fixed keystroke counts, or against the optimal path (unranked)",
+		value: func(c *Config) string { return c.Scoring.String() },
+		change: func(c *Config, dir int) {
+			c.Scoring = cycle([]Scoring{ScoringThresholds, ScoringSolver}, c.Scoring, dir)
+		},
+	},
+	{
+		name:  "Line numbers",
+		help:  "Relative numbers show the count for j and k; hybrid keeps the cursor line's number",
+		value: func(c *Config) string { return lineNumberNames[c.LineNumbers()] },
+		change: func(c *Config, dir int) {
+			mode := cycle([]ui.LineNumbers{ui.NumberAbsolute, ui.NumberRelative, ui.NumberHybrid}, c.LineNumbers(), dir)
+			c.Number = mode != ui.NumberRelative
+			c.RelativeNumber = mode != ui.NumberAbsolute
+		},
+	},
+	{
+		name: "Scrolloff",
+		help: "Lines kept in view above and below the cursor",
+		value: func(c *Config) string {
+			if c.ScrollOff >= DefaultScrollOff {
+				return "center"
+			}
+			return fmt.Sprint(c.ScrollOff)
+		},
+		change: func(c *Config, dir int) { c.ScrollOff = cycle(scrollOffChoices, c.ScrollOff, dir) },
+	},
+	{
+		name:   "Tab width",
+		help:   "Columns a tab expands to in practice files",
+		value:  func(c *Config) string { return fmt.Sprint(c.TabWidth) },
+		change: func(c *Config, dir int) { c.TabWidth = cycle(tabWidthChoices, c.TabWidth, dir) },
+	},
+	{
+		name: "Shiftwidth",
+		help: "Tab in insert mode indents to a multiple of this",
+		value: func(c *Config) string {
+			if c.ShiftWidth == 0 {
+				return "off"
+			}
+			return fmt.Sprint(c.ShiftWidth)
+		},
+		change: func(c *Config, dir int) { c.ShiftWidth = cycle(shiftWidthChoices, c.ShiftWidth, dir) },
+	},
+	{
+		name:   "Showcmd",
+		help:   "Show a command's keys while it is being typed",
+		value:  func(c *Config) string { return onOff(c.ShowCmd) },
+		change: func(c *Config, dir int) { c.ShowCmd = !c.ShowCmd },
+	},
+	{
+		name:   "Bell",
+		help:   "Ring the terminal bell on invalid keys and lost lives",
+		value:  func(c *Config) string { return onOff(c.Bell) },
+		change: func(c *Config, dir int) { c.Bell = !c.Bell },
+	},
+	{
+		name: "Key timeout",
+		help: "Drop a half-typed command (3, g, f, r) after this long",
+		value: func(c *Config) string {
+			if c.KeyTimeout == 0 {
+				return "off"
+			}
+			return fmt.Sprintf("%dms", c.KeyTimeout)
+		},
+		change: func(c *Config, dir int) { c.KeyTimeout = cycle(timeoutChoices, c.KeyTimeout, dir) },
+	},
+}
+
+// cycle returns the choice dir steps from cur, wrapping around. A value
+// that is not one of the choices moves to the first.
+func cycle[T comparable](choices []T, cur T, dir int) T {
+	i := slices.Index(choices, cur)
+	if i < 0 {
+		return choices[0]
+	}
+	return choices[(i+dir+len(choices))%len(choices)]
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// OpenSettings shows the settings screen.
+func (m *Model) OpenSettings() {
+	m.State = StateSettings
+	m.ConfigErr = nil
+}
+
+func (m Model) handleSettingsInput(key string) (tea.Model, tea.Cmd) {
+	dir := 0
+	switch key {
+	case "esc":
+		m.State = StateMenu
+	case "j", "down":
+		m.SettingsCursor = min(m.SettingsCursor+1, len(settings)-1)
+	case "k", "up":
+		m.SettingsCursor = max(m.SettingsCursor-1, 0)
+	case "l", "right", "enter", " ", "space":
+		dir = 1
+	case "h", "left":
+		dir = -1
+	}
+	if dir == 0 {
+		return m, nil
+	}
+
+	// Change a copy so that a theme that fails to load is not kept
+	c := *m.Config
+	settings[m.SettingsCursor].change(&c, dir)
+	t, err := c.LoadTheme(m.defaultTheme())
+	if err != nil {
+		m.ConfigErr = err
+		return m, nil
+	}
+	m.Rules = c.Rules()
+	m.setTheme(t)
+	if m.Profile == nil {
+		*m.Config = c
+		m.ConfigErr = m.Config.Save()
+		return m, nil
+	}
+	// The profile keeps its own settings from the first change on
+	m.Profile.Settings = &c
+	m.Config = &c
+	m.saveProfile()
+	m.ConfigErr = m.ProfileErr
+	return m, nil
+}
+
+func (m Model) viewSettings() string {
+	th := ui.CurrentTheme()
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(th.Title).
+		Padding(1, 2)
+
+	nameStyle := lipgloss.NewStyle().
+		Foreground(th.Text)
+
+	selectedStyle := nameStyle.Bold(true).Foreground(th.Title)
+
+	valueStyle := lipgloss.NewStyle().
+		Foreground(th.Highlight).
+		Bold(true)
+
+	infoStyle := lipgloss.NewStyle().
+		Foreground(th.Dim)
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Settings"))
+	sb.WriteString("\n\n")
+
+	for i, s := range settings {
+		marker, label := "  ", nameStyle.Render(fmt.Sprintf("%-18s", s.name))
+		if i == m.SettingsCursor {
+			marker, label = "▸ ", selectedStyle.Render(fmt.Sprintf("%-18s", s.name))
+		}
+		sb.WriteString("  " + marker + label + valueStyle.Render(s.value(m.Config)) + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(infoStyle.Render("  "+settings[m.SettingsCursor].help) + "\n\n")
+	sb.WriteString(infoStyle.Render("  j/k: select  •  h/l or Enter: change  •  ESC: back"))
+	sb.WriteString("\n")
+	if m.ConfigErr != nil {
+		sb.WriteString("\n" + lipgloss.NewStyle().Foreground(th.Bad).Render("  Settings: "+m.ConfigErr.Error()) + "\n")
+	}
+	return sb.String()
+}
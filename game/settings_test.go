@@ -0,0 +1,101 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// settingsModel returns a model with a config file and a profile store in
+// temporary directories, playing as ada with relative line numbers.
+func settingsModel(t *testing.T) (Model, *Config) {
+	t.Helper()
+	machine, err := OpenConfig(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := OpenProfileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel()
+	m.Profiles = profiles
+	if err := m.UseConfig(machine, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UseProfile("ada"); err != nil {
+		t.Fatal(err)
+	}
+	m.OpenSettings()
+	m.SettingsCursor = slices.IndexFunc(settings, func(s setting) bool { return s.name == "Line numbers" })
+	m = sendKeys(m, Model.handleSettingsInput, "l")
+	if m.ConfigErr != nil {
+		t.Fatal(m.ConfigErr)
+	}
+	return m, machine
+}
+
+// sendKeys passes keys to a screen's input handler.
+func sendKeys(m Model, handle func(Model, string) (tea.Model, tea.Cmd), keys ...string) Model {
+	for _, k := range keys {
+		next, _ := handle(m, k)
+		m = next.(Model)
+	}
+	return m
+}
+
+// checkNumbers checks how the buffer's lines are numbered.
+func checkNumbers(t *testing.T, m Model, who string, want ui.LineNumbers) {
+	t.Helper()
+	if got := m.bufferView().Numbers; got != want {
+		t.Errorf("%s's buffer is numbered %v, want %v", who, got, want)
+	}
+}
+
+func TestProfileSettings(t *testing.T) {
+	m, machine := settingsModel(t)
+	checkNumbers(t, m, "ada", ui.NumberRelative)
+	if machine.LineNumbers() != ui.NumberAbsolute {
+		t.Error("ada's setting changed the config file's")
+	}
+
+	// bob plays by the config file's settings, and ada's are kept with ada's
+	// profile
+	if err := m.UseProfile("bob"); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, m, "bob", ui.NumberAbsolute)
+	if err := m.UseProfile("ada"); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, m, "ada", ui.NumberRelative)
+}
+
+func TestNewProfileSettings(t *testing.T) {
+	m, _ := settingsModel(t)
+	m.OpenProfileMenu()
+	m = sendKeys(m, Model.handleProfileMenuInput, "n", "b", "o", "b", "enter")
+	if m.Profile == nil || m.Profile.Name != "bob" {
+		t.Fatalf("playing as %v, want the new profile", m.Profile)
+	}
+	checkNumbers(t, m, "the new profile", ui.NumberAbsolute)
+	if m.Profile.Settings != nil {
+		t.Error("the new profile has settings of its own")
+	}
+}
+
+func TestDeleteProfileSettings(t *testing.T) {
+	m, machine := settingsModel(t)
+	m.OpenProfileMenu()
+	m = sendKeys(m, Model.handleProfileMenuInput, "d", "y")
+	if m.Profile != nil {
+		t.Fatalf("still playing as the deleted %s", m.Profile.Name)
+	}
+	if m.Config != machine {
+		t.Error("the deleted profile's settings are still in effect")
+	}
+	checkNumbers(t, m, "nobody", ui.NumberAbsolute)
+}
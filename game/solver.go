@@ -0,0 +1,301 @@
+package game
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MotionSet is a set of motions the player is allowed to use.
+type MotionSet map[Motion]bool
+
+// allMotions lists every motion the engine implements.
+var allMotions = []Motion{
+	MotionH, MotionJ, MotionK, MotionL,
+	MotionW, MotionB, MotionE,
+	MotionZero, MotionDollar, MotionCaret,
+	MotionGG, MotionBigG,
+	MotionFChar, MotionBigFChar,
+}
+
+// AllMotions returns a set containing every motion.
+func AllMotions() MotionSet {
+	set := make(MotionSet, len(allMotions))
+	for _, mo := range allMotions {
+		set[mo] = true
+	}
+	return set
+}
+
+// MotionsForCommands returns the motions named in a command list such as a
+// lesson's NewCommands or a level's Commands. Non-motion commands are ignored.
+func MotionsForCommands(commands []string) MotionSet {
+	set := make(MotionSet)
+	for _, cmd := range commands {
+		for _, mo := range allMotions {
+			if commandMatchesMotion(cmd, mo) {
+				set[mo] = true
+			}
+		}
+	}
+	return set
+}
+
+func commandMatchesMotion(cmd string, mo Motion) bool {
+	switch mo {
+	case MotionFChar:
+		return strings.HasPrefix(cmd, "f{")
+	case MotionBigFChar:
+		return strings.HasPrefix(cmd, "F{")
+	}
+	return cmd == MotionName(mo)
+}
+
+// countedMotions may be prefixed with a count (e.g. 5j, 3w).
+var countedMotions = []Motion{MotionH, MotionJ, MotionK, MotionL, MotionW, MotionB, MotionE}
+
+// maxSolverCount is the largest count prefix the solver considers.
+const maxSolverCount = 9
+
+// solverWindow is how many lines above and below the start the solver
+// searches in longer buffers, such as large practice files. {n}G reaches
+// any line in a few keys, so without a window every search would cover the
+// whole file; paths that leave the window are not considered.
+const solverWindow = 50
+
+// step applies a motion count times the way handleMotion does, including
+// vim's curswant for j/k (the starting column is the desired column).
+func step(lines []string, pos Position, motion Motion, char rune, count int) Position {
+	next := pos
+	for i := 0; i < count; i++ {
+		next = ApplyMotion(lines, next, motion, char)
+	}
+	if motion == MotionJ || motion == MotionK {
+		maxCol := len(lines[next.Row]) - 1
+		if maxCol < 0 {
+			maxCol = 0
+		}
+		next.Col = min(pos.Col, maxCol)
+	}
+	return next
+}
+
+// KeystrokeDistances returns, for every buffer position, the fewest keystrokes
+// needed to move the cursor there from start using only the allowed motions.
+// Unreachable positions are -1, as are those more than solverWindow lines
+// from start. Counts up to 9 and {n}G/{n}gg are considered.
+func KeystrokeDistances(lines []string, start Position, allowed MotionSet) [][]int {
+	dist, _ := shortestPaths(lines, start, allowed, noLimit)
+	return dist
+}
+
+// SolverStep is one command on an optimal path: a motion with its count
+// (0 for none) and, for f/F, the character searched for.
+type SolverStep struct {
+	Motion Motion
+	Count  int
+	Char   byte
+}
+
+// solverEdge records how the solver first reached a position.
+type solverEdge struct {
+	from Position
+	step SolverStep
+}
+
+// OptimalPath returns the commands of one fewest-keystroke path between two
+// positions, or nil if the target cannot be reached (or from == to).
+func OptimalPath(lines []string, from, to Position, allowed MotionSet) []SolverStep {
+	dist, prev := shortestPaths(lines, from, allowed, searchLimit{goal: to})
+	if to.Row < 0 || to.Row >= len(dist) || to.Col < 0 || to.Col >= len(dist[to.Row]) || dist[to.Row][to.Col] < 0 {
+		return nil
+	}
+	var path []SolverStep
+	for p := to; p != from; p = prev[p.Row][p.Col].from {
+		path = append(path, prev[p.Row][p.Col].step)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// PathKeystrokes returns the keys needed to type a path's commands.
+func PathKeystrokes(path []SolverStep) int {
+	keys := 0
+	for _, st := range path {
+		switch st.Motion {
+		case MotionFChar, MotionBigFChar, MotionGG:
+			keys += 2
+		default:
+			keys++
+		}
+		if st.Count > 0 {
+			keys += len(strconv.Itoa(st.Count))
+		}
+	}
+	return keys
+}
+
+// searchLimit stops a keystroke search early. Distances it returns are exact
+// up to where it stopped; positions beyond are -1.
+type searchLimit struct {
+	maxDist int      // positions further than this are not searched; 0 for no limit
+	goal    Position // stop once the distance to goal is known
+}
+
+var noLimit = searchLimit{goal: Position{-1, -1}}
+
+// shortestPaths runs the keystroke search from start, returning distances
+// and the edge each position was reached by.
+func shortestPaths(lines []string, start Position, allowed MotionSet, limit searchLimit) ([][]int, [][]solverEdge) {
+	lo, hi := 0, len(lines) // rows searched
+	if len(lines) > 2*solverWindow+1 {
+		lo, hi = max(start.Row-solverWindow, 0), min(start.Row+solverWindow+1, len(lines))
+		if limit.goal.Row >= 0 && limit.goal.Row < len(lines) {
+			lo, hi = min(lo, limit.goal.Row), max(hi, limit.goal.Row+1)
+		}
+	}
+
+	dist := make([][]int, len(lines))
+	prev := make([][]solverEdge, len(lines))
+	var unsearched []int // shared by the rows outside the window
+	for r, line := range lines {
+		n := max(len(line), 1)
+		if r < lo || r >= hi {
+			for len(unsearched) < n {
+				unsearched = append(unsearched, -1)
+			}
+			dist[r] = unsearched[:n:n]
+			continue
+		}
+		dist[r] = make([]int, n)
+		prev[r] = make([]solverEdge, n)
+		for c := range dist[r] {
+			dist[r][c] = -1
+		}
+	}
+	if len(lines) == 0 {
+		return dist, prev
+	}
+
+	// occurrences of each line's characters, for f and F, built as needed
+	occurrences := make([][]occurrence, len(lines))
+
+	// Bucket queue: every edge costs between 1 and a few keystrokes.
+	var buckets [][]Position
+	var from Position
+	push := func(p Position, d int, st SolverStep) {
+		if p.Row < lo || p.Row >= hi || (limit.maxDist > 0 && d > limit.maxDist) {
+			return
+		}
+		if cur := dist[p.Row][p.Col]; cur >= 0 && cur <= d {
+			return
+		}
+		dist[p.Row][p.Col] = d
+		prev[p.Row][p.Col] = solverEdge{from, st}
+		for len(buckets) <= d {
+			buckets = append(buckets, nil)
+		}
+		buckets[d] = append(buckets[d], p)
+	}
+
+	from = start
+	push(start, 0, SolverStep{})
+
+	// Jumps to a line cost the same from anywhere, so they are seeded directly.
+	if allowed[MotionGG] {
+		push(Position{0, 0}, 2, SolverStep{Motion: MotionGG})
+	}
+	if allowed[MotionBigG] {
+		push(Position{len(lines) - 1, 0}, 1, SolverStep{Motion: MotionBigG})
+		for r := range lines {
+			push(Position{r, 0}, len(strconv.Itoa(r+1))+1, SolverStep{Motion: MotionBigG, Count: r + 1})
+		}
+	}
+
+	for d := 0; d < len(buckets); d++ {
+		for i := 0; i < len(buckets[d]); i++ {
+			p := buckets[d][i]
+			if dist[p.Row][p.Col] != d {
+				continue // stale entry
+			}
+			if p == limit.goal {
+				return dist, prev
+			}
+			from = p
+			for _, mo := range []Motion{MotionZero, MotionDollar, MotionCaret} {
+				if allowed[mo] {
+					push(step(lines, p, mo, 0, 1), d+1, SolverStep{Motion: mo})
+				}
+			}
+			for _, mo := range countedMotions {
+				if !allowed[mo] {
+					continue
+				}
+				// Walk the motion once per count so each count reuses the last.
+				cur := p
+				for n := 1; n <= maxSolverCount; n++ {
+					cur = ApplyMotion(lines, cur, mo, 0)
+					next := cur
+					if mo == MotionJ || mo == MotionK {
+						next = step(lines, p, mo, 0, n)
+					}
+					cost, count := 1, 0
+					if n > 1 {
+						cost, count = 2, n
+					}
+					push(next, d+cost, SolverStep{Motion: mo, Count: count})
+				}
+			}
+			if allowed[MotionFChar] || allowed[MotionBigFChar] {
+				line := lines[p.Row]
+				occ := occurrences[p.Row]
+				if occ == nil {
+					occ = lineOccurrences(line)
+					occurrences[p.Row] = occ
+				}
+				for c := 0; c < len(line); c++ {
+					// f reaches a character with no copy between the cursor
+					// and it, F likewise backwards.
+					if c > p.Col && allowed[MotionFChar] && occ[c].prev <= p.Col {
+						push(Position{p.Row, c}, d+2, SolverStep{Motion: MotionFChar, Char: line[c]})
+					} else if c < p.Col && allowed[MotionBigFChar] && (occ[c].next < 0 || occ[c].next >= p.Col) {
+						push(Position{p.Row, c}, d+2, SolverStep{Motion: MotionBigFChar, Char: line[c]})
+					}
+				}
+			}
+		}
+	}
+	return dist, prev
+}
+
+// occurrence links a character of a line to the previous and next columns
+// holding the same character, -1 where there is none.
+type occurrence struct {
+	prev, next int
+}
+
+func lineOccurrences(line string) []occurrence {
+	occ := make([]occurrence, len(line))
+	var last [256]int
+	for i := range last {
+		last[i] = -1
+	}
+	for c := 0; c < len(line); c++ {
+		occ[c] = occurrence{prev: last[line[c]], next: -1}
+		if p := last[line[c]]; p >= 0 {
+			occ[p].next = c
+		}
+		last[line[c]] = c
+	}
+	return occ
+}
+
+// OptimalKeystrokes returns the fewest keystrokes needed to move from one
+// position to another with the allowed motions, or -1 if it cannot be done.
+func OptimalKeystrokes(lines []string, from, to Position, allowed MotionSet) int {
+	dist, _ := shortestPaths(lines, from, allowed, searchLimit{goal: to})
+	if to.Row < 0 || to.Row >= len(dist) || to.Col < 0 || to.Col >= len(dist[to.Row]) {
+		return -1
+	}
+	return dist[to.Row][to.Col]
+}
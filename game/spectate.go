@@ -0,0 +1,469 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"vimgame/race"
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// A SpectatorModel shows other players' runs side by side, read-only. Each
+// run is rebuilt by simulating the player's keys on the same level and
+// seed, so a feed only needs to send every run's replay header and then
+// its keys as they are pressed. Feeds send these messages:
+//
+//	WatchJoinMsg   a player is there, not necessarily playing
+//	WatchRunMsg    a player started a run; its Keys are the keys so far
+//	WatchKeysMsg   a player pressed keys
+//	WatchClockMsg  a player's run time moved on without a key
+//	WatchPlaceMsg  a player finished a race in a place
+//	WatchLeaveMsg  a player left
+type (
+	WatchJoinMsg struct {
+		ID   int
+		Name string
+	}
+	WatchRunMsg struct {
+		ID     int
+		Replay Replay
+	}
+	WatchKeysMsg struct {
+		ID   int
+		Keys []ReplayKey
+	}
+	WatchClockMsg struct {
+		ID int
+		At time.Duration
+	}
+	WatchPlaceMsg struct {
+		ID    int
+		Place int
+	}
+	WatchLeaveMsg struct {
+		ID int
+	}
+)
+
+// Watched is a player being spectated.
+type Watched struct {
+	ID      int
+	Name    string
+	Run     Model // the player's run, rebuilt from their keys
+	Running bool  // Run has been started
+	Place   int   // finishing place in a race, 0 while racing
+	Left    bool
+	Err     error // the run could not be rebuilt
+}
+
+// SpectatorModel is the Bubble Tea model of a spectator.
+type SpectatorModel struct {
+	Title    string
+	Status   string // what the feed is doing
+	Players  []*Watched
+	Pack     Pack // extra content the players have installed
+	Race     *race.Client
+	Playback *RecordingPlayback // non-nil when playing back a recording
+	Width    int
+	Height   int
+}
+
+// NewSpectator returns a spectator fed by Program.Send.
+func NewSpectator(title string, pack Pack) SpectatorModel {
+	return SpectatorModel{Title: title, Pack: pack}
+}
+
+func (s SpectatorModel) Init() tea.Cmd {
+	if s.Race != nil {
+		return waitRace(s.Race)
+	}
+	if s.Playback != nil {
+		return s.Playback.tick()
+	}
+	return nil
+}
+
+func (s SpectatorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.Width, s.Height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		if k := msg.String(); k == "q" || k == "ctrl+c" || k == "esc" {
+			return s, tea.Quit
+		}
+	case WatchJoinMsg:
+		s.player(msg.ID, msg.Name)
+	case WatchRunMsg:
+		s.startRun(s.player(msg.ID, ""), msg.Replay)
+	case WatchKeysMsg:
+		s.feed(s.player(msg.ID, ""), msg.Keys)
+	case WatchClockMsg:
+		s.clock(s.player(msg.ID, ""), msg.At)
+	case WatchPlaceMsg:
+		s.player(msg.ID, "").Place = msg.Place
+	case WatchLeaveMsg:
+		s.Players = slices.DeleteFunc(s.Players, func(w *Watched) bool { return w.ID == msg.ID })
+	case raceMsg:
+		s.raceEvent(race.Message(msg))
+		return s, waitRace(s.Race)
+	case raceClosedMsg:
+		s.Status = "Disconnected from the race"
+		s.Race = nil
+	case recordingTickMsg:
+		if s.Playback == nil || s.Playback.Done() {
+			return s, nil
+		}
+		e := s.Playback.Events[s.Playback.Next]
+		s.Playback.Next++
+		s.feed(s.player(e.ID, ""), []ReplayKey{e.Key})
+		if s.Playback.Done() {
+			for id, place := range s.Playback.Places {
+				s.player(id, "").Place = place
+			}
+			s.Status += "  •  recording finished"
+		}
+		return s, s.Playback.tick()
+	}
+	return s, nil
+}
+
+// player returns the watched player with an id, adding them if new.
+func (s *SpectatorModel) player(id int, name string) *Watched {
+	for _, w := range s.Players {
+		if w.ID == id {
+			if name != "" {
+				w.Name = name
+			}
+			return w
+		}
+	}
+	w := &Watched{ID: id, Name: name}
+	s.Players = append(s.Players, w)
+	return w
+}
+
+// startRun rebuilds a run from its replay header and keys so far.
+func (s *SpectatorModel) startRun(w *Watched, r Replay) {
+	m := NewModel()
+	m.AddPack(s.Pack)
+	keys := r.Keys
+	r.Keys = nil
+	w.Err = m.replayRun(r)
+	w.Run = m
+	w.Running = w.Err == nil
+	w.Place = 0
+	s.feed(w, keys)
+}
+
+// feed plays keys into a player's run.
+func (s *SpectatorModel) feed(w *Watched, keys []ReplayKey) {
+	if !w.Running {
+		return
+	}
+	for _, k := range keys {
+		next, _ := w.Run.Update(ReplayKeyMsg{Key: k.Key, At: time.Duration(k.At) * time.Millisecond})
+		w.Run = next.(Model)
+	}
+}
+
+// clock moves a player's run time on, ending an edit challenge whose clock
+// has run out as the player's own game does.
+func (s *SpectatorModel) clock(w *Watched, at time.Duration) {
+	if !w.Running || at < w.Run.Elapsed {
+		return
+	}
+	w.Run.Elapsed = at
+	if w.Run.GameMode == GameModeEditChallenge && w.Run.State == StatePlaying {
+		w.Run.editTimeUp()
+	}
+}
+
+// --- Race feed ---
+
+// NewRaceSpectator returns a spectator of the race c is watching, caught
+// up with the race so far.
+func NewRaceSpectator(c *race.Client, pack Pack) SpectatorModel {
+	s := SpectatorModel{Title: "Race", Pack: pack, Race: c, Status: "Waiting for the host to start the race"}
+	w := c.Welcome()
+	for _, p := range w.Players {
+		s.player(p.ID, p.Name)
+	}
+	if w.Racing {
+		s.raceStart(w.Seed, w.LevelName, w.LevelHash, w.Players)
+		for _, snap := range w.Snapshot {
+			p := s.player(snap.ID, "")
+			s.feed(p, raceKeys(snap.Progress.Keys))
+			p.Place = snap.Place
+		}
+	}
+	return s
+}
+
+func raceKeys(keys []race.Key) []ReplayKey {
+	out := make([]ReplayKey, len(keys))
+	for i, k := range keys {
+		out[i] = ReplayKey{Key: k.Key, At: k.At}
+	}
+	return out
+}
+
+// raceStart starts every player's run on the race's level and seed.
+func (s *SpectatorModel) raceStart(seed int64, name, hash string, players []race.Player) {
+	m := NewModel()
+	m.AddPack(s.Pack)
+	level, err := raceLevel(m.LevelCatalog, name, hash)
+	if err != nil {
+		s.Status = "Cannot watch: " + err.Error()
+		return
+	}
+	s.Status = "Race on " + name
+	r := Replay{Version: ReplayVersion, Mode: GameModeRace, LevelIndex: level, Level: name, Seed: seed}
+	for _, p := range players {
+		w := s.player(p.ID, p.Name)
+		w.Left = false
+		r.Rules = playerRules(p)
+		s.startRun(w, r)
+	}
+}
+
+func (s *SpectatorModel) raceEvent(msg race.Message) {
+	switch msg.Type {
+	case race.TypeLobby:
+		for _, p := range msg.Players {
+			s.player(p.ID, p.Name)
+		}
+	case race.TypeStart:
+		s.raceStart(msg.Seed, msg.LevelName, msg.LevelHash, msg.Players)
+	case race.TypeProgress:
+		if msg.Progress != nil {
+			s.feed(s.player(msg.ID, ""), raceKeys(msg.Progress.Keys))
+		}
+	case race.TypeResult:
+		s.player(msg.ID, "").Place = msg.Place
+	case race.TypeBye:
+		s.player(msg.ID, "").Left = true
+	}
+}
+
+// --- Recordings ---
+
+// RecordingVersion is the current race recording file format version.
+const RecordingVersion = 1
+
+// Recording is every player's run of a race, for watching it again.
+type Recording struct {
+	Version int           `json:"version"`
+	Title   string        `json:"title"`
+	Runs    []RecordedRun `json:"runs"`
+}
+
+// RecordedRun is one player's run in a recording.
+type RecordedRun struct {
+	Name   string `json:"name"`
+	Place  int    `json:"place,omitempty"`
+	Replay Replay `json:"replay"`
+}
+
+// Recording returns the runs the spectator has seen.
+func (s SpectatorModel) Recording() Recording {
+	rec := Recording{Version: RecordingVersion, Title: s.Status}
+	for _, w := range s.Players {
+		if w.Running {
+			rec.Runs = append(rec.Runs, RecordedRun{Name: w.Name, Place: w.Place, Replay: w.Run.Replay})
+		}
+	}
+	return rec
+}
+
+// SaveRecording writes a recording file.
+func SaveRecording(path string, rec Recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadRecording reads a recording file.
+func LoadRecording(path string) (Recording, error) {
+	var rec Recording
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, fmt.Errorf("%s: %w", path, err)
+	}
+	if rec.Version != RecordingVersion {
+		return rec, fmt.Errorf("%s: unsupported recording version %d", path, rec.Version)
+	}
+	return rec, nil
+}
+
+// recordingTickMsg advances a recording playback to its next key.
+type recordingTickMsg struct{}
+
+// recordedKey is a key of one run in a recording.
+type recordedKey struct {
+	ID  int
+	Key ReplayKey
+}
+
+// RecordingPlayback plays every run of a recording back together in real
+// time.
+type RecordingPlayback struct {
+	Events []recordedKey // every run's keys in time order
+	Next   int
+	Speed  float64
+	Places map[int]int // shown once every key has been played
+}
+
+// Done reports whether every key has been played.
+func (p *RecordingPlayback) Done() bool {
+	return p.Next >= len(p.Events)
+}
+
+func (p *RecordingPlayback) tick() tea.Cmd {
+	if p.Done() {
+		return nil
+	}
+	prev := int64(0)
+	if p.Next > 0 {
+		prev = p.Events[p.Next-1].Key.At
+	}
+	delay := time.Duration(float64(p.Events[p.Next].Key.At-prev)/p.Speed) * time.Millisecond
+	return tea.Tick(delay, func(time.Time) tea.Msg { return recordingTickMsg{} })
+}
+
+// NewRecordingSpectator returns a spectator playing back a recording.
+func NewRecordingSpectator(rec Recording, pack Pack, speed float64) SpectatorModel {
+	if speed <= 0 {
+		speed = 1
+	}
+	s := SpectatorModel{Title: "Replay", Status: rec.Title, Pack: pack, Playback: &RecordingPlayback{Speed: speed, Places: make(map[int]int)}}
+	for i, run := range rec.Runs {
+		w := s.player(i, run.Name)
+		r := run.Replay
+		for _, k := range r.Keys {
+			s.Playback.Events = append(s.Playback.Events, recordedKey{i, k})
+		}
+		r.Keys = nil
+		s.startRun(w, r)
+		s.Playback.Places[i] = run.Place
+	}
+	sort.SliceStable(s.Playback.Events, func(i, j int) bool { return s.Playback.Events[i].Key.At < s.Playback.Events[j].Key.At })
+	return s
+}
+
+// --- View ---
+
+func (s SpectatorModel) View() string {
+	th := ui.CurrentTheme()
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Title).Padding(1, 0, 0, 2)
+	dimStyle := lipgloss.NewStyle().Foreground(th.Dim).PaddingLeft(2)
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Spectating — "+s.Title) + "\n")
+	if s.Status != "" {
+		sb.WriteString(dimStyle.Render(s.Status) + "\n")
+	}
+	sb.WriteString("\n")
+	if len(s.Players) == 0 {
+		sb.WriteString(dimStyle.Render("Nobody is playing yet") + "\n")
+	}
+
+	perRow := len(s.Players)
+	width := 0
+	if s.Width > 0 && perRow > 0 {
+		perRow = max(1, min(perRow, s.Width/36))
+		width = s.Width/perRow - 1
+	}
+	height := 0
+	if s.Height > 0 {
+		rows := (len(s.Players) + perRow - 1) / max(perRow, 1)
+		height = max(3, (s.Height-6)/max(rows, 1)-5)
+	}
+	panels := make([]string, len(s.Players))
+	for i, w := range s.Players {
+		panels[i] = s.panel(i, w, width, height)
+	}
+	sb.WriteString(ui.RenderPanels(panels, perRow) + "\n")
+	sb.WriteString(dimStyle.Render("q: stop watching") + "\n")
+	return sb.String()
+}
+
+// panel renders one player's run.
+func (s SpectatorModel) panel(i int, w *Watched, width, height int) string {
+	header := ui.RenderPlayerTag(w.Name, i)
+	m := w.Run
+	if w.Running {
+		header += fmt.Sprintf("  Score %d", m.Score)
+	}
+	switch {
+	case w.Left:
+		header += "  (left)"
+	case w.Place > 0:
+		header += fmt.Sprintf("  finished #%d", w.Place)
+	}
+
+	info, body := "", ""
+	switch {
+	case w.Err != nil:
+		info = w.Err.Error()
+	case !w.Running:
+		info = "Not playing"
+	case m.State == StatePlaying || m.State == StateExerciseComplete || m.State == StateLevelComplete:
+		info, body = s.runView(i, w, width, height)
+	case m.State == StateGameOver:
+		info = "Run over"
+	default:
+		info = "In the menus"
+	}
+	return ui.RenderSpectatorPanel(header, info, body, width)
+}
+
+// runView renders a run in progress: where it is and the player's buffer,
+// with the cursors of anyone on the same exercise as ghosts.
+func (s SpectatorModel) runView(i int, w *Watched, width, height int) (string, string) {
+	m := w.Run
+	var info string
+	if m.GameMode == GameModeTutorial {
+		info = fmt.Sprintf("Lesson %d  •  Exercise %d/%d", m.LessonIndex+1, m.ExIndex+1, len(m.Lessons[m.LessonIndex].Exercises))
+	} else {
+		level := m.Levels[m.LevelIndex]
+		ex := min(m.ExIndex, len(level.Exercises)-1)
+		info = fmt.Sprintf("%s  •  Exercise %d/%d", level.Name, ex+1, len(level.Exercises))
+		if m.State == StatePlaying && level.Exercises[ex].Type == ExerciseMotion {
+			info += fmt.Sprintf("  •  Targets %d/%d", m.TargetsHit, level.Exercises[ex].NumTargets)
+		}
+		if m.GameMode == GameModeEditChallenge {
+			info += fmt.Sprintf("  •  ⏱ %.1fs", m.EditRun.Remaining(m.Elapsed).Seconds())
+		}
+	}
+	if m.State == StateLevelComplete {
+		return info + "  •  Complete", ""
+	}
+
+	var ghosts []ui.Marker
+	for j, o := range s.Players {
+		om := o.Run
+		if j == i || !o.Running || o.Left || om.State != StatePlaying || om.GameMode != m.GameMode || om.Seed != m.Seed ||
+			om.LevelIndex != m.LevelIndex || om.LessonIndex != m.LessonIndex || om.ExIndex != m.ExIndex {
+			continue
+		}
+		ghosts = append(ghosts, ui.Marker{Row: om.Cursor.Row, Col: om.Cursor.Col, Color: j})
+	}
+	targetRow, targetCol := m.Target.Row, m.Target.Col
+	if m.GoalLines != nil {
+		targetRow, targetCol = -1, -1
+	}
+	return info, ui.RenderBuffer(m.Buffer.Lines, m.bufferView(), m.Cursor.Row, m.Cursor.Col, targetRow, targetCol, height, width, ghosts...)
+}
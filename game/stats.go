@@ -0,0 +1,442 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"vimgame/ui"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxStatsDays caps how many days of trend history a profile keeps.
+const maxStatsDays = 90
+
+// Stats is a profile's per-command usage record.
+type Stats struct {
+	Commands map[string]*CommandStat `json:"commands"` // keyed by command name, e.g. "w", "f{char}"
+	Days     []DayStats              `json:"days"`     // one entry per day played, oldest first
+	Habits   map[AntiPattern]int     `json:"habits"`   // anti-patterns the coach found
+}
+
+// CommandStat counts how a player uses one command. Suggested and Matched
+// compare the player with the solver: Suggested counts targets whose optimal
+// path used the command, Matched those where the player used it too.
+type CommandStat struct {
+	Uses      int       `json:"uses"`
+	WithCount int       `json:"with_count"` // uses with a count prefix, e.g. 5j
+	Suggested int       `json:"suggested"`
+	Matched   int       `json:"matched"`
+	LastUsed  time.Time `json:"last_used,omitzero"`
+}
+
+// Accuracy is the share of suggested uses the player matched, or -1 if the
+// solver never suggested the command.
+func (c *CommandStat) Accuracy() float64 {
+	if c.Suggested == 0 {
+		return -1
+	}
+	return float64(c.Matched) / float64(c.Suggested)
+}
+
+// DayStats is one day of motion-target results for trends over time.
+type DayStats struct {
+	Date        string `json:"date"` // DailyDateFormat
+	Targets     int    `json:"targets"`
+	Optimal     int    `json:"optimal"` // targets reached in the optimal keystrokes
+	Keystrokes  int    `json:"keystrokes"`
+	OptimalKeys int    `json:"optimal_keys"`
+}
+
+// Efficiency is optimal keystrokes over keystrokes used, 1 being perfect.
+func (d DayStats) Efficiency() float64 {
+	if d.Keystrokes == 0 {
+		return 0
+	}
+	return float64(d.OptimalKeys) / float64(d.Keystrokes)
+}
+
+// NewStats returns empty stats.
+func NewStats() *Stats {
+	return &Stats{Commands: make(map[string]*CommandStat), Habits: make(map[AntiPattern]int)}
+}
+
+// canonicalCommand maps the command spellings used by lessons and levels
+// (f{c} vs f{char}) to one name.
+func canonicalCommand(cmd string) string {
+	switch cmd {
+	case "f{c}":
+		return "f{char}"
+	case "F{c}":
+		return "F{char}"
+	}
+	return cmd
+}
+
+// resultCommand names the command a parse result stands for, or "" for
+// partial input and text typed in insert mode.
+func resultCommand(r ParseResult) string {
+	switch r.Action {
+	case ActionMotion:
+		return MotionName(r.Motion)
+	case ActionDeleteChar:
+		return "x"
+	case ActionReplaceChar:
+		return "r"
+	case ActionInsertBefore:
+		return "i"
+	case ActionInsertAfter:
+		return "a"
+	case ActionAppendEOL:
+		return "A"
+	case ActionOpenBelow:
+		return "o"
+	case ActionOpenAbove:
+		return "O"
+	case ActionUndo:
+		return "u"
+	case ActionRedo:
+		return "Ctrl-R"
+	case ActionExitInsert:
+		return "ESC"
+	}
+	return ""
+}
+
+func (s *Stats) command(name string) *CommandStat {
+	c := s.Commands[name]
+	if c == nil {
+		c = &CommandStat{}
+		s.Commands[name] = c
+	}
+	return c
+}
+
+func (s *Stats) day(now time.Time) *DayStats {
+	date := now.Format(DailyDateFormat)
+	if n := len(s.Days); n > 0 && s.Days[n-1].Date == date {
+		return &s.Days[n-1]
+	}
+	s.Days = append(s.Days, DayStats{Date: date})
+	if len(s.Days) > maxStatsDays {
+		s.Days = s.Days[len(s.Days)-maxStatsDays:]
+	}
+	return &s.Days[len(s.Days)-1]
+}
+
+// Record counts one command the player entered.
+func (s *Stats) Record(r ParseResult, now time.Time) {
+	name := resultCommand(r)
+	if name == "" {
+		return
+	}
+	c := s.command(name)
+	c.Uses++
+	if r.Count > 0 {
+		c.WithCount++
+	}
+	c.LastUsed = now
+}
+
+// RecordTarget compares the commands the player used to reach a target with
+// an optimal path and adds the keystrokes to today's trend.
+func (s *Stats) RecordTarget(used []ParseResult, keystrokes int, optimal []SolverStep, optimalKeys int, now time.Time) {
+	usedSet := make(map[string]bool)
+	for _, r := range used {
+		usedSet[resultCommand(r)] = true
+	}
+	seen := make(map[string]bool)
+	for _, st := range optimal {
+		name := MotionName(st.Motion)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		c := s.command(name)
+		c.Suggested++
+		if usedSet[name] {
+			c.Matched++
+		}
+	}
+	d := s.day(now)
+	d.Targets++
+	d.Keystrokes += keystrokes
+	d.OptimalKeys += optimalKeys
+	if keystrokes <= optimalKeys {
+		d.Optimal++
+	}
+}
+
+// recordTargetStats adds the target just reached to the profile's stats,
+// given an optimal path to it (nil if the solver found none).
+func (m *Model) recordTargetStats(path []SolverStep) {
+	if m.Profile == nil || path == nil {
+		return
+	}
+	m.Profile.Stats.RecordTarget(m.Coach.History, m.Keystrokes, path, PathKeystrokes(path), time.Now())
+}
+
+// --- Report ---
+
+// CommandReport is one command's line in the stats report.
+type CommandReport struct {
+	Command   string  `json:"command"`
+	Uses      int     `json:"uses"`
+	Share     float64 `json:"share"`      // fraction of all command uses
+	CountRate float64 `json:"count_rate"` // fraction of uses with a count
+	Accuracy  float64 `json:"accuracy"`   // -1 when never suggested
+	Suggested int     `json:"suggested"`
+	Taught    bool    `json:"taught"`
+}
+
+// StatsReport summarizes a profile's stats for the stats screen and export.
+type StatsReport struct {
+	Profile    string          `json:"profile"`
+	Generated  time.Time       `json:"generated"`
+	Commands   []CommandReport `json:"commands"` // most used first
+	NeverUsed  []string        `json:"never_used"`
+	Weak       []string        `json:"weak"` // often optimal, rarely used
+	Efficiency float64         `json:"efficiency"`
+	Trend      []DayStats      `json:"trend"`
+	Habits     []PatternCount  `json:"habits"` // most frequent first
+}
+
+// Weak commands were suggested at least weakMinSuggested times and matched
+// less than weakAccuracy of the time.
+const (
+	weakMinSuggested = 3
+	weakAccuracy     = 0.5
+)
+
+// TaughtCommands returns the commands introduced by the completed lessons,
+// or by every lesson if none is completed yet.
+func TaughtCommands(lessons []Lesson, p *Profile) []string {
+	var all, done []string
+	for _, l := range lessons {
+		for _, c := range l.NewCommands {
+			c = canonicalCommand(c)
+			all = append(all, c)
+			if p != nil && p.LessonDone(l.Name) {
+				done = append(done, c)
+			}
+		}
+	}
+	if len(done) > 0 {
+		return done
+	}
+	return all
+}
+
+// Report builds the stats report. taught lists the commands the player has
+// been taught; those never used are called out.
+func (s *Stats) Report(profile string, taught []string) StatsReport {
+	rep := StatsReport{
+		Profile:   profile,
+		Generated: time.Now(),
+		Commands:  []CommandReport{},
+		NeverUsed: []string{},
+		Weak:      []string{},
+		Trend:     s.Days,
+		Habits:    topPatterns(s.Habits, 0),
+	}
+	if rep.Trend == nil {
+		rep.Trend = []DayStats{}
+	}
+	taughtSet := make(map[string]bool)
+	for _, c := range taught {
+		taughtSet[canonicalCommand(c)] = true
+	}
+
+	total := 0
+	for _, c := range s.Commands {
+		total += c.Uses
+	}
+	for name, c := range s.Commands {
+		cr := CommandReport{
+			Command:   name,
+			Uses:      c.Uses,
+			Accuracy:  c.Accuracy(),
+			Suggested: c.Suggested,
+			Taught:    taughtSet[name],
+		}
+		if total > 0 {
+			cr.Share = float64(c.Uses) / float64(total)
+		}
+		if c.Uses > 0 {
+			cr.CountRate = float64(c.WithCount) / float64(c.Uses)
+		}
+		rep.Commands = append(rep.Commands, cr)
+		if c.Suggested >= weakMinSuggested && c.Accuracy() < weakAccuracy {
+			rep.Weak = append(rep.Weak, name)
+		}
+	}
+	sort.Slice(rep.Commands, func(i, j int) bool {
+		if rep.Commands[i].Uses != rep.Commands[j].Uses {
+			return rep.Commands[i].Uses > rep.Commands[j].Uses
+		}
+		return rep.Commands[i].Command < rep.Commands[j].Command
+	})
+	sort.Strings(rep.Weak)
+
+	seen := make(map[string]bool)
+	for _, c := range taught {
+		c = canonicalCommand(c)
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if st := s.Commands[c]; st == nil || st.Uses == 0 {
+			rep.NeverUsed = append(rep.NeverUsed, c)
+		}
+	}
+
+	keys, opt := 0, 0
+	for _, d := range s.Days {
+		keys += d.Keystrokes
+		opt += d.OptimalKeys
+	}
+	if keys > 0 {
+		rep.Efficiency = float64(opt) / float64(keys)
+	}
+	return rep
+}
+
+// String renders the report as plain text.
+func (r StatsReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Stats for %s\n\n", r.Profile)
+	if len(r.Commands) == 0 {
+		sb.WriteString("No commands recorded yet.\n")
+	} else {
+		fmt.Fprintf(&sb, "%-9s %6s %6s %7s %9s\n", "Command", "Uses", "Share", "Counted", "Accuracy")
+		for _, c := range r.Commands {
+			acc := "—"
+			if c.Accuracy >= 0 {
+				acc = fmt.Sprintf("%.0f%%", c.Accuracy*100)
+			}
+			fmt.Fprintf(&sb, "%-9s %6d %5.0f%% %6.0f%% %9s\n", c.Command, c.Uses, c.Share*100, c.CountRate*100, acc)
+		}
+	}
+	if len(r.NeverUsed) > 0 {
+		fmt.Fprintf(&sb, "\nTaught but never used: %s\n", strings.Join(r.NeverUsed, " "))
+	}
+	if len(r.Weak) > 0 {
+		fmt.Fprintf(&sb, "Often optimal, rarely used: %s\n", strings.Join(r.Weak, " "))
+	}
+	if len(r.Habits) > 0 {
+		sb.WriteString("\nHabits to work on:\n")
+		for _, h := range r.Habits {
+			fmt.Fprintf(&sb, "  %-36s ×%d\n", h.Pattern, h.Count)
+		}
+	}
+	if len(r.Trend) > 0 {
+		fmt.Fprintf(&sb, "\nEfficiency: %.0f%% of optimal\n", r.Efficiency*100)
+		start := max(len(r.Trend)-7, 0)
+		for _, d := range r.Trend[start:] {
+			fmt.Fprintf(&sb, "  %s  %3d targets  %3.0f%%\n", d.Date, d.Targets, d.Efficiency()*100)
+		}
+	}
+	return sb.String()
+}
+
+// taughtCommands returns the commands the current profile has been taught.
+func (m Model) taughtCommands() []string {
+	return TaughtCommands(m.Lessons, m.Profile)
+}
+
+// statsReport builds the current profile's stats report.
+func (m Model) statsReport() StatsReport {
+	return m.Profile.Stats.Report(m.Profile.Name, m.taughtCommands())
+}
+
+// statsRows caps the commands listed on the stats screen.
+const statsRows = 12
+
+func (m Model) viewStats() string {
+	th := ui.CurrentTheme()
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(th.Title).
+		Padding(1, 2)
+
+	headStyle := lipgloss.NewStyle().
+		Foreground(th.Dim).
+		Bold(true)
+
+	cmdStyle := lipgloss.NewStyle().
+		Foreground(th.Highlight).
+		Bold(true)
+
+	textStyle := lipgloss.NewStyle().
+		Foreground(th.Text)
+
+	infoStyle := lipgloss.NewStyle().
+		Foreground(th.Dim)
+
+	warnStyle := lipgloss.NewStyle().
+		Foreground(th.Warn)
+
+	rep := m.statsReport()
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Stats — " + rep.Profile))
+	sb.WriteString("\n\n")
+
+	if len(rep.Commands) == 0 {
+		sb.WriteString(infoStyle.Render("    No commands recorded yet. Play a lesson or challenge first.") + "\n")
+	} else {
+		sb.WriteString(headStyle.Render(fmt.Sprintf("    %-9s %6s  %-20s %7s %9s", "Command", "Uses", "", "Counted", "Accuracy")) + "\n")
+		top := rep.Commands[0].Uses
+		for i, c := range rep.Commands {
+			if i == statsRows {
+				sb.WriteString(infoStyle.Render(fmt.Sprintf("    … and %d more", len(rep.Commands)-statsRows)) + "\n")
+				break
+			}
+			bar := ""
+			if c.Uses > 0 {
+				bar = strings.Repeat("█", max(c.Uses*20/top, 1))
+			}
+			acc := "—"
+			if c.Accuracy >= 0 {
+				acc = fmt.Sprintf("%.0f%%", c.Accuracy*100)
+			}
+			sb.WriteString("    " + cmdStyle.Render(fmt.Sprintf("%-9s", c.Command)) +
+				textStyle.Render(fmt.Sprintf(" %6d  %-20s %6.0f%% %9s", c.Uses, bar, c.CountRate*100, acc)) + "\n")
+		}
+	}
+
+	if len(rep.NeverUsed) > 0 {
+		sb.WriteString("\n  " + warnStyle.Render("Taught but never used: ") + cmdStyle.Render(strings.Join(rep.NeverUsed, " ")) + "\n")
+	}
+	if len(rep.Weak) > 0 {
+		sb.WriteString("  " + warnStyle.Render("Often optimal, rarely used: ") + cmdStyle.Render(strings.Join(rep.Weak, " ")) + "\n")
+	}
+
+	if len(rep.Habits) > 0 {
+		sb.WriteString("\n  " + warnStyle.Render("Habits to work on: "))
+		for i, h := range rep.Habits[:min(len(rep.Habits), 3)] {
+			if i > 0 {
+				sb.WriteString(infoStyle.Render(", "))
+			}
+			sb.WriteString(textStyle.Render(fmt.Sprintf("%s ×%d", h.Pattern, h.Count)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(rep.Trend) > 0 {
+		sb.WriteString("\n  " + headStyle.Render(fmt.Sprintf("Efficiency %.0f%% of optimal", rep.Efficiency*100)) + "\n")
+		start := max(len(rep.Trend)-7, 0)
+		for _, d := range rep.Trend[start:] {
+			eff := d.Efficiency()
+			bar := strings.Repeat("█", int(eff*20))
+			sb.WriteString(infoStyle.Render("    "+d.Date+"  ") +
+				textStyle.Render(fmt.Sprintf("%-20s %3.0f%%", bar, eff*100)) +
+				infoStyle.Render(fmt.Sprintf("  %d targets", d.Targets)) + "\n")
+		}
+	}
+
+	sb.WriteString("\n" + infoStyle.Render("  Accuracy: how often you used a command when it was on the optimal path") + "\n")
+	sb.WriteString(infoStyle.Render("  ESC: back  •  vimgame stats -json to export") + "\n")
+	return sb.String()
+}
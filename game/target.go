@@ -0,0 +1,188 @@
+package game
+
+import (
+	"math/rand"
+	"unicode"
+)
+
+// TargetStrategy selects which buffer positions a motion exercise targets.
+type TargetStrategy int
+
+const (
+	TargetAny        TargetStrategy = iota // any non-space character
+	TargetWord                             // start or end of a word (w, b, e)
+	TargetLineEdge                         // line start, first non-blank or line end (0, ^, $)
+	TargetUniqueChar                       // a character unique on its line (f, F)
+)
+
+var targetStrategyNames = map[TargetStrategy]string{
+	TargetAny:        "any",
+	TargetWord:       "word",
+	TargetLineEdge:   "line-edge",
+	TargetUniqueChar: "unique-char",
+}
+
+func (s TargetStrategy) String() string {
+	return targetStrategyNames[s]
+}
+
+// ParseTargetStrategy parses a strategy name as used in level files.
+func ParseTargetStrategy(name string) (TargetStrategy, bool) {
+	for s, n := range targetStrategyNames {
+		if n == name {
+			return s, true
+		}
+	}
+	return TargetAny, false
+}
+
+// TargetSpec describes how the next target should be chosen.
+type TargetSpec struct {
+	Strategy   TargetStrategy
+	Difficulty int       // desired optimal keystrokes to reach the target (0 = any)
+	Allowed    MotionSet // motions the player knows; targets must be reachable with them
+}
+
+// minTargetKeys is the fewest optimal keystrokes a target may need, so that
+// a target is never one keypress away.
+const minTargetKeys = 2
+
+// ChooseTarget picks the next target for a motion exercise. Candidates must
+// match the strategy and be reachable with the allowed motions. With a
+// difficulty, targets whose optimal keystroke count is closest to it are
+// preferred. It falls back to looser candidates rather than failing.
+func ChooseTarget(rng *rand.Rand, lines []string, cursor Position, spec TargetSpec) Position {
+	allowed := spec.Allowed
+	if len(allowed) == 0 {
+		allowed = AllMotions()
+	}
+	if spec.Difficulty > 0 {
+		// Targets within a keystroke of the difficulty are the ones wanted,
+		// and on a large buffer they are near the cursor. Only if there are
+		// none is the whole buffer searched.
+		dist, _ := shortestPaths(lines, cursor, allowed, searchLimit{maxDist: spec.Difficulty + 1, goal: Position{-1, -1}})
+		candidates := closestTargets(targetCandidates(lines, cursor, spec.Strategy, dist, false), dist, spec.Difficulty)
+		if len(candidates) > 0 && targetGap(candidates[0], dist, spec.Difficulty) == 0 {
+			return candidates[rng.Intn(len(candidates))]
+		}
+	}
+	dist := KeystrokeDistances(lines, cursor, allowed)
+	candidates := targetCandidates(lines, cursor, spec.Strategy, dist, true)
+	if len(candidates) == 0 {
+		return GenerateTarget(rng, lines, cursor, 3)
+	}
+	if spec.Difficulty > 0 {
+		candidates = closestTargets(candidates, dist, spec.Difficulty)
+	}
+	return candidates[rng.Intn(len(candidates))]
+}
+
+// targetCandidates returns the reachable positions matching a strategy, in
+// buffer order. With fallback, any reachable character is a candidate when
+// none match the strategy.
+func targetCandidates(lines []string, cursor Position, strategy TargetStrategy, dist [][]int, fallback bool) []Position {
+	reachable := func(p Position) bool {
+		return dist[p.Row][p.Col] >= minTargetKeys
+	}
+
+	var candidates []Position
+	for r, line := range lines {
+		for c := range line {
+			p := Position{r, c}
+			if p != cursor && matchesStrategy(lines, p, strategy) && reachable(p) {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	if len(candidates) == 0 && fallback && strategy != TargetAny {
+		for r, line := range lines {
+			for c := range line {
+				p := Position{r, c}
+				if p != cursor && matchesStrategy(lines, p, TargetAny) && reachable(p) {
+					candidates = append(candidates, p)
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// closestTargets returns the candidates whose optimal keystroke count is
+// closest to the difficulty.
+func closestTargets(candidates []Position, dist [][]int, difficulty int) []Position {
+	best := -1
+	var closest []Position
+	for _, p := range candidates {
+		gap := targetGap(p, dist, difficulty)
+		switch {
+		case best < 0 || gap < best:
+			best = gap
+			closest = []Position{p}
+		case gap == best:
+			closest = append(closest, p)
+		}
+	}
+	return closest
+}
+
+// targetGap returns how far a position's optimal keystroke count is from
+// the difficulty.
+func targetGap(p Position, dist [][]int, difficulty int) int {
+	gap := abs(dist[p.Row][p.Col] - difficulty)
+	if gap <= 1 {
+		gap = 0 // anything within one keystroke of the goal is fine
+	}
+	return gap
+}
+
+// matchesStrategy reports whether the position is a valid target for s.
+func matchesStrategy(lines []string, p Position, s TargetStrategy) bool {
+	line := lines[p.Row]
+	ch := line[p.Col]
+	if ch == ' ' || ch == '\t' {
+		return false
+	}
+	switch s {
+	case TargetWord:
+		return isWordStart(line, p.Col) || isWordEnd(line, p.Col)
+	case TargetLineEdge:
+		first := firstNonBlank(line)
+		return p.Col == 0 || p.Col == first || p.Col == len(line)-1
+	case TargetUniqueChar:
+		for i := 0; i < len(line); i++ {
+			if i != p.Col && line[i] == ch {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+func wordClass(ch byte) int {
+	switch {
+	case ch == ' ' || ch == '\t':
+		return 0
+	case isWordChar(ch):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func isWordStart(line string, col int) bool {
+	return wordClass(line[col]) != 0 && (col == 0 || wordClass(line[col-1]) != wordClass(line[col]))
+}
+
+func isWordEnd(line string, col int) bool {
+	return wordClass(line[col]) != 0 && (col == len(line)-1 || wordClass(line[col+1]) != wordClass(line[col]))
+}
+
+func firstNonBlank(line string) int {
+	for i, ch := range line {
+		if !unicode.IsSpace(ch) {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+var targetLines = []string{
+	"func main() {",
+	"	fmt.Println(\"hello, world\")",
+	"	for i := 0; i < 10; i++ {",
+	"		total += values[i] * weight",
+	"	}",
+	"	return",
+	"}",
+}
+
+func TestChooseTargetDifficulty(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		commands   []string
+		strategy   TargetStrategy
+		difficulty int
+	}{
+		{"hjkl near", []string{"h", "j", "k", "l"}, TargetAny, 2},
+		{"hjkl far", []string{"h", "j", "k", "l"}, TargetAny, 4},
+		{"words", []string{"w", "b", "e", "j", "k"}, TargetWord, 3},
+		{"every motion", nil, TargetAny, 3},
+		{"line edges", nil, TargetLineEdge, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed := MotionsForCommands(tc.commands)
+			if len(allowed) == 0 {
+				allowed = AllMotions()
+			}
+			spec := TargetSpec{Strategy: tc.strategy, Difficulty: tc.difficulty, Allowed: allowed}
+			rng := rand.New(rand.NewSource(1))
+			cursor := Position{0, 0}
+			for i := 0; i < 20; i++ {
+				target := ChooseTarget(rng, targetLines, cursor, spec)
+				keys := OptimalKeystrokes(targetLines, cursor, target, allowed)
+				if keys < minTargetKeys || abs(keys-tc.difficulty) > 1 {
+					t.Fatalf("target %v from %v takes %d keystrokes, want %d±1", target, cursor, keys, tc.difficulty)
+				}
+				if !matchesStrategy(targetLines, target, tc.strategy) {
+					t.Fatalf("target %v does not match strategy %v", target, tc.strategy)
+				}
+				cursor = target
+			}
+		})
+	}
+}
+
+func TestOptimalKeystrokes(t *testing.T) {
+	lines := []string{"alpha beta", "gamma"}
+	for _, tc := range []struct {
+		name     string
+		commands []string
+		from, to Position
+		want     int
+	}{
+		{"counted l", []string{"l"}, Position{0, 0}, Position{0, 3}, 2},
+		{"word", []string{"w"}, Position{0, 0}, Position{0, 6}, 1},
+		{"down", []string{"j"}, Position{0, 0}, Position{1, 0}, 1},
+		{"no motion right", []string{"h"}, Position{0, 0}, Position{0, 3}, -1},
+		{"no motion across", []string{"j", "k"}, Position{0, 0}, Position{1, 3}, -1},
+		{"outside the buffer", []string{"j"}, Position{0, 0}, Position{5, 0}, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := OptimalKeystrokes(lines, tc.from, tc.to, MotionsForCommands(tc.commands))
+			if got != tc.want {
+				t.Errorf("OptimalKeystrokes(%v → %v) = %d, want %d", tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package game
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TeamBoard is a shared leaderboard server the game submits runs to and
+// fetches boards from. The server re-simulates each submitted replay, so
+// only the replay and the player's name are sent.
+type TeamBoard interface {
+	Submit(name string, r Replay) ([]TeamRank, error)
+	Boards() (map[string][]LeaderboardEntry, error)
+	ReplayURL(board string, rank int) string
+	Replay(board string, rank int) (Replay, error)
+}
+
+// TeamRank is where a submitted run placed on one team board; Rank is -1 if
+// it did not place.
+type TeamRank struct {
+	Board string `json:"board"`
+	Rank  int    `json:"rank"`
+	Score int    `json:"score"`
+}
+
+// teamSubmitMsg carries the team server's answer to a submission.
+type teamSubmitMsg struct {
+	ranks []TeamRank
+	err   error
+}
+
+// teamBoardsMsg carries boards fetched from the team server.
+type teamBoardsMsg struct {
+	boards map[string][]LeaderboardEntry
+	err    error
+}
+
+// submitTeam sends the run just ranked locally to the team server.
+func (m Model) submitTeam() tea.Cmd {
+	team := m.Team
+	name := "player"
+	if m.Profile != nil {
+		name = m.Profile.Name
+	}
+	r := m.Replay
+	r.Keys = append([]ReplayKey(nil), m.Replay.Keys...)
+	return func() tea.Msg {
+		ranks, err := team.Submit(name, r)
+		return teamSubmitMsg{ranks, err}
+	}
+}
+
+// fetchTeamBoards loads the team server's boards for the leaderboard screen.
+func (m Model) fetchTeamBoards() tea.Cmd {
+	team := m.Team
+	return func() tea.Msg {
+		boards, err := team.Boards()
+		return teamBoardsMsg{boards, err}
+	}
+}
+
+// teamSubmitted reports a submission's result on the next screen.
+func (m *Model) teamSubmitted(msg teamSubmitMsg) {
+	if msg.err != nil {
+		m.TeamMsg = "Team board: " + msg.err.Error()
+		return
+	}
+	m.TeamMsg = "Team board: submitted, did not place"
+	for _, r := range msg.ranks {
+		if r.Rank >= 0 {
+			m.TeamMsg = fmt.Sprintf("Team board: #%d on %s", r.Rank+1, BoardTitle(r.Board))
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// parSeed seeds the sample target sequence used to estimate motion par.
+const parSeed = 1
+
+// ExerciseReport summarizes one exercise for pack authors.
+type ExerciseReport struct {
+	File    string
+	Line    int
+	Owner   string // e.g. `lesson "Moving Around"`
+	Index   int    // 1-based exercise number within its lesson or level
+	Type    ExerciseType
+	Par     int // estimated optimal keystrokes for the whole exercise
+	Targets int // motion exercises: targets in the sampled sequence
+}
+
+func (r ExerciseReport) String() string {
+	if r.Type == ExerciseMotion {
+		return fmt.Sprintf("%s:%d: %s exercise %d (motion): par %d over %d targets", r.File, r.Line, r.Owner, r.Index, r.Par, r.Targets)
+	}
+	return fmt.Sprintf("%s:%d: %s exercise %d (edit): par %d", r.File, r.Line, r.Owner, r.Index, r.Par)
+}
+
+// BuiltinPacks returns the embedded lesson and level packs.
+func BuiltinPacks() []Pack {
+	return []Pack{builtinPack("lessons.pack"), builtinPack("levels.pack")}
+}
+
+// CheckPacks checks packs the way the game would load them, in order, and
+// reports par keystrokes for every exercise. On top of ValidatePack it
+// flags duplicate lesson and level names, edit goals that cannot be reached
+// with the commands taught so far (or the level's or the exercise's own
+// commands), and motion exercises whose targets cannot be reached with the
+// known motions.
+func CheckPacks(packs []Pack) ([]ExerciseReport, PackErrors) {
+	var reports []ExerciseReport
+	var errs PackErrors
+	at := func(pos srcPos, format string, args ...any) {
+		errs = append(errs, PackError{File: pos.file, Line: pos.line, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	lessonNames := make(map[string]srcPos)
+	levelNames := make(map[string]srcPos)
+	var taught []string
+
+	check := func(owner string, exercises []Exercise, commands []string) {
+		for i, ex := range exercises {
+			rep := ExerciseReport{File: ex.src.file, Line: ex.src.line, Owner: owner, Index: i + 1, Type: ex.Type}
+			where := fmt.Sprintf("%s exercise %d", owner, i+1)
+			commands := commands
+			if ex.Commands != nil {
+				commands = ex.Commands
+			}
+			if ex.Type == ExerciseEdit {
+				plan := SolveEdit(ex.InitBuffer, ex.GoalBuffer, ex.StartCursor, commands)
+				for _, p := range plan.Problems {
+					at(ex.src, "%s: goal unreachable: %s", where, p)
+				}
+				rep.Par = plan.Par
+			} else {
+				par, ok := motionPar(ex, commands)
+				if !ok {
+					motions := "the motions " + strings.Join(commands, " ")
+					if len(MotionsForCommands(commands)) == 0 {
+						motions = "any motion"
+					}
+					at(ex.src, "%s: targets cannot be reached with %s", where, motions)
+				}
+				rep.Par, rep.Targets = par, ex.NumTargets
+			}
+			reports = append(reports, rep)
+		}
+	}
+
+	for _, pack := range packs {
+		for _, l := range pack.Lessons {
+			if prev, ok := lessonNames[l.Name]; ok {
+				at(l.src, "duplicate lesson name %q (first declared at %s:%d)", l.Name, prev.file, prev.line)
+			} else {
+				lessonNames[l.Name] = l.src
+			}
+			taught = append(taught, l.NewCommands...)
+			check(fmt.Sprintf("lesson %q", l.Name), l.Exercises, taught)
+		}
+		for _, l := range pack.Levels {
+			if prev, ok := levelNames[l.Name]; ok {
+				at(l.src, "duplicate level name %q (first declared at %s:%d)", l.Name, prev.file, prev.line)
+			} else {
+				levelNames[l.Name] = l.src
+			}
+			check(fmt.Sprintf("level %q", l.Name), l.Exercises, l.Commands)
+		}
+	}
+	return reports, errs
+}
+
+// motionPar sums the optimal keystrokes over a sampled target sequence.
+// It reports false if some target is unreachable with the allowed motions,
+// which are all of them when commands name none, as in the game.
+func motionPar(ex Exercise, commands []string) (int, bool) {
+	allowed := MotionsForCommands(commands)
+	if len(allowed) == 0 {
+		allowed = AllMotions()
+	}
+	if len(ex.InitBuffer) == 0 {
+		return 0, false
+	}
+	rng := rand.New(rand.NewSource(parSeed))
+	spec := TargetSpec{Strategy: ex.Targets, Difficulty: ex.Difficulty, Allowed: allowed}
+	cursor := ex.StartCursor
+	par := 0
+	for i := 0; i < ex.NumTargets; i++ {
+		target := ChooseTarget(rng, ex.InitBuffer, cursor, spec)
+		d := OptimalKeystrokes(ex.InitBuffer, cursor, target, allowed)
+		if d < 0 {
+			return par, false
+		}
+		par += d
+		cursor = target
+	}
+	return par, true
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// motionLevel is a level pack with one motion exercise; commands is its
+// commands line.
+func motionLevel(name, commands string) string {
+	return "level " + name + "\n" + commands + `
+exercise motion
+cursor 1 1
+targets 3
+buffer
+alpha beta gamma
+delta epsilon
+zeta eta theta
+end
+`
+}
+
+const editLevel = `level Edits
+commands h l
+exercise edit
+cursor 1 1
+buffer
+alpha beta
+end
+goal
+beta
+end
+`
+
+func TestCheckPacks(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		errs []string // substrings of the expected errors, in order
+	}{
+		{"commands", motionLevel("Motions", "commands h j k l"), nil},
+		{"no commands allow every motion", motionLevel("Motions", ""), nil},
+		{"unreachable targets", motionLevel("Motions", "commands 0"), []string{
+			`test.pack:3: level "Motions" exercise 1: targets cannot be reached with the motions 0`,
+		}},
+		{"duplicate level", motionLevel("Twice", "") + motionLevel("Twice", ""), []string{
+			`test.pack:11: duplicate level name "Twice" (first declared at test.pack:1)`,
+		}},
+		{"unreachable goal", editLevel, []string{`test.pack:3: level "Edits" exercise 1: goal unreachable`}},
+		{"exercise commands", strings.Replace(editLevel, "exercise edit\n", "exercise edit\ncommands w x\n", 1), nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pack := parseTestPack(t, "test.pack", tc.text)
+			reports, errs := CheckPacks([]Pack{pack})
+			if len(errs) != len(tc.errs) {
+				t.Fatalf("errors %v, want %d", errs, len(tc.errs))
+			}
+			for i, want := range tc.errs {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("error %q, want %q", errs[i], want)
+				}
+			}
+			if len(tc.errs) == 0 {
+				for _, r := range reports {
+					if r.Par <= 0 {
+						t.Errorf("%s: par %d", r, r.Par)
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,18 +1,616 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"vimgame/game"
+	"vimgame/race"
+	"vimgame/server"
+	"vimgame/sshd"
+	"vimgame/store"
+	"vimgame/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	p := tea.NewProgram(game.NewModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	var err error
+	cmd := ""
+	if len(os.Args) > 1 {
+		cmd = os.Args[1]
+	}
+	switch cmd {
+	case "replay":
+		err = runReplay(os.Args[2:])
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "practice":
+		err = runPractice(os.Args[2:])
+	case "stats":
+		err = runStats(os.Args[2:])
+	case "serve":
+		err = runServe(os.Args[2:])
+	case "sshd":
+		err = runSSHD(os.Args[2:])
+	case "ghost":
+		err = runGhost(os.Args[2:])
+	case "host":
+		err = runHost(os.Args[2:])
+	case "join":
+		err = runJoin(os.Args[2:])
+	case "spectate":
+		err = runSpectate(os.Args[2:])
+	default:
+		err = runPlay(os.Args[1:])
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// runPlay launches the interactive game.
+func runPlay(args []string) error {
+	fs := flag.NewFlagSet("vimgame", flag.ExitOnError)
+	record := fs.String("record", "", "write a replay of the last run to `file` on exit")
+	seed := fs.Int64("seed", 0, "use a fixed RNG `seed` so target sequences are reproducible")
+	profile := fs.String("profile", "", "play as the named `profile` instead of choosing one")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`")
+	team := fs.String("server", os.Getenv("VIMGAME_SERVER"), "submit runs to the team leaderboard server at `url`")
+	theme := themeFlag(fs)
+	fs.Parse(args)
+
+	m := game.NewModel()
+	if err := attachConfig(&m, *theme); err != nil {
+		return err
+	}
+	if err := useTheme(*theme); err != nil {
+		return err
+	}
+	m.FixedSeed = *seed
+	if err := attachProfile(&m, *profile); err != nil {
+		return err
+	}
+	boards, err := game.DefaultLeaderboards()
+	if err != nil {
+		return err
+	}
+	m.Leaderboards = boards
+	if *team != "" {
+		m.Team = server.NewClient(*team)
+	}
+	pack, err := game.LoadPackDir(*packs)
+	var perrs game.PackErrors
+	if errors.As(err, &perrs) {
+		m.PackErr = err
+	} else if err != nil {
+		return err
+	}
+	m.AddPack(pack)
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		return err
+	}
+	if *record != "" {
+		m = final.(game.Model)
+		if len(m.Replay.Keys) == 0 {
+			return fmt.Errorf("nothing to record: no run was played")
+		}
+		return game.SaveReplay(*record, m.Replay)
+	}
+	return nil
+}
+
+// runPractice starts a motion challenge over one of the player's own files.
+func runPractice(args []string) error {
+	cfg, err := game.DefaultConfig()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("vimgame practice", flag.ExitOnError)
+	targets := fs.Int("targets", game.DefaultPracticeTargets, "number of targets to hit")
+	difficulty := fs.Int("difficulty", 3, "minimum keystrokes to reach each target")
+	edits := fs.Int("edits", 0, "for Go files, add `n` edit exercises generated from the code")
+	tabWidth := fs.Int("tabwidth", cfg.TabWidth, "expand tabs to `n` columns")
+	record := fs.String("record", "", "write a replay of the run to `file` on exit")
+	seed := fs.Int64("seed", 0, "use a fixed RNG `seed` so target sequences are reproducible")
+	profile := fs.String("profile", game.DefaultProfileName, "record progress in the named `profile`")
+	team := fs.String("server", os.Getenv("VIMGAME_SERVER"), "submit runs to the team leaderboard server at `url`")
+	theme := themeFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vimgame practice [flags] <file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	m := game.NewModel()
+	if err := m.UseConfig(cfg, *theme != ""); err != nil {
+		return err
+	}
+	m.Bell = os.Stdout
+	if err := useTheme(*theme); err != nil {
+		return err
+	}
+	path, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	m.FixedSeed = *seed
+	if err := attachProfile(&m, *profile); err != nil {
+		return err
+	}
+	boards, err := game.DefaultLeaderboards()
+	if err != nil {
+		return err
+	}
+	m.Leaderboards = boards
+	if *team != "" {
+		m.Team = server.NewClient(*team)
+	}
+	spec := game.PracticeSpec{File: path, TabWidth: *tabWidth, Targets: *targets, Difficulty: *difficulty, Edits: *edits}
+	if err := m.StartPractice(spec); err != nil {
+		return err
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		return err
+	}
+	if *record != "" {
+		return game.SaveReplay(*record, final.(game.Model).Replay)
+	}
+	return nil
+}
+
+// runReplay plays a recorded run back in the terminal.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("vimgame replay", flag.ExitOnError)
+	speed := fs.Float64("speed", 1, "playback speed multiplier")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`; the recording player's packs")
+	theme := themeFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vimgame replay [flags] <file or url>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := useTheme(*theme); err != nil {
+		return err
+	}
+
+	var r game.Replay
+	var err error
+	if arg := fs.Arg(0); strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		r, err = server.FetchReplay(arg)
+	} else {
+		r, err = game.LoadReplay(arg)
+	}
+	if err != nil {
+		return err
+	}
+	pack, err := game.LoadPackDir(*packs)
+	if err != nil {
+		return err
+	}
+	m, err := game.NewPlaybackModel(r, *speed, pack)
+	if err != nil {
+		return err
+	}
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// runGhost plays a run against the ghost of a recorded one, on the same
+// level and seed.
+func runGhost(args []string) error {
+	fs := flag.NewFlagSet("vimgame ghost", flag.ExitOnError)
+	name := fs.String("name", "", "call the ghost `name` (default: the replay's file name)")
+	profile := fs.String("profile", game.DefaultProfileName, "record progress in the named `profile`")
+	record := fs.String("record", "", "write a replay of the run to `file` on exit")
+	team := fs.String("server", os.Getenv("VIMGAME_SERVER"), "submit runs to the team leaderboard server at `url`")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`; the recording player's packs")
+	theme := themeFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vimgame ghost [flags] <replay file or url>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := useTheme(*theme); err != nil {
+		return err
+	}
+
+	var r game.Replay
+	var err error
+	arg := fs.Arg(0)
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		r, err = server.FetchReplay(arg)
+	} else {
+		r, err = game.LoadReplay(arg)
+	}
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+		if strings.Contains(arg, "://") {
+			*name = "ghost"
+		}
+	}
+
+	m := game.NewModel()
+	if err := attachProfile(&m, *profile); err != nil {
+		return err
+	}
+	boards, err := game.DefaultLeaderboards()
+	if err != nil {
+		return err
+	}
+	m.Leaderboards = boards
+	if *team != "" {
+		m.Team = server.NewClient(*team)
+	}
+	pack, err := game.LoadPackDir(*packs)
+	if err != nil {
+		return err
+	}
+	m.AddPack(pack)
+	if err := m.StartGhost(r, *name); err != nil {
+		return err
+	}
+
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return err
+	}
+	if *record != "" {
+		return game.SaveReplay(*record, final.(game.Model).Replay)
+	}
+	return nil
+}
+
+// runValidate checks lesson/level packs offline and reports par keystrokes.
+// Problems are printed as file:line diagnostics and make it exit non-zero.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("vimgame validate", flag.ExitOnError)
+	quiet := fs.Bool("q", false, "only print problems, not the par report")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vimgame validate [-q] [pack files or dirs...]")
+		fmt.Fprintln(fs.Output(), "With no arguments, checks the built-in content and the user pack directory.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{filepath.Join(store.DataDir(), "packs")}
+	}
+
+	// Built-in content is always loaded first, as in the game.
+	packs := game.BuiltinPacks()
+	reportFrom := len(packs)
+	if fs.NArg() == 0 {
+		reportFrom = 0
+	}
+
+	failed := 0
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			files, _ = filepath.Glob(filepath.Join(path, "*"+game.PackExt))
+		} else if err != nil && fs.NArg() == 0 {
+			continue // no user pack directory
+		}
+		for _, file := range files {
+			pack, err := game.LoadPackFile(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed++
+				continue
+			}
+			packs = append(packs, pack)
+		}
+	}
+
+	reports, errs := game.CheckPacks(packs)
+	if !*quiet {
+		var files []string
+		for _, p := range packs[reportFrom:] {
+			files = append(files, p.File)
+		}
+		for _, r := range reports {
+			if slices.Contains(files, r.File) {
+				fmt.Println(r)
+			}
+		}
+	}
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, e)
+	}
+	if n := failed + len(errs); n > 0 {
+		if n == 1 {
+			return errors.New("1 problem found")
+		}
+		return fmt.Errorf("%d problems found", n)
+	}
+	return nil
+}
+
+// runStats prints a profile's command stats, or exports them as JSON.
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("vimgame stats", flag.ExitOnError)
+	profile := fs.String("profile", game.DefaultProfileName, "report on the named `profile`")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	profiles, err := game.DefaultProfileStore()
+	if err != nil {
+		return err
+	}
+	p, err := profiles.Load(*profile)
+	if err != nil {
+		return err
+	}
+	rep := p.Stats.Report(p.Name, game.TaughtCommands(game.AllLessons(), p))
+	if !*asJSON {
+		fmt.Print(rep)
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+// runServe runs the team leaderboard server.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("vimgame serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:7777", "listen on `address`")
+	data := fs.String("data", filepath.Join(store.DataDir(), "server"), "keep leaderboards and replays in `dir`")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`; the team's packs")
+	fs.Parse(args)
+
+	pack, err := game.LoadPackDir(*packs)
+	if err != nil {
+		return err
+	}
+	srv, err := server.New(*data, pack)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Serving team leaderboards on http://%s\n", *addr)
+	return http.ListenAndServe(*addr, srv)
+}
+
+// runSSHD serves the game to the team over SSH.
+func runSSHD(args []string) error {
+	fs := flag.NewFlagSet("vimgame sshd", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:2222", "listen on `address`; other machines need -authorized-keys")
+	data := fs.String("data", filepath.Join(store.DataDir(), "sshd"), "keep the host key, profiles and leaderboards in `dir`")
+	authorized := fs.String("authorized-keys", "", "only accept the public keys in `file` (authorized_keys format); required beyond localhost")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`")
+	team := fs.String("server", os.Getenv("VIMGAME_SERVER"), "submit runs to the team leaderboard server at `url`")
+	fs.Parse(args)
+
+	cfg := sshd.Config{DataDir: *data}
+	if *authorized != "" {
+		keys, err := sshd.LoadAuthorizedKeys(*authorized)
+		if err != nil {
+			return err
+		}
+		cfg.AuthorizedKeys = keys
+	}
+	pack, err := game.LoadPackDir(*packs)
+	if err != nil {
+		return err
+	}
+	cfg.Pack = pack
+	if *team != "" {
+		cfg.Team = server.NewClient(*team)
+	}
+	srv, err := sshd.New(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Serving vimgame over SSH on %s\n", *addr)
+	return srv.ListenAndServe(*addr)
+}
+
+// runHost hosts a head-to-head race and plays in it.
+func runHost(args []string) error {
+	fs := flag.NewFlagSet("vimgame host", flag.ExitOnError)
+	addr := fs.String("addr", ":7778", "listen for racers on `address`")
+	level := fs.Int("level", 1, "start with challenge level `n` selected")
+	seed := fs.Int64("seed", 0, "race on a fixed RNG `seed` instead of a new one each race")
+	profile := fs.String("profile", game.DefaultProfileName, "race as the named `profile`")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`")
+	theme := themeFlag(fs)
+	fs.Parse(args)
+
+	m, err := newRaceModel(*profile, *packs, *seed, *theme)
+	if err != nil {
+		return err
+	}
+	h, err := race.Listen(*addr, m.Profile.Name, m.RaceRules())
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+	m.JoinRace(h, h, h.Players())
+	m.Race.Level = min(max(*level-1, 0), len(m.LevelCatalog)-1)
+
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// runJoin joins a race hosted with runHost.
+func runJoin(args []string) error {
+	fs := flag.NewFlagSet("vimgame join", flag.ExitOnError)
+	profile := fs.String("profile", game.DefaultProfileName, "race as the named `profile`")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`; racers need the same packs")
+	theme := themeFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vimgame join [flags] <host:port>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	m, err := newRaceModel(*profile, *packs, 0, *theme)
+	if err != nil {
+		return err
+	}
+	c, err := race.Join(fs.Arg(0), m.Profile.Name, m.RaceRules())
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	m.JoinRace(c, nil, c.Players())
+
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// runSpectate watches a race live, or plays back a recorded one.
+func runSpectate(args []string) error {
+	fs := flag.NewFlagSet("vimgame spectate", flag.ExitOnError)
+	record := fs.String("record", "", "write a recording of the race to `file` on exit")
+	replay := fs.String("replay", "", "play back the race recording in `file` instead of connecting")
+	speed := fs.Float64("speed", 1, "playback speed multiplier")
+	packs := fs.String("packs", filepath.Join(store.DataDir(), "packs"), "load extra lesson/level packs from `dir`; the racers' packs")
+	theme := themeFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: vimgame spectate [flags] <host:port>")
+		fmt.Fprintln(fs.Output(), "       vimgame spectate -replay <recording>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if (*replay == "") != (fs.NArg() == 1) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := useTheme(*theme); err != nil {
+		return err
+	}
+	pack, err := game.LoadPackDir(*packs)
+	if err != nil {
+		return err
+	}
+
+	var m game.SpectatorModel
+	if *replay != "" {
+		rec, err := game.LoadRecording(*replay)
+		if err != nil {
+			return err
+		}
+		m = game.NewRecordingSpectator(rec, pack, *speed)
+	} else {
+		c, err := race.Watch(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		m = game.NewRaceSpectator(c, pack)
+	}
+
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return err
+	}
+	if *record != "" {
+		return game.SaveRecording(*record, final.(game.SpectatorModel).Recording())
+	}
+	return nil
+}
+
+// newRaceModel returns a model for racing as the named profile.
+func newRaceModel(profile, packs string, seed int64, theme string) (game.Model, error) {
+	m := game.NewModel()
+	if err := attachConfig(&m, theme); err != nil {
+		return m, err
+	}
+	if err := useTheme(theme); err != nil {
+		return m, err
+	}
+	m.FixedSeed = seed
+	if err := attachProfile(&m, profile); err != nil {
+		return m, err
+	}
+	pack, err := game.LoadPackDir(packs)
+	if err != nil {
+		return m, err
+	}
+	m.AddPack(pack)
+	return m, nil
+}
+
+// themeFlag defines the -theme flag of the interactive commands.
+func themeFlag(fs *flag.FlagSet) *string {
+	return fs.String("theme", os.Getenv("VIMGAME_THEME"), "color `theme`: dark, light, high-contrast, deuteranopia, mono or a JSON theme file")
+}
+
+// useTheme switches to the named theme; the default one (mono under
+// NO_COLOR) is kept when name is empty.
+func useTheme(name string) error {
+	if name == "" {
+		return nil
+	}
+	t, err := ui.LoadTheme(name)
+	if err != nil {
+		return err
+	}
+	ui.SetTheme(t)
+	return nil
+}
+
+// attachConfig applies the player's settings, except for the theme when
+// one was given on the command line, and lets the game ring the bell.
+func attachConfig(m *game.Model, theme string) error {
+	cfg, err := game.DefaultConfig()
+	if err != nil {
+		return err
+	}
+	if err := m.UseConfig(cfg, theme != ""); err != nil {
+		return err
+	}
+	m.Bell = os.Stdout
+	return nil
+}
+
+// attachProfile opens the profile store so progress is saved. With a
+// profile name that profile is used (and created if new); otherwise the game
+// starts at the profile menu.
+func attachProfile(m *game.Model, name string) error {
+	profiles, err := game.DefaultProfileStore()
+	if err != nil {
+		return err
+	}
+	m.Profiles = profiles
+	if name == "" {
+		m.OpenProfileMenu()
+		return nil
+	}
+	return m.UseProfile(name)
+}
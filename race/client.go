@@ -0,0 +1,134 @@
+package race
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// dialTimeout bounds connecting to a host.
+const dialTimeout = 5 * time.Second
+
+// Client is a joined player's or spectator's session.
+type Client struct {
+	c       *conn
+	nc      net.Conn
+	out     chan Message  // waiting to be written
+	done    chan struct{} // closed by Close
+	once    sync.Once
+	id      int
+	players []Player
+	welcome Message
+	events  chan Message
+}
+
+// Join connects to the race hosted at addr. rules are passed on to the
+// other players and spectators as the rules the player plays by.
+func Join(addr, name string, rules json.RawMessage) (*Client, error) {
+	return dial(addr, Message{Type: TypeHello, Name: name, Version: ProtocolVersion, Rules: rules})
+}
+
+// Watch connects to the race hosted at addr as a spectator. Its Welcome
+// holds the race so far.
+func Watch(addr string) (*Client, error) {
+	return dial(addr, Message{Type: TypeHello, Version: ProtocolVersion, Spectator: true})
+}
+
+func dial(addr string, hello Message) (*Client, error) {
+	nc, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := newConn(nc)
+	if err := c.write(hello); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	nc.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	m, err := c.read()
+	nc.SetReadDeadline(time.Time{})
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	switch m.Type {
+	case TypeWelcome:
+	case TypeError:
+		nc.Close()
+		return nil, fmt.Errorf("host refused: %s", m.Error)
+	default:
+		nc.Close()
+		return nil, errors.New("host did not welcome us")
+	}
+
+	cl := &Client{
+		c: c, nc: nc, out: make(chan Message, peerBuffer), done: make(chan struct{}),
+		id: m.ID, players: m.Players, welcome: m, events: make(chan Message, eventBuffer),
+	}
+	go cl.read()
+	go cl.writeLoop()
+	return cl, nil
+}
+
+func (cl *Client) read() {
+	defer close(cl.events)
+	for {
+		m, err := cl.c.read()
+		if err != nil {
+			return
+		}
+		cl.events <- m
+	}
+}
+
+func (cl *Client) ID() int                { return cl.id }
+func (cl *Client) Events() <-chan Message { return cl.events }
+
+// Players returns who was in the lobby when the client joined.
+func (cl *Client) Players() []Player { return cl.players }
+
+// Welcome returns the host's welcome message.
+func (cl *Client) Welcome() Message { return cl.welcome }
+
+// writeLoop writes the queued messages until the client is closed. A host
+// that takes writeTimeout to accept one is hung up on.
+func (cl *Client) writeLoop() {
+	for {
+		select {
+		case m := <-cl.out:
+			cl.nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := cl.c.write(m); err != nil {
+				cl.nc.Close()
+				return
+			}
+		case <-cl.done:
+			return
+		}
+	}
+}
+
+// Send queues the player's progress for the host without waiting for it
+// to be written, hanging up if the host has stopped reading.
+func (cl *Client) Send(p Progress) error {
+	select {
+	case <-cl.done:
+		return net.ErrClosed
+	default:
+	}
+	select {
+	case cl.out <- Message{Type: TypeProgress, Progress: &p}:
+		return nil
+	default:
+		cl.nc.Close()
+		return errors.New("the host stopped reading")
+	}
+}
+
+// Close leaves the race.
+func (cl *Client) Close() error {
+	cl.once.Do(func() { close(cl.done) })
+	return cl.nc.Close()
+}
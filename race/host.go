@@ -0,0 +1,327 @@
+package race
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// handshakeTimeout bounds how long a joiner may take to say hello.
+const handshakeTimeout = 5 * time.Second
+
+// maxName caps the length of a player name.
+const maxName = 32
+
+// maxRules caps the size of a player's rules.
+const maxRules = 1024
+
+// A peer that has peerBuffer messages waiting, or that takes writeTimeout
+// to accept one, has stopped reading and is hung up on, so that one stuck
+// player cannot hold up the race. Clients treat a stuck host the same way.
+const (
+	peerBuffer   = 256
+	writeTimeout = 5 * time.Second
+)
+
+// Host runs the hub of a race and plays in it as player 0.
+type Host struct {
+	ln     net.Listener
+	events chan Message
+
+	mu       sync.Mutex
+	name     string
+	rules    json.RawMessage
+	peers    map[int]*peer
+	nextID   int
+	started  bool
+	seed     int64
+	level    int
+	lvName   string // level name and content hash
+	lvHash   string
+	placed   map[int]int      // finishing place by player id
+	progress map[int]Progress // latest progress by player id
+	keys     map[int][]Key    // every key of the race by player id
+	closed   bool
+}
+
+type peer struct {
+	Player
+	c         *conn
+	nc        net.Conn
+	spectator bool
+	out       chan Message // waiting to be written; closed when the peer leaves
+}
+
+// send queues a message for the peer, hanging up if it has stopped
+// reading. The caller holds h.mu.
+func (p *peer) send(m Message) {
+	select {
+	case p.out <- m:
+	default:
+		p.nc.Close()
+	}
+}
+
+// writeLoop writes the peer's queued messages until it leaves.
+func (p *peer) writeLoop() {
+	for m := range p.out {
+		p.nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := p.c.write(m); err != nil {
+			p.nc.Close()
+			break
+		}
+	}
+	for range p.out {
+	}
+}
+
+// Listen starts hosting a race on addr, playing by rules (see Join).
+func Listen(addr, name string, rules json.RawMessage) (*Host, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	h := &Host{
+		ln:     ln,
+		events: make(chan Message, eventBuffer),
+		name:   name,
+		rules:  rules,
+		peers:  make(map[int]*peer),
+		nextID: HostID + 1,
+	}
+	h.reset()
+	go h.accept()
+	return h, nil
+}
+
+// Addr returns the address the host listens on.
+func (h *Host) Addr() net.Addr { return h.ln.Addr() }
+
+func (h *Host) ID() int                { return HostID }
+func (h *Host) Events() <-chan Message { return h.events }
+
+// Players returns everyone in the race, host first.
+func (h *Host) Players() []Player {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.players()
+}
+
+func (h *Host) players() []Player {
+	ps := []Player{{ID: HostID, Name: h.name, Rules: h.rules}}
+	for _, p := range h.peers {
+		if !p.spectator {
+			ps = append(ps, p.Player)
+		}
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].ID < ps[j].ID })
+	return ps
+}
+
+// reset forgets the last race's results.
+func (h *Host) reset() {
+	h.placed = make(map[int]int)
+	h.progress = make(map[int]Progress)
+	h.keys = make(map[int][]Key)
+}
+
+// Start begins a race with a seed on a level, given by its catalog index,
+// name and content hash. Players joining afterwards are refused until the
+// host starts over with Reset; spectators may join at any time.
+func (h *Host) Start(seed int64, level int, name, hash string) error {
+	h.mu.Lock()
+	if h.started {
+		h.mu.Unlock()
+		return errors.New("race already started")
+	}
+	h.started = true
+	h.seed, h.level, h.lvName, h.lvHash = seed, level, name, hash
+	h.reset()
+	m := Message{Type: TypeStart, Seed: seed, Level: level, LevelName: name, LevelHash: hash, Players: h.players()}
+	h.mu.Unlock()
+	h.broadcast(m, -1)
+	return nil
+}
+
+// Reset returns to the lobby so new players can join the next race.
+func (h *Host) Reset() {
+	h.mu.Lock()
+	h.started = false
+	h.mu.Unlock()
+}
+
+// Send reports the host's own progress.
+func (h *Host) Send(p Progress) error {
+	h.relay(HostID, p)
+	return nil
+}
+
+// Close stops hosting and disconnects everyone.
+func (h *Host) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	for _, p := range h.peers {
+		p.c.rw.Close()
+	}
+	close(h.events)
+	h.mu.Unlock()
+	return h.ln.Close()
+}
+
+func (h *Host) accept() {
+	for {
+		nc, err := h.ln.Accept()
+		if err != nil {
+			return
+		}
+		go h.serve(newConn(nc), nc)
+	}
+}
+
+// serve runs one joiner's connection: the handshake, then relaying its
+// progress until it disconnects.
+func (h *Host) serve(c *conn, nc net.Conn) {
+	defer nc.Close()
+	nc.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	hello, err := c.read()
+	if err != nil || hello.Type != TypeHello {
+		return
+	}
+	nc.SetReadDeadline(time.Time{})
+	name := strings.TrimSpace(hello.Name)
+	if err := h.admit(hello, name); err != nil {
+		nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		c.write(Message{Type: TypeError, Error: err.Error()})
+		return
+	}
+
+	// Welcome under the lock, so no broadcast reaches the peer first
+	h.mu.Lock()
+	p := &peer{Player: Player{ID: h.nextID, Name: name, Rules: hello.Rules}, c: c, nc: nc, spectator: hello.Spectator, out: make(chan Message, peerBuffer)}
+	go p.writeLoop()
+	h.nextID++
+	h.peers[p.ID] = p
+	players := h.players()
+	welcome := Message{Type: TypeWelcome, ID: p.ID, Players: players}
+	if p.spectator {
+		welcome.Racing, welcome.Seed, welcome.Level = h.started, h.seed, h.level
+		welcome.LevelName, welcome.LevelHash = h.lvName, h.lvHash
+		welcome.Snapshot = h.snapshot()
+	}
+	p.send(welcome)
+	h.mu.Unlock()
+	if !p.spectator {
+		h.broadcast(Message{Type: TypeLobby, Players: players}, p.ID)
+	}
+
+	for {
+		m, err := c.read()
+		if err != nil {
+			break
+		}
+		if m.Type == TypeProgress && m.Progress != nil && !p.spectator {
+			h.relay(p.ID, *m.Progress)
+		}
+	}
+
+	h.mu.Lock()
+	delete(h.peers, p.ID)
+	close(p.out)
+	players = h.players()
+	started := h.started
+	h.mu.Unlock()
+	if p.spectator {
+		return
+	}
+	h.broadcast(Message{Type: TypeBye, ID: p.ID}, p.ID)
+	if !started {
+		h.broadcast(Message{Type: TypeLobby, Players: players}, p.ID)
+	}
+}
+
+// admit reports why a joiner who said hello as name cannot enter the race.
+func (h *Host) admit(hello Message, name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case hello.Version != ProtocolVersion:
+		return fmt.Errorf("protocol version %d, host speaks %d", hello.Version, ProtocolVersion)
+	case hello.Spectator:
+	case name == "" || len(name) > maxName:
+		return fmt.Errorf("name must be 1 to %d characters", maxName)
+	case len(hello.Rules) > maxRules:
+		return fmt.Errorf("rules must be at most %d bytes", maxRules)
+	case h.started:
+		return errors.New("race already started")
+	}
+	return nil
+}
+
+// snapshot returns every player's race so far.
+func (h *Host) snapshot() []Snapshot {
+	var snap []Snapshot
+	for _, p := range h.players() {
+		pr, ok := h.progress[p.ID]
+		if !ok {
+			continue
+		}
+		pr.Keys = h.keys[p.ID]
+		snap = append(snap, Snapshot{ID: p.ID, Progress: pr, Place: h.placed[p.ID]})
+	}
+	return snap
+}
+
+// relay passes a player's progress to everyone else and announces a
+// finish in place order.
+func (h *Host) relay(id int, p Progress) {
+	h.mu.Lock()
+	h.keys[id] = append(h.keys[id], p.Keys...)
+	last := p
+	last.Keys = nil
+	h.progress[id] = last
+	h.mu.Unlock()
+
+	h.broadcast(Message{Type: TypeProgress, ID: id, Progress: &p}, id)
+	if !p.Finished {
+		return
+	}
+	h.mu.Lock()
+	if h.placed[id] > 0 {
+		h.mu.Unlock()
+		return
+	}
+	place := len(h.placed) + 1
+	h.placed[id] = place
+	h.mu.Unlock()
+	h.broadcast(Message{Type: TypeResult, ID: id, Place: place, Progress: &p}, -1)
+}
+
+// broadcast sends m to every player but skip, the host included. It does
+// not wait for the peers to read it.
+func (h *Host) broadcast(m Message, skip int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	if skip != HostID {
+		select {
+		case h.events <- m:
+		default: // the game is not keeping up; drop rather than stall the hub
+		}
+	}
+	for id, p := range h.peers {
+		if id != skip {
+			p.send(m)
+		}
+	}
+}
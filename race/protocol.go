@@ -0,0 +1,146 @@
+// Package race connects the players of a head-to-head race: one player
+// hosts (`vimgame host`) and the others join (`vimgame join addr`).
+//
+// The protocol is newline-delimited JSON over TCP, one Message per line.
+// Every message has a "type"; the other fields depend on it:
+//
+//	client → host   hello     {name, version, spectator,  first message after connecting
+//	                           rules}
+//	host → client   welcome   {id, players, racing, seed, the joiner's id and who is in the lobby;
+//	                           level, level_name,         spectators also get the race so far
+//	                           level_hash, snapshot}
+//	host → client   error     {error}                     the join was refused; the host hangs up
+//	host → all      lobby     {players}                   a player joined or left before the start
+//	host → all      start     {seed, level, level_name,   the race begins on a level with a seed; racers
+//	                           level_hash, players}       refuse a level whose content differs from theirs
+//	client → host   progress  {progress}                  the sender's cursor, progress and new keys
+//	host → all      progress  {id, progress}              relayed to everyone but the sender
+//	host → all      result    {id, place, progress}       a player finished, in place order
+//	host → all      bye       {id}                        a player disconnected
+//
+// The host is player 0 and plays through the same hub as everyone else.
+// Everyone who receives the same seed and level plays the same buffer and
+// target sequence, so only cursor positions, progress and the keys pressed
+// cross the wire. A level is named, with a hash of its content, since
+// racers may have different packs installed. Spectators receive every
+// message but never play; they may join mid-race, rebuilding each player's
+// run from the snapshot's keys and the rules the player gave in their
+// hello.
+package race
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// ProtocolVersion is bumped when messages change incompatibly.
+const ProtocolVersion = 4
+
+// Message types.
+const (
+	TypeHello    = "hello"
+	TypeWelcome  = "welcome"
+	TypeError    = "error"
+	TypeLobby    = "lobby"
+	TypeStart    = "start"
+	TypeProgress = "progress"
+	TypeResult   = "result"
+	TypeBye      = "bye"
+)
+
+// HostID is the host's player id.
+const HostID = 0
+
+// Player is a racer in the lobby.
+type Player struct {
+	ID    int             `json:"id"`
+	Name  string          `json:"name"`
+	Rules json.RawMessage `json:"rules,omitempty"` // the game's rules the player plays by, passed on as is
+}
+
+// Key is a key a racer pressed, at a time in milliseconds since the start.
+type Key struct {
+	Key string `json:"key"`
+	At  int64  `json:"at"`
+}
+
+// Progress is a racer's position in the race.
+type Progress struct {
+	Exercise int   `json:"exercise"` // index of the exercise being played
+	Row      int   `json:"row"`      // cursor position in that exercise
+	Col      int   `json:"col"`
+	Done     int   `json:"done"`  // targets and edits finished
+	Total    int   `json:"total"` // targets and edits in the level
+	Finished bool  `json:"finished,omitempty"`
+	TimeMs   int64 `json:"time_ms"`        // race time at the last key
+	Keys     []Key `json:"keys,omitempty"` // keys pressed since the last report
+}
+
+// Snapshot is a player's race so far, sent to spectators who join mid-race.
+type Snapshot struct {
+	ID       int      `json:"id"`
+	Progress Progress `json:"progress"` // Keys holds every key since the start
+	Place    int      `json:"place,omitempty"`
+}
+
+// Message is one line of the protocol.
+type Message struct {
+	Type      string          `json:"type"`
+	ID        int             `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Version   int             `json:"version,omitempty"`
+	Spectator bool            `json:"spectator,omitempty"`
+	Rules     json.RawMessage `json:"rules,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Players   []Player        `json:"players,omitempty"`
+	Seed      int64           `json:"seed,omitempty"`
+	Level     int             `json:"level,omitempty"`
+	LevelName string          `json:"level_name,omitempty"`
+	LevelHash string          `json:"level_hash,omitempty"`
+	Place     int             `json:"place,omitempty"` // 1-based
+	Progress  *Progress       `json:"progress,omitempty"`
+	Racing    bool            `json:"racing,omitempty"`
+	Snapshot  []Snapshot      `json:"snapshot,omitempty"`
+}
+
+// Session is one player's end of a race, hosting or joined.
+type Session interface {
+	// ID returns the player's id.
+	ID() int
+	// Events delivers messages from the other players. It is closed when
+	// the session ends.
+	Events() <-chan Message
+	// Send reports the player's progress.
+	Send(p Progress) error
+	Close() error
+}
+
+// eventBuffer is how many messages a session queues for the game.
+const eventBuffer = 256
+
+// conn reads and writes messages on a connection. Writes are serialized so
+// that several goroutines can share one.
+type conn struct {
+	rw  io.ReadWriteCloser
+	dec *json.Decoder
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newConn(rw io.ReadWriteCloser) *conn {
+	return &conn{rw: rw, dec: json.NewDecoder(bufio.NewReader(rw)), enc: json.NewEncoder(rw)}
+}
+
+func (c *conn) write(m Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(m)
+}
+
+func (c *conn) read() (Message, error) {
+	var m Message
+	err := c.dec.Decode(&m)
+	return m, err
+}
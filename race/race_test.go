@@ -0,0 +1,170 @@
+package race
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// next returns the next message from a session, failing after a second.
+func next(t *testing.T, events <-chan Message) Message {
+	t.Helper()
+	select {
+	case m, ok := <-events:
+		if !ok {
+			t.Fatal("session closed")
+		}
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+	}
+	return Message{}
+}
+
+// expect returns the next message from a session, checking its type and
+// sender.
+func expect(t *testing.T, events <-chan Message, typ string, id int) Message {
+	t.Helper()
+	m := next(t, events)
+	if m.Type != typ || m.ID != id {
+		t.Fatalf("got %s from %d, want %s from %d", m.Type, m.ID, typ, id)
+	}
+	return m
+}
+
+func TestRace(t *testing.T) {
+	h, err := Listen("127.0.0.1:0", "host", json.RawMessage(`{"shiftwidth":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	c, err := Join(h.Addr().String(), "ada", json.RawMessage(`{"shiftwidth":8}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.ID() == HostID || len(c.Players()) != 2 || c.Players()[1].Name != "ada" {
+		t.Fatalf("welcomed as %d with players %v", c.ID(), c.Players())
+	}
+	if ps := c.Players(); string(ps[0].Rules) != `{"shiftwidth":2}` || string(ps[1].Rules) != `{"shiftwidth":8}` {
+		t.Errorf("players' rules %s and %s", ps[0].Rules, ps[1].Rules)
+	}
+	if m := expect(t, h.Events(), TypeLobby, 0); len(m.Players) != 2 {
+		t.Errorf("host's lobby has %v", m.Players)
+	}
+
+	if err := h.Start(42, 3, "Quick Motions", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	m := expect(t, c.Events(), TypeStart, 0)
+	if m.Seed != 42 || m.Level != 3 || m.LevelName != "Quick Motions" || m.LevelHash != "abc123" {
+		t.Errorf("start = seed %d, level %d %q %q", m.Seed, m.Level, m.LevelName, m.LevelHash)
+	}
+	expect(t, h.Events(), TypeStart, 0)
+	if _, err := Join(h.Addr().String(), "late", nil); err == nil {
+		t.Error("joined a race that had started")
+	}
+
+	// Progress is relayed to everyone but its sender
+	c.Send(Progress{Done: 1, Total: 3, Keys: []Key{{Key: "j", At: 150}}})
+	m = expect(t, h.Events(), TypeProgress, c.ID())
+	if m.Progress.Done != 1 || len(m.Progress.Keys) != 1 || m.Progress.Keys[0].Key != "j" {
+		t.Errorf("relayed progress %+v", m.Progress)
+	}
+
+	// Finishers are placed in order
+	h.Send(Progress{Done: 3, Total: 3, Finished: true})
+	expect(t, c.Events(), TypeProgress, HostID)
+	if m := expect(t, c.Events(), TypeResult, HostID); m.Place != 1 {
+		t.Errorf("host placed %d, want 1", m.Place)
+	}
+	expect(t, h.Events(), TypeResult, HostID)
+	c.Send(Progress{Done: 3, Total: 3, Finished: true})
+	expect(t, h.Events(), TypeProgress, c.ID())
+	if m := expect(t, h.Events(), TypeResult, c.ID()); m.Place != 2 {
+		t.Errorf("joiner placed %d, want 2", m.Place)
+	}
+	expect(t, c.Events(), TypeResult, c.ID())
+
+	c.Close()
+	expect(t, h.Events(), TypeBye, c.ID())
+}
+
+func TestRaceDropsStalledPeer(t *testing.T) {
+	h, err := Listen("127.0.0.1:0", "host", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// A player that says hello and then never reads
+	nc, err := net.Dial("tcp", h.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	json.NewEncoder(nc).Encode(Message{Type: TypeHello, Name: "stuck", Version: ProtocolVersion})
+	m := expect(t, h.Events(), TypeLobby, 0)
+	id := m.Players[1].ID
+
+	keys := []Key{{Key: strings.Repeat("x", 1<<16)}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 4 * peerBuffer {
+			h.Send(Progress{Keys: keys})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(writeTimeout / 2):
+		t.Fatal("the host blocked on a player that stopped reading")
+	}
+	expect(t, h.Events(), TypeBye, id)
+}
+
+func TestClientDropsStalledHost(t *testing.T) {
+	// A host that welcomes the player and then never reads
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		nc, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer nc.Close()
+		json.NewEncoder(nc).Encode(Message{Type: TypeWelcome, ID: 1})
+		time.Sleep(writeTimeout)
+	}()
+	c, err := Join(ln.Addr().String(), "ada", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	keys := []Key{{Key: strings.Repeat("x", 1<<16)}}
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		for range 4 * peerBuffer {
+			if err = c.Send(Progress{Keys: keys}); err != nil {
+				break
+			}
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("sent everything to a host that stopped reading")
+		}
+	case <-time.After(writeTimeout / 2):
+		t.Fatal("the player blocked on a host that stopped reading")
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"vimgame/game"
+)
+
+// clientTimeout bounds every request to the server.
+const clientTimeout = 10 * time.Second
+
+// Client talks to a team leaderboard server. It implements game.TeamBoard.
+type Client struct {
+	URL  string // base URL, e.g. http://localhost:7777
+	HTTP *http.Client
+}
+
+// NewClient returns a client for the server at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		URL:  strings.TrimSuffix(baseURL, "/"),
+		HTTP: &http.Client{Timeout: clientTimeout},
+	}
+}
+
+// Submit sends a run's replay to be verified and ranked.
+func (c *Client) Submit(name string, r game.Replay) ([]game.TeamRank, error) {
+	body, err := json.Marshal(Submission{Name: name, Replay: r})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTP.Post(c.URL+"/api/submit", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	var sr SubmitResponse
+	if err := decode(resp, &sr); err != nil {
+		return nil, err
+	}
+	return sr.Ranks, nil
+}
+
+// Boards fetches every board from the server.
+func (c *Client) Boards() (map[string][]game.LeaderboardEntry, error) {
+	resp, err := c.HTTP.Get(c.URL + "/api/boards")
+	if err != nil {
+		return nil, err
+	}
+	var br BoardsResponse
+	if err := decode(resp, &br); err != nil {
+		return nil, err
+	}
+	return br.Boards, nil
+}
+
+// ReplayURL returns the URL of a ranked entry's replay.
+func (c *Client) ReplayURL(board string, rank int) string {
+	q := url.Values{"board": {board}, "rank": {strconv.Itoa(rank)}}
+	return c.URL + "/api/replay?" + q.Encode()
+}
+
+// Replay downloads the replay of an entry on a team board.
+func (c *Client) Replay(board string, rank int) (game.Replay, error) {
+	return FetchReplay(c.ReplayURL(board, rank))
+}
+
+// FetchReplay downloads a replay from a URL given by ReplayURL.
+func FetchReplay(replayURL string) (game.Replay, error) {
+	var r game.Replay
+	resp, err := (&http.Client{Timeout: clientTimeout}).Get(replayURL)
+	if err != nil {
+		return r, err
+	}
+	if err := decode(resp, &r); err != nil {
+		return r, err
+	}
+	if r.Version != game.ReplayVersion {
+		return r, fmt.Errorf("%s: unsupported replay version %d", replayURL, r.Version)
+	}
+	return r, nil
+}
+
+// decode reads a JSON response into v, turning error responses into errors.
+func decode(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("server: %s", e.Error)
+		}
+		return fmt.Errorf("server: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,236 @@
+// Package server is the team leaderboard server behind `vimgame serve`, and
+// the client the game uses to talk to it.
+//
+// The API is JSON over HTTP:
+//
+//	POST /api/submit              {"name": "ada", "replay": {...}}
+//	                              → {"ranks": [{"board": "level:Quick Motions", "rank": 0, "score": 550}]}
+//	GET  /api/boards              → {"boards": {"level:Quick Motions": [entry, ...]}}
+//	GET  /api/replay?board=&rank= → the replay of a ranked entry
+//
+// Errors are reported as {"error": "..."} with a 4xx or 5xx status.
+// Submissions carry only a name and a replay: the server re-simulates the
+// replay through the game engine and ranks the results it reproduces, so a
+// score cannot be posted without the keys that earn it. Times are taken
+// from the replay's key timestamps, which must run forward at a pace a
+// person can type; runs whose result rests on the clock, such as edit
+// challenges, are not ranked, nor is anything played after a replay
+// returns to the menu.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"vimgame/game"
+)
+
+// maxSubmission caps the size of a submission body.
+const maxSubmission = 4 << 20
+
+// maxName caps the length of a submitted player name.
+const maxName = 32
+
+// maxReplayKeys caps the keys of a submitted replay, each of which is
+// simulated.
+const maxReplayKeys = 20000
+
+// No keyWindow keys in a row may come faster than minKeyWindow after the
+// key before them: that is quicker than anyone types.
+const (
+	keyWindow    = 10
+	minKeyWindow = 150 * time.Millisecond
+)
+
+// Submission is the body of POST /api/submit.
+type Submission struct {
+	Name   string      `json:"name"`
+	Replay game.Replay `json:"replay"`
+}
+
+// SubmitResponse is the answer to a submission.
+type SubmitResponse struct {
+	Ranks []game.TeamRank `json:"ranks"`
+}
+
+// BoardsResponse is the answer to GET /api/boards.
+type BoardsResponse struct {
+	Boards map[string][]game.LeaderboardEntry `json:"boards"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server ranks verified submissions on leaderboards kept in a data
+// directory.
+type Server struct {
+	mu     sync.Mutex
+	boards *game.Leaderboards
+	pack   game.Pack
+	mux    *http.ServeMux
+}
+
+// New returns a server keeping its leaderboards under dataDir. Replays are
+// verified on the built-in content plus pack, which should be the packs the
+// team plays.
+func New(dataDir string, pack game.Pack) (*Server, error) {
+	boards, err := game.OpenLeaderboards(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{boards: boards, pack: pack, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /api/submit", s.handleSubmit)
+	s.mux.HandleFunc("GET /api/boards", s.handleBoards)
+	s.mux.HandleFunc("GET /api/replay", s.handleReplay)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// checkName reports why a submitted player name is not acceptable.
+func checkName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("name is empty")
+	case len(name) > maxName:
+		return fmt.Errorf("name is longer than %d characters", maxName)
+	case strings.IndexFunc(name, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0:
+		return errors.New("name has unprintable characters")
+	}
+	return nil
+}
+
+// checkTiming reports why a replay's key times cannot be a person typing.
+func checkTiming(keys []game.ReplayKey) error {
+	for i, k := range keys {
+		if k.At < 0 || (i > 0 && k.At < keys[i-1].At) {
+			return fmt.Errorf("key %d goes back in time", i+1)
+		}
+		if i >= keyWindow && time.Duration(k.At-keys[i-keyWindow].At)*time.Millisecond < minKeyWindow {
+			return fmt.Errorf("keys %d to %d come too fast", i-keyWindow+2, i+1)
+		}
+	}
+	return nil
+}
+
+// Submit verifies a submission and ranks the results its replay reproduces.
+func (s *Server) Submit(sub Submission) (SubmitResponse, error) {
+	name := strings.TrimSpace(sub.Name)
+	if err := checkName(name); err != nil {
+		return SubmitResponse{}, err
+	}
+	if sub.Replay.Practice.File != "" {
+		return SubmitResponse{}, errors.New("practice runs on local files cannot be verified")
+	}
+	if sub.Replay.ClientTimed() {
+		return SubmitResponse{}, errors.New("timed runs are only ranked locally")
+	}
+	if len(sub.Replay.Keys) > maxReplayKeys {
+		return SubmitResponse{}, fmt.Errorf("replay has more than %d keys", maxReplayKeys)
+	}
+	if err := checkTiming(sub.Replay.Keys); err != nil {
+		return SubmitResponse{}, fmt.Errorf("replay: %w", err)
+	}
+	results, err := game.VerifyReplay(sub.Replay, s.pack)
+	if err != nil {
+		return SubmitResponse{}, fmt.Errorf("replay: %w", err)
+	}
+	results = slices.DeleteFunc(results, func(res game.LevelResult) bool { return res.Replay.ClientTimed() })
+	if len(results) == 0 {
+		return SubmitResponse{}, errors.New("replay does not finish a ranked level")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := SubmitResponse{Ranks: []game.TeamRank{}}
+	now := time.Now()
+	for _, res := range results {
+		e := res.Entry
+		e.Name = name
+		e.Date = now
+		for _, key := range res.Boards {
+			rank, ok := s.boards.Rank(key, e)
+			if !ok {
+				// Resubmitted: a later level's replay replays the earlier ones
+				resp.Ranks = append(resp.Ranks, game.TeamRank{Board: key, Rank: rank, Score: e.Score})
+				continue
+			}
+			if rank, err = s.boards.Add(key, e, res.Replay); err != nil {
+				return SubmitResponse{}, err
+			}
+			resp.Ranks = append(resp.Ranks, game.TeamRank{Board: key, Rank: rank, Score: e.Score})
+		}
+	}
+	return resp, s.boards.Save()
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var sub Submission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmission)).Decode(&sub); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("bad submission: %w", err))
+		return
+	}
+	resp, err := s.Submit(sub)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleBoards(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	resp := BoardsResponse{Boards: make(map[string][]game.LeaderboardEntry)}
+	for _, key := range s.boards.Keys() {
+		resp.Boards[key] = s.boards.Board(key)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("board")
+	rank, err := strconv.Atoi(r.URL.Query().Get("rank"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("rank must be a number"))
+		return
+	}
+	s.mu.Lock()
+	board := s.boards.Board(key)
+	path := ""
+	if rank >= 0 && rank < len(board) {
+		path = s.boards.ReplayPath(board[rank])
+	}
+	s.mu.Unlock()
+	if path == "" {
+		writeError(w, http.StatusNotFound, errors.New("no such entry"))
+		return
+	}
+	replay, err := game.LoadReplay(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, replay)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{err.Error()})
+}
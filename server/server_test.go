@@ -0,0 +1,39 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"vimgame/game"
+)
+
+// keys returns a replay's keys, typed 200ms apart.
+func keys(ks ...string) []game.ReplayKey {
+	out := make([]game.ReplayKey, len(ks))
+	for i, k := range ks {
+		out[i] = game.ReplayKey{Key: k, At: int64(i+1) * 200}
+	}
+	return out
+}
+
+func TestSubmitRefusesRunsStartedFromTheMenu(t *testing.T) {
+	s, err := New(t.TempDir(), game.Pack{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leaving a challenge for the menu and starting a timed mode there
+	// must not rank the timed run
+	for _, mode := range []string{"4", "5", "3"} {
+		ks := []string{"esc", mode}
+		for range 200 {
+			ks = append(ks, "x")
+		}
+		r := game.Replay{Version: game.ReplayVersion, Mode: game.GameModeMotionChallenge, Seed: 5, Keys: keys(ks...)}
+		resp, err := s.Submit(Submission{Name: "ada", Replay: r})
+		if err == nil {
+			t.Errorf("menu key %s: ranked %+v", mode, resp.Ranks)
+		} else if !strings.Contains(err.Error(), "ranked level") {
+			t.Errorf("menu key %s: %v", mode, err)
+		}
+	}
+}
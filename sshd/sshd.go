@@ -0,0 +1,424 @@
+// Package sshd serves the game over SSH, so a team can share one instance
+// without installing anything:
+//
+//	ssh -p 2222 alice@vimgame.example.com
+//
+// Every session gets its own Model and Bubble Tea program bound to the
+// session's PTY and window size, drawn in the colors the session's TERM,
+// COLORTERM and NO_COLOR ask for. Players are told apart by public key: each
+// key has its own profile store, and the SSH user name is the profile it
+// plays by default. Leaderboards are shared by everyone on the server. A
+// server open to other machines only lets in the keys it is given.
+//
+// Running the command "watch" instead of a shell spectates every session
+// live, read-only:
+//
+//	ssh -t -p 2222 vimgame.example.com watch
+package sshd
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"vimgame/game"
+	"vimgame/ui"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeyFile is the server's private key, relative to the data directory.
+const hostKeyFile = "ssh_host_ed25519_key"
+
+// keyExtension carries the player's key id from authentication to the
+// session.
+const keyExtension = "vimgame-key"
+
+// Config configures a Server.
+type Config struct {
+	DataDir        string          // host key, per-key profiles and the shared leaderboards
+	AuthorizedKeys []ssh.PublicKey // if set, only these keys may connect
+	Pack           game.Pack       // extra lessons and levels for every session
+	Team           game.TeamBoard  // team leaderboard server, if any
+}
+
+// Server accepts SSH connections and runs a game in each session.
+type Server struct {
+	cfg    Config
+	ssh    *ssh.ServerConfig
+	boards *game.Leaderboards
+	hub    *hub
+}
+
+// New returns a server, creating its host key on first use.
+func New(cfg Config) (*Server, error) {
+	signer, err := loadHostKey(filepath.Join(cfg.DataDir, hostKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	boards, err := game.OpenLeaderboards(cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: cfg, boards: boards, hub: newHub()}
+	s.ssh = &ssh.ServerConfig{PublicKeyCallback: s.authorize}
+	s.ssh.AddHostKey(signer)
+	return s, nil
+}
+
+// ListenAndServe serves SSH on addr. Without authorized keys anyone who
+// can connect may play, so addr must then be a loopback address.
+func (s *Server) ListenAndServe(addr string) error {
+	if len(s.cfg.AuthorizedKeys) == 0 && !loopback(addr) {
+		return fmt.Errorf("%s is reachable from other machines, so authorized keys are required", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until it is closed.
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleConn(nc)
+	}
+}
+
+// loopback reports whether a listen address only accepts connections from
+// this machine.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// KeyID returns the id a public key's profiles are stored under.
+func KeyID(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return hex.EncodeToString(sum[:16])
+}
+
+// authorize accepts any key, or only the authorized ones if configured.
+func (s *Server) authorize(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	if len(s.cfg.AuthorizedKeys) > 0 {
+		ok := false
+		for _, k := range s.cfg.AuthorizedKeys {
+			if string(k.Marshal()) == string(key.Marshal()) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, errors.New("key not authorized")
+		}
+	}
+	return &ssh.Permissions{Extensions: map[string]string{keyExtension: KeyID(key)}}, nil
+}
+
+func (s *Server) handleConn(nc net.Conn) {
+	conn, chans, reqs, err := ssh.NewServerConn(nc, s.ssh)
+	if err != nil {
+		nc.Close()
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+	for nch := range chans {
+		if nch.ChannelType() != "session" {
+			nch.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, creqs, err := nch.Accept()
+		if err != nil {
+			continue
+		}
+		go s.handleSession(conn, ch, creqs)
+	}
+}
+
+// ptyRequest is the payload of a "pty-req" request (RFC 4254 6.2).
+type ptyRequest struct {
+	Term          string
+	Cols, Rows    uint32
+	Width, Height uint32
+	Modes         string
+}
+
+// windowChange is the payload of a "window-change" request (RFC 4254 6.7).
+type windowChange struct {
+	Cols, Rows    uint32
+	Width, Height uint32
+}
+
+// envRequest is the payload of an "env" request (RFC 4254 6.4).
+type envRequest struct {
+	Name, Value string
+}
+
+// session is one SSH session running a game.
+type session struct {
+	mu   sync.Mutex
+	prog *tea.Program
+	size tea.WindowSizeMsg
+	env  environ // TERM from the PTY request and the variables the client sent
+}
+
+// environ is a session's environment, for termenv.
+type environ map[string]string
+
+func (e environ) Getenv(name string) string { return e[name] }
+
+func (e environ) Environ() []string {
+	var vars []string
+	for name, value := range e {
+		vars = append(vars, name+"="+value)
+	}
+	return vars
+}
+
+// look returns the theme and color profile the session's terminal asks
+// for: as many colors as TERM and COLORTERM promise, or the mono theme
+// under NO_COLOR. Mono still draws with reverse video and underlines where
+// the terminal has them.
+func (ss *session) look(w io.Writer) (ui.Theme, termenv.Profile) {
+	out := termenv.NewOutput(w, termenv.WithEnvironment(ss.env), termenv.WithTTY(true))
+	if out.EnvNoColor() {
+		return ui.MonoTheme, out.ColorProfile()
+	}
+	return ui.DarkTheme, out.EnvColorProfile()
+}
+
+// resize passes a new window size to the game once it runs.
+func (ss *session) resize(size tea.WindowSizeMsg) {
+	ss.mu.Lock()
+	ss.size = size
+	p := ss.prog
+	ss.mu.Unlock()
+	if p != nil {
+		go p.Send(size)
+	}
+}
+
+func (s *Server) handleSession(conn *ssh.ServerConn, ch ssh.Channel, reqs <-chan *ssh.Request) {
+	ss := &session{env: environ{}}
+	pty := false
+	for req := range reqs {
+		switch req.Type {
+		case "pty-req":
+			var pr ptyRequest
+			if err := ssh.Unmarshal(req.Payload, &pr); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			pty = true
+			ss.env["TERM"] = pr.Term
+			ss.resize(tea.WindowSizeMsg{Width: int(pr.Cols), Height: int(pr.Rows)})
+			req.Reply(true, nil)
+		case "env":
+			var er envRequest
+			if err := ssh.Unmarshal(req.Payload, &er); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			ss.env[er.Name] = er.Value
+			req.Reply(true, nil)
+		case "window-change":
+			var wc windowChange
+			if err := ssh.Unmarshal(req.Payload, &wc); err == nil {
+				ss.resize(tea.WindowSizeMsg{Width: int(wc.Cols), Height: int(wc.Rows)})
+			}
+		case "shell", "exec":
+			var cmd struct{ Command string }
+			if req.Type == "exec" {
+				ssh.Unmarshal(req.Payload, &cmd)
+			}
+			if cmd.Command != "" && cmd.Command != "watch" {
+				req.Reply(false, nil)
+				fmt.Fprintf(ch.Stderr(), "unknown command %q; the only command is watch\r\n", cmd.Command)
+				exit(ch, 1)
+				return
+			}
+			if !pty {
+				req.Reply(false, nil)
+				fmt.Fprint(ch.Stderr(), "vimgame needs a terminal; connect with ssh -t\r\n")
+				exit(ch, 1)
+				return
+			}
+			req.Reply(true, nil)
+			go s.play(conn, ch, ss, cmd.Command == "watch")
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// play runs a game, or a spectator, on the session until the player quits
+// or disconnects.
+func (s *Server) play(conn *ssh.ServerConn, ch ssh.Channel, ss *session, watch bool) {
+	theme, profile := ss.look(ch)
+	var model tea.Model
+	if watch {
+		model = game.NewSpectator("live sessions", s.cfg.Pack)
+	} else {
+		m, err := s.newModel(conn.Permissions.Extensions[keyExtension], conn.User(), &theme)
+		if err != nil {
+			fmt.Fprintf(ch.Stderr(), "Error: %v\r\n", err)
+			exit(ch, 1)
+			return
+		}
+		id := s.hub.join(conn.User())
+		defer s.hub.leave(id)
+		model = tap{Model: m, hub: s.hub, id: id}
+	}
+	model = painted{Model: model, theme: &theme, profile: profile}
+	p := tea.NewProgram(model,
+		tea.WithInput(ch),
+		tea.WithOutput(ch),
+		tea.WithAltScreen(),
+		tea.WithoutSignalHandler(),
+	)
+	ss.mu.Lock()
+	ss.prog = p
+	size := ss.size
+	ss.mu.Unlock()
+	go p.Send(size)
+	if watch {
+		defer s.hub.watch(p)()
+	}
+	go func() {
+		conn.Wait()
+		p.Kill()
+	}()
+
+	status := 0
+	if _, err := p.Run(); err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+		log.Printf("session %s@%s: %v", conn.User(), conn.RemoteAddr(), err)
+		status = 1
+	}
+	exit(ch, status)
+}
+
+// painted renders a session's model in the session's own theme and color
+// profile.
+type painted struct {
+	tea.Model
+	theme   *ui.Theme // shared with the game, which changes it from the settings
+	profile termenv.Profile
+}
+
+func (p painted) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	p.Model, cmd = p.Model.Update(msg)
+	return p, cmd
+}
+
+func (p painted) View() string {
+	return ui.Paint(*p.theme, p.profile, p.Model.View)
+}
+
+// exit reports the exit status and ends the session.
+func exit(ch ssh.Channel, status int) {
+	ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+	ch.Close()
+}
+
+// newModel returns a game for a player: the key's profile store, playing
+// the profile named after the SSH user, drawn in theme unless the profile's
+// settings choose another. Settings are kept in the profile.
+func (s *Server) newModel(keyID, user string, theme *ui.Theme) (game.Model, error) {
+	m := game.NewModel()
+	m.Theme = theme
+	m.NoColor = theme.Mono
+	if err := m.UseConfig(game.NewConfig(), false); err != nil {
+		return m, err
+	}
+	profiles, err := game.OpenProfileStore(filepath.Join(s.cfg.DataDir, "users", keyID))
+	if err != nil {
+		return m, err
+	}
+	m.Profiles = profiles
+	if err := m.UseProfile(user); err != nil {
+		// Not every user name makes a profile name
+		if err := m.UseProfile(game.DefaultProfileName); err != nil {
+			return m, err
+		}
+	}
+	m.Leaderboards = s.boards
+	m.Team = s.cfg.Team
+	m.AddPack(s.cfg.Pack)
+	return m, nil
+}
+
+// loadHostKey reads the server's host key, generating it if it is missing.
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "vimgame host key")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// LoadAuthorizedKeys reads public keys in authorized_keys format.
+func LoadAuthorizedKeys(path string) ([]ssh.PublicKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var keys []ssh.PublicKey
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Bytes()
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, sc.Err()
+}
@@ -0,0 +1,117 @@
+package sshd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muesli/termenv"
+	"golang.org/x/crypto/ssh"
+)
+
+// syncBuffer collects a session's output while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServeMenu(t *testing.T) {
+	srv, err := New(Config{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go srv.Serve(ln)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := ssh.Dial("tcp", ln.Addr().String(), &ssh.ClientConfig{
+		User:            "ada",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	sess, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	var out syncBuffer
+	sess.Stdout = &out
+	if err := sess.RequestPty("xterm-256color", 40, 120, ssh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.Shell(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Tutorial") || !strings.Contains(out.String(), "ada") {
+		if time.Now().After(deadline) {
+			t.Fatalf("menu not rendered; got %q", out.String())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestListenNeedsKeysBeyondLocalhost(t *testing.T) {
+	srv, err := New(Config{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.ListenAndServe(":0"); err == nil || !strings.Contains(err.Error(), "authorized keys") {
+		t.Errorf("ListenAndServe on all interfaces without keys: err = %v", err)
+	}
+}
+
+func TestSessionLook(t *testing.T) {
+	for _, tc := range []struct {
+		env     environ
+		theme   string
+		profile termenv.Profile
+	}{
+		{environ{"TERM": "xterm-256color"}, "dark", termenv.ANSI256},
+		{environ{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, "dark", termenv.TrueColor},
+		{environ{"TERM": "xterm"}, "dark", termenv.ANSI},
+		{environ{"TERM": "dumb"}, "dark", termenv.Ascii},
+		{environ{"TERM": "xterm-256color", "NO_COLOR": "1"}, "mono", termenv.ANSI256},
+	} {
+		ss := &session{env: tc.env}
+		theme, profile := ss.look(io.Discard)
+		if theme.Name != tc.theme || profile != tc.profile {
+			t.Errorf("%v: theme %s, profile %v; want %s, %v", tc.env, theme.Name, profile, tc.theme, tc.profile)
+		}
+	}
+}
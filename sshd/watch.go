@@ -0,0 +1,196 @@
+package sshd
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"vimgame/game"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// watchBuffer is how many messages a spectator may fall behind by before
+// it is caught up afresh.
+const watchBuffer = 4096
+
+// hub follows every session's runs for spectators (`ssh -t host watch`).
+type hub struct {
+	mu       sync.Mutex
+	nextID   int
+	players  map[int]*watchedSession
+	watchers map[*watcher]bool
+}
+
+// watcher is a subscribed spectator. One that falls too far behind stops
+// getting messages and is sent every run afresh once it has caught up.
+type watcher struct {
+	ch    chan tea.Msg
+	stale bool
+	gone  []int // sessions that left while stale
+}
+
+// watchedSession is what a spectator needs to catch up with a session.
+type watchedSession struct {
+	name   string
+	run    game.Replay // header and keys of the current run
+	active bool        // run has started
+}
+
+func newHub() *hub {
+	return &hub{players: make(map[int]*watchedSession), watchers: make(map[*watcher]bool)}
+}
+
+// publish sends msg to every spectator. The caller holds h.mu.
+func (h *hub) publish(msg tea.Msg) {
+	for w := range h.watchers {
+		if leave, ok := msg.(game.WatchLeaveMsg); ok && w.stale {
+			w.gone = append(w.gone, leave.ID)
+		}
+		w.send(msg)
+	}
+}
+
+// send queues msg for the spectator, marking it stale if there is no room.
+func (w *watcher) send(msg tea.Msg) {
+	if w.stale {
+		return
+	}
+	select {
+	case w.ch <- msg:
+	default:
+		w.stale = true
+	}
+}
+
+// catchUp sends a spectator every session and its run so far. The caller
+// holds h.mu.
+func (h *hub) catchUp(w *watcher) {
+	w.stale = false
+	for _, id := range w.gone {
+		w.send(game.WatchLeaveMsg{ID: id})
+	}
+	w.gone = nil
+	ids := make([]int, 0, len(h.players))
+	for id := range h.players {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		s := h.players[id]
+		w.send(game.WatchJoinMsg{ID: id, Name: s.name})
+		if s.active {
+			r := s.run
+			r.Keys = slices.Clone(r.Keys)
+			w.send(game.WatchRunMsg{ID: id, Replay: r})
+		}
+	}
+}
+
+func (h *hub) join(name string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := h.nextID
+	h.nextID++
+	h.players[id] = &watchedSession{name: name}
+	h.publish(game.WatchJoinMsg{ID: id, Name: name})
+	return id
+}
+
+func (h *hub) leave(id int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.players, id)
+	h.publish(game.WatchLeaveMsg{ID: id})
+}
+
+func (h *hub) run(id int, r game.Replay) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if p := h.players[id]; p != nil {
+		r.Keys = slices.Clone(r.Keys)
+		p.run, p.active = r, true
+		p.run.Keys = slices.Clone(r.Keys)
+		h.publish(game.WatchRunMsg{ID: id, Replay: r})
+	}
+}
+
+func (h *hub) keys(id int, keys []game.ReplayKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if p := h.players[id]; p != nil {
+		p.run.Keys = append(p.run.Keys, keys...)
+		h.publish(game.WatchKeysMsg{ID: id, Keys: keys})
+	}
+}
+
+func (h *hub) clock(id int, at time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.players[id] != nil {
+		h.publish(game.WatchClockMsg{ID: id, At: at})
+	}
+}
+
+// watch subscribes a spectator program, first catching it up with every
+// session, and returns a function that unsubscribes it.
+func (h *hub) watch(p *tea.Program) func() {
+	w := &watcher{ch: make(chan tea.Msg, watchBuffer)}
+	h.mu.Lock()
+	h.catchUp(w)
+	h.watchers[w] = true
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case msg := <-w.ch:
+				p.Send(msg)
+			case <-done:
+				return
+			}
+			h.mu.Lock()
+			if w.stale && len(w.ch) == 0 {
+				h.catchUp(w)
+			}
+			h.mu.Unlock()
+		}
+	}()
+	return func() {
+		h.mu.Lock()
+		delete(h.watchers, w)
+		h.mu.Unlock()
+		close(done)
+	}
+}
+
+// tap wraps a session's game to report its runs to the hub.
+type tap struct {
+	game.Model
+	hub  *hub
+	id   int
+	run  time.Time     // Recorded of the run last reported
+	sent int           // keys of that run reported
+	at   time.Duration // run time last reported
+}
+
+func (t tap) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := t.Model.Update(msg)
+	t.Model = next.(game.Model)
+	r := t.Model.Replay
+	switch {
+	case r.Recorded.IsZero():
+	case !r.Recorded.Equal(t.run):
+		t.run, t.sent = r.Recorded, len(r.Keys)
+		t.hub.run(t.id, r)
+	case len(r.Keys) > t.sent:
+		t.hub.keys(t.id, slices.Clone(r.Keys[t.sent:]))
+		t.sent = len(r.Keys)
+	case t.Model.Elapsed != t.at:
+		// The clock moved without a key, as an edit challenge's does
+		t.hub.clock(t.id, t.Model.Elapsed)
+	}
+	t.at = t.Model.Elapsed
+	return t, cmd
+}
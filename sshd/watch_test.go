@@ -0,0 +1,82 @@
+package sshd
+
+import (
+	"testing"
+	"time"
+
+	"vimgame/game"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// drain returns the messages waiting for a spectator.
+func drain(w *watcher) []tea.Msg {
+	var msgs []tea.Msg
+	for len(w.ch) > 0 {
+		msgs = append(msgs, <-w.ch)
+	}
+	return msgs
+}
+
+func TestWatcherCatchesUp(t *testing.T) {
+	h := newHub()
+	w := &watcher{ch: make(chan tea.Msg, 3)}
+	h.watchers[w] = true
+	id := h.join("ada")
+	gone := h.join("bob")
+	h.run(id, game.Replay{Recorded: time.Now()})
+	for _, key := range []string{"j", "k", "l"} {
+		h.keys(id, []game.ReplayKey{{Key: key}})
+	}
+	h.leave(gone)
+	if !w.stale {
+		t.Fatal("watcher not stale after overflowing")
+	}
+	drain(w)
+
+	h.catchUp(w)
+	msgs := drain(w)
+	if len(msgs) != 3 {
+		t.Fatalf("caught up with %d messages, want leave, join and run: %v", len(msgs), msgs)
+	}
+	if m, ok := msgs[0].(game.WatchLeaveMsg); !ok || m.ID != gone {
+		t.Errorf("first message %#v, want bob leaving", msgs[0])
+	}
+	if m, ok := msgs[2].(game.WatchRunMsg); !ok || len(m.Replay.Keys) != 3 {
+		t.Errorf("last message %#v, want the run with all its keys", msgs[2])
+	}
+}
+
+func TestWatchEditChallengeClock(t *testing.T) {
+	h := newHub()
+	w := &watcher{ch: make(chan tea.Msg, watchBuffer)}
+	h.watchers[w] = true
+	var player tea.Model = tap{Model: game.NewModel(), hub: h, id: h.join("ada")}
+	player, tick := player.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("5")})
+	spec := tea.Model(game.NewSpectator("test", game.Pack{}))
+	for _, msg := range drain(w) {
+		spec, _ = spec.Update(msg)
+	}
+
+	// A tick moves the clock without a key; spectators follow it
+	p := player.(tap)
+	p.RunStart = p.RunStart.Add(-30 * time.Second)
+	player, _ = p.Update(tick())
+	msgs := drain(w)
+	if len(msgs) != 1 {
+		t.Fatalf("published %v, want the clock", msgs)
+	}
+	if _, ok := msgs[0].(game.WatchClockMsg); !ok {
+		t.Fatalf("published %#v, want the clock", msgs[0])
+	}
+	spec, _ = spec.Update(msgs[0])
+	if got := spec.(game.SpectatorModel).Players[0].Run.Elapsed; got < 30*time.Second {
+		t.Errorf("spectated run time %v, want the player's", got)
+	}
+
+	// A clock past the deadline ends the run even without its key
+	spec, _ = spec.Update(game.WatchClockMsg{ID: p.id, At: game.EditChallengeTime})
+	if got := spec.(game.SpectatorModel).Players[0].Run.State; got != game.StateGameOver {
+		t.Errorf("spectated run is in state %d, want game over", got)
+	}
+}
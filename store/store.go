@@ -0,0 +1,104 @@
+// Package store persists vimgame's user data: profiles, leaderboards and
+// configuration. Files are JSON under the XDG base directories and are
+// written atomically so a crash never leaves a half-written file behind.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DataDir returns the directory for user data: $XDG_DATA_HOME/vimgame, or
+// ~/.local/share/vimgame.
+func DataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "vimgame")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "vimgame"
+	}
+	return filepath.Join(home, ".local", "share", "vimgame")
+}
+
+// ConfigDir returns the directory for configuration: $XDG_CONFIG_HOME/vimgame,
+// or ~/.config/vimgame.
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "vimgame")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "vimgame"
+	}
+	return filepath.Join(home, ".config", "vimgame")
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it into place, creating the directory if needed.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op once renamed
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SaveJSON writes v as indented JSON to path atomically.
+func SaveJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// LoadJSON reads JSON from path into v. A missing file is reported with an
+// error satisfying errors.Is(err, fs.ErrNotExist).
+func LoadJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Versioned is implemented by stored documents that carry a schema version.
+type Versioned interface {
+	SchemaVersion() int
+}
+
+// CheckVersion rejects documents written by a newer version of the game,
+// which this one could silently corrupt on save.
+func CheckVersion(path string, v Versioned, current int) error {
+	if got := v.SchemaVersion(); got > current {
+		return fmt.Errorf("%s: schema version %d is newer than supported version %d", path, got, current)
+	}
+	return nil
+}
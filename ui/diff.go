@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DiffMark is how a character differs from the other side of a diff.
+type DiffMark uint8
+
+const (
+	DiffSame    DiffMark = iota
+	DiffChanged          // the character is not on the other side
+	DiffGap              // the other side has text just before this character
+)
+
+// DiffMarks flags the parts of one buffer that differ from another.
+// Chars has an entry per character plus one past the end of the line, for
+// text missing at the end. Lines marks lines the other side lacks.
+type DiffMarks struct {
+	Chars [][]DiffMark
+	Lines []bool
+}
+
+// NewDiffMarks returns unmarked DiffMarks sized for lines.
+func NewDiffMarks(lines []string) DiffMarks {
+	d := DiffMarks{Chars: make([][]DiffMark, len(lines)), Lines: make([]bool, len(lines))}
+	for i, line := range lines {
+		d.Chars[i] = make([]DiffMark, len(line)+1)
+	}
+	return d
+}
+
+// at returns the mark at r, c. The zero DiffMarks marks nothing.
+func (d DiffMarks) at(r, c int) DiffMark {
+	if r < len(d.Lines) && d.Lines[r] {
+		return DiffChanged
+	}
+	if r < len(d.Chars) && c < len(d.Chars[r]) {
+		return d.Chars[r][c]
+	}
+	return DiffSame
+}
+
+// marked reports whether line r has any mark.
+func (d DiffMarks) marked(r int) bool {
+	if r >= len(d.Lines) {
+		return false
+	}
+	return d.Lines[r] || slices.ContainsFunc(d.Chars[r], func(m DiffMark) bool { return m != DiffSame })
+}
+
+var (
+	diffCountStyle lipgloss.Style
+	diffDoneStyle  lipgloss.Style
+)
+
+func buildDiffStyles(t Theme) {
+	diffCountStyle = lipgloss.NewStyle().
+		Foreground(t.Warn)
+
+	diffDoneStyle = lipgloss.NewStyle().
+		Foreground(t.Good)
+}
+
+// diffSide is the pane a diff is drawn in: the player's buffer, where
+// marked text has to go, or the goal, where it is still missing.
+type diffSide bool
+
+const (
+	diffRemove diffSide = false
+	diffAdd    diffSide = true
+)
+
+// diffStyle layers a diff mark over the character's own style. Without
+// colors, removals are struck through and additions underlined.
+func diffStyle(base lipgloss.Style, mark DiffMark, side diffSide) lipgloss.Style {
+	switch {
+	case mark == DiffSame:
+		return base
+	case mark == DiffGap:
+		return base.Underline(true)
+	case theme.Mono && side == diffRemove:
+		return base.Strikethrough(true)
+	case theme.Mono:
+		return base.Underline(true)
+	case side == diffRemove:
+		return base.Background(theme.DiffRemove)
+	}
+	return base.Background(theme.DiffAdd)
+}
+
+// diffSpace renders a marked blank, for text missing at the end of a line
+// or a whole empty line that differs.
+func diffSpace(side diffSide) string {
+	if theme.Mono {
+		return normalStyle.Reverse(true).Render(" ")
+	}
+	return diffStyle(normalStyle, DiffChanged, side).Render(" ")
+}
+
+// RenderDiffCount renders how many separate changes the buffer is from the goal.
+func RenderDiffCount(n int) string {
+	switch n {
+	case 0:
+		return diffDoneStyle.Render("  ✓ Matches the goal")
+	case 1:
+		return diffCountStyle.Render("  1 difference remaining")
+	}
+	return diffCountStyle.Render(fmt.Sprintf("  %d differences remaining", n))
+}
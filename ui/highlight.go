@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"go/scanner"
+	"go/token"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Syntax selects how RenderBuffer highlights a buffer.
+type Syntax int
+
+const (
+	SyntaxGo    Syntax = iota // go/scanner tokens
+	SyntaxPlain               // strings, numbers, comments and words in any language
+)
+
+// tokenClass is the kind of token a character belongs to.
+type tokenClass uint8
+
+const (
+	classNone tokenClass = iota
+	classKeyword
+	classString
+	classComment
+	classNumber
+	classIdent
+	classBuiltin // predeclared Go identifiers: int, nil, len, ...
+)
+
+var classStyles [classBuiltin + 1]lipgloss.Style
+
+func buildHighlightStyles(t Theme) {
+	classStyles = [...]lipgloss.Style{
+		classNone:    normalStyle,
+		classKeyword: lipgloss.NewStyle().Foreground(t.Keyword),
+		classString:  lipgloss.NewStyle().Foreground(t.String),
+		classComment: lipgloss.NewStyle().Foreground(t.Comment).Italic(true),
+		classNumber:  lipgloss.NewStyle().Foreground(t.Number),
+		classIdent:   lipgloss.NewStyle().Foreground(t.Ident),
+		classBuiltin: lipgloss.NewStyle().Foreground(t.Builtin),
+	}
+	if t.Mono {
+		classStyles[classKeyword] = classStyles[classKeyword].Bold(true)
+	}
+}
+
+// lineState is what a line leaves open for the next one.
+type lineState uint8
+
+const (
+	stateCode         lineState = iota
+	stateBlockComment           // inside /* */
+	stateRawString              // inside a `raw string`
+)
+
+// Highlighting is cached per row, keyed by the line's text and the state
+// it starts in, so only edited lines (and lines whose starting state an
+// edit changed) are scanned again.
+type lineKey struct {
+	syntax Syntax
+	state  lineState
+	text   string
+}
+
+type lineHighlight struct {
+	classes []tokenClass
+	end     lineState
+}
+
+type highlightRow struct {
+	key lineKey
+	lineHighlight
+}
+
+// A Highlighter caches the highlighting of one buffer by row. It holds
+// every row scanned, however long the buffer, and follows rows shifted by
+// lines inserted or deleted above them. A nil Highlighter caches nothing.
+type Highlighter struct {
+	mu    sync.Mutex
+	rows  []highlightRow
+	total int // len(lines) when rows was filled
+}
+
+// NewHighlighter returns an empty highlight cache for one buffer.
+func NewHighlighter() *Highlighter { return &Highlighter{} }
+
+// lines returns the token class of every character of lines[:end].
+func (c *Highlighter) lines(lines []string, syntax Syntax, end int) [][]tokenClass {
+	if c == nil {
+		c = &Highlighter{}
+	} else {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+	}
+	prev := c.rows
+	shift := len(lines) - c.total // rows below an insertion or deletion moved by shift
+	rows := make([]highlightRow, end)
+	out := make([][]tokenClass, end)
+	state := stateCode
+	for r := 0; r < end; r++ {
+		key := lineKey{syntax, state, lines[r]}
+		h, ok := cachedRow(prev, r, key)
+		if !ok && shift != 0 {
+			h, ok = cachedRow(prev, r-shift, key)
+		}
+		if !ok {
+			if syntax == SyntaxGo {
+				h = highlightGo(lines[r], state)
+			} else {
+				h = highlightPlain(lines[r])
+			}
+		}
+		rows[r] = highlightRow{key, h}
+		out[r] = h.classes
+		state = h.end
+	}
+	// Rows past end are still good for the next call
+	if end < len(prev) && shift == 0 {
+		rows = append(rows, prev[end:]...)
+	}
+	c.rows, c.total = rows, len(lines)
+	return out
+}
+
+func cachedRow(rows []highlightRow, r int, key lineKey) (lineHighlight, bool) {
+	if r < 0 || r >= len(rows) || rows[r].key != key {
+		return lineHighlight{}, false
+	}
+	return rows[r].lineHighlight, true
+}
+
+// highlightGo classifies a line of Go source starting in state.
+func highlightGo(line string, state lineState) lineHighlight {
+	classes := make([]tokenClass, len(line))
+	start := 0
+	switch state {
+	case stateBlockComment, stateRawString:
+		class, closer := classComment, "*/"
+		if state == stateRawString {
+			class, closer = classString, "`"
+		}
+		i := strings.Index(line, closer)
+		if i < 0 {
+			fill(classes, 0, len(line), class)
+			return lineHighlight{classes, state}
+		}
+		start = i + len(closer)
+		fill(classes, 0, start, class)
+	}
+
+	src := []byte(line[start:])
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+	end := stateCode
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue // inserted at the end of the line
+		}
+		off := start + file.Offset(pos)
+		n := len(lit)
+		if lit == "" {
+			n = len(tok.String())
+		}
+		n = min(n, len(line)-off)
+		switch {
+		case tok == token.COMMENT:
+			fill(classes, off, off+n, classComment)
+			if strings.HasPrefix(lit, "/*") && (len(lit) < 4 || !strings.HasSuffix(lit, "*/")) {
+				end = stateBlockComment
+			}
+		case tok == token.STRING || tok == token.CHAR:
+			fill(classes, off, off+n, classString)
+			if strings.HasPrefix(lit, "`") && (len(lit) < 2 || !strings.HasSuffix(lit, "`")) {
+				end = stateRawString
+			}
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			fill(classes, off, off+n, classNumber)
+		case tok.IsKeyword():
+			fill(classes, off, off+n, classKeyword)
+		case tok == token.IDENT && predeclared[lit]:
+			fill(classes, off, off+n, classBuiltin)
+		case tok == token.IDENT:
+			fill(classes, off, off+n, classIdent)
+		}
+	}
+	return lineHighlight{classes, end}
+}
+
+// predeclared lists Go's predeclared identifiers.
+var predeclared = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		any bool byte comparable complex64 complex128 error float32 float64
+		int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr
+		true false iota nil
+		append cap clear close complex copy delete imag len make max min new panic
+		print println real recover`) {
+		predeclared[name] = true
+	}
+}
+
+// highlightPlain classifies a line of any language: quoted strings,
+// numbers, // and # comments, and words.
+func highlightPlain(line string) lineHighlight {
+	classes := make([]tokenClass, len(line))
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for j < len(line) && line[j] != c {
+				if line[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(line))
+			fill(classes, i, j, classString)
+			i = j
+		case c == '#' || strings.HasPrefix(line[i:], "//"):
+			fill(classes, i, len(line), classComment)
+			i = len(line)
+		case isDigit(c):
+			j := i
+			for j < len(line) && (isWordChar(line[j]) || line[j] == '.') {
+				j++
+			}
+			fill(classes, i, j, classNumber)
+			i = j
+		case isWordChar(c):
+			j := i
+			for j < len(line) && isWordChar(line[j]) {
+				j++
+			}
+			fill(classes, i, j, classIdent)
+			i = j
+		default:
+			i++
+		}
+	}
+	return lineHighlight{classes, stateCode}
+}
+
+func fill(classes []tokenClass, from, to int, class tokenClass) {
+	for i := max(from, 0); i < min(to, len(classes)); i++ {
+		classes[i] = class
+	}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isWordChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
@@ -3,61 +3,112 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
 var (
+	hudStyle            lipgloss.Style
+	hudLabelStyle       lipgloss.Style
+	medalDiamondStyle   lipgloss.Style
+	medalGoldStyle      lipgloss.Style
+	medalSilverStyle    lipgloss.Style
+	medalBronzeStyle    lipgloss.Style
+	hintBoxStyle        lipgloss.Style
+	hintTitleStyle      lipgloss.Style
+	hintKeyStyle        lipgloss.Style
+	hintKeyDimStyle     lipgloss.Style
+	hintDescDimStyle    lipgloss.Style
+	modeInsertStyle     lipgloss.Style
+	progressStyle       lipgloss.Style
+	targetProgressStyle lipgloss.Style
+	tipStyle            lipgloss.Style
+	tipLabelStyle       lipgloss.Style
+	livesStyle          lipgloss.Style
+	clockLowStyle       lipgloss.Style
+	bonusStyle          lipgloss.Style
+	lifeLostStyle       lipgloss.Style
+	showCmdStyle        lipgloss.Style
+)
+
+func buildHUDStyles(t Theme) {
 	hudStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("236")).
-			Foreground(lipgloss.Color("252")).
-			Padding(0, 1).
-			Width(60)
+		Background(t.Panel).
+		Foreground(t.Text).
+		Padding(0, 1).
+		Width(60)
 
 	hudLabelStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("75")).
-			Bold(true)
+		Foreground(t.Title).
+		Bold(true)
 
-	medalDiamondStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Bold(true)
-	medalGoldStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Bold(true)
-	medalSilverStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Bold(true)
-	medalBronzeStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Bold(true)
+	medalDiamondStyle = lipgloss.NewStyle().Foreground(t.Diamond).Bold(true)
+	medalGoldStyle = lipgloss.NewStyle().Foreground(t.Gold).Bold(true)
+	medalSilverStyle = lipgloss.NewStyle().Foreground(t.Silver).Bold(true)
+	medalBronzeStyle = lipgloss.NewStyle().Foreground(t.Bronze).Bold(true)
 
 	hintBoxStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("241")).
-			Padding(0, 1).
-			Width(30)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Dim).
+		Padding(0, 1).
+		Width(30)
 
 	hintTitleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("75"))
+		Bold(true).
+		Foreground(t.Title)
 
 	hintKeyStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("226")).
-			Bold(true)
+		Foreground(t.Highlight).
+		Bold(true)
 
 	hintKeyDimStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241"))
+		Foreground(t.Dim)
 
 	hintDescDimStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("241"))
+		Foreground(t.Dim)
 
 	modeInsertStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("28")).
-			Foreground(lipgloss.Color("15")).
-			Bold(true).
-			Padding(0, 1)
+		Background(t.Insert).
+		Foreground(t.Text).
+		Bold(true).
+		Padding(0, 1).
+		Reverse(t.Mono)
 
 	progressStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("236")).
-			Foreground(lipgloss.Color("252")).
-			Padding(0, 1)
+		Background(t.Panel).
+		Foreground(t.Text).
+		Padding(0, 1)
 
 	targetProgressStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("252")).
-				Padding(0, 1)
-)
+		Foreground(t.Text).
+		Padding(0, 1)
+
+	tipStyle = lipgloss.NewStyle().
+		Foreground(t.Muted).
+		Italic(true)
+
+	tipLabelStyle = lipgloss.NewStyle().
+		Foreground(t.Title)
+
+	livesStyle = lipgloss.NewStyle().
+		Foreground(t.Bad)
+
+	clockLowStyle = lipgloss.NewStyle().
+		Foreground(t.Bad).
+		Bold(true)
+
+	bonusStyle = lipgloss.NewStyle().
+		Foreground(t.Good)
+
+	lifeLostStyle = lipgloss.NewStyle().
+		Foreground(t.Bad).
+		Bold(true)
+
+	showCmdStyle = lipgloss.NewStyle().
+		Foreground(t.Highlight).
+		Padding(0, 1)
+}
 
 // RenderHUD renders the heads-up display bar.
 func RenderHUD(levelNum int, levelName string, score, targetsHit, targetsTotal, keystrokes, pendingCount int) string {
@@ -68,7 +119,7 @@ func RenderHUD(levelNum int, levelName string, score, targetsHit, targetsTotal,
 		hudLabelStyle.Render("Keys: ") + fmt.Sprintf("%d", keystrokes),
 	}
 	if pendingCount > 0 {
-		countStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("226")).Bold(true)
+		countStyle := lipgloss.NewStyle().Foreground(theme.Highlight).Bold(true)
 		parts = append(parts, countStyle.Render(fmt.Sprintf("%d…", pendingCount)))
 	}
 	return hudStyle.Render(strings.Join(parts, "  │  "))
@@ -114,6 +165,16 @@ func RenderHints(hints []HintItem) string {
 	return hintBoxStyle.Render(sb.String())
 }
 
+// RenderPractising renders the commands an edit exercise is meant to
+// practise; edit exercises have no hints panel.
+func RenderPractising(commands []string) string {
+	keys := make([]string, len(commands))
+	for i, cmd := range commands {
+		keys[i] = hintKeyStyle.Render(cmd)
+	}
+	return hintDescDimStyle.Render("Practise: ") + strings.Join(keys, "  ")
+}
+
 // RenderModeIndicator renders the vim mode indicator (e.g., "-- INSERT --").
 func RenderModeIndicator(mode string) string {
 	return modeInsertStyle.Render("  -- " + mode + " --  ")
@@ -136,3 +197,43 @@ func RenderChallengeProgress(levelNum int, levelName string, exNum, totalEx, sco
 	text := fmt.Sprintf("Level %d: %s  │  Exercise %d/%d  │  Score: %d", levelNum, levelName, exNum, totalEx, score)
 	return progressStyle.Render(text)
 }
+
+// RenderTip renders a non-intrusive coaching tip, e.g. "jjjj → 4j".
+func RenderTip(text string) string {
+	return tipLabelStyle.Render("Tip: ") + tipStyle.Render(text)
+}
+
+// RenderEndlessStatus renders round, lives, keystroke budget and score for
+// endless mode.
+func RenderEndlessStatus(round, lives, maxLives, keys, budget, score int) string {
+	hearts := livesStyle.Render(strings.Repeat("♥", lives)) + strings.Repeat("♡", max(maxLives-lives, 0))
+	text := fmt.Sprintf("Round %d  │  ", round) + hearts + fmt.Sprintf("  │  Keys: %d/%d  │  Score: %d", keys, budget, score)
+	return progressStyle.Render(text)
+}
+
+// RenderLifeLost renders the notice shown after going over the keystroke budget.
+func RenderLifeLost() string {
+	return lifeLostStyle.Render("✗ Over budget — life lost")
+}
+
+// RenderEditChallengeStatus renders exercise progress, the countdown and
+// score for the timed edit challenge. The clock turns red under ten seconds.
+func RenderEditChallengeStatus(exNum, totalEx int, remaining time.Duration, score int) string {
+	clock := fmt.Sprintf("⏱ %.1fs", remaining.Seconds())
+	if remaining < 10*time.Second {
+		clock = clockLowStyle.Render(clock)
+	}
+	text := fmt.Sprintf("Edit Challenge  │  Exercise %d/%d  │  ", exNum, totalEx) + clock + fmt.Sprintf("  │  Score: %d", score)
+	return progressStyle.Render(text)
+}
+
+// RenderBonusTime renders the time banked by finishing an exercise early.
+func RenderBonusTime(bonus time.Duration) string {
+	return bonusStyle.Render(fmt.Sprintf("+%.1fs", bonus.Seconds()))
+}
+
+// RenderShowCmd renders the keys of a command still being typed, like
+// vim's showcmd.
+func RenderShowCmd(keys string) string {
+	return showCmdStyle.Render(keys)
+}
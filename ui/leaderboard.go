@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	boardTitleStyle     lipgloss.Style
+	boardHeadStyle      lipgloss.Style
+	boardRowStyle       lipgloss.Style
+	boardHighlightStyle lipgloss.Style
+	boardEmptyStyle     lipgloss.Style
+)
+
+func buildLeaderboardStyles(t Theme) {
+	boardTitleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Title)
+
+	boardHeadStyle = lipgloss.NewStyle().
+		Foreground(t.Dim).
+		Bold(true)
+
+	boardRowStyle = lipgloss.NewStyle().
+		Foreground(t.Text)
+
+	boardHighlightStyle = lipgloss.NewStyle().
+		Foreground(t.Highlight).
+		Bold(true)
+
+	boardEmptyStyle = lipgloss.NewStyle().
+		Foreground(t.Dim)
+}
+
+// LeaderboardRow is one entry of a rendered leaderboard.
+type LeaderboardRow struct {
+	Rank       int // 1-based place on the board
+	Name       string
+	Score      int
+	Keystrokes int
+	Time       time.Duration
+	Date       time.Time
+}
+
+// RenderLeaderboard renders a ranked table of entries under a title. The
+// row at highlight (-1 for none) is picked out, e.g. the run just played.
+func RenderLeaderboard(title string, rows []LeaderboardRow, highlight int) string {
+	var sb strings.Builder
+	sb.WriteString(boardTitleStyle.Render(title) + "\n")
+	if len(rows) == 0 {
+		sb.WriteString(boardEmptyStyle.Render("  No entries yet."))
+		return sb.String()
+	}
+	sb.WriteString(boardHeadStyle.Render(fmt.Sprintf("  %3s  %-16s %7s %6s %8s  %-10s", "#", "Name", "Score", "Keys", "Time", "Date")) + "\n")
+	for i, r := range rows {
+		marker := "  "
+		style := boardRowStyle
+		if i == highlight {
+			marker = "▸ "
+			style = boardHighlightStyle
+		}
+		name := r.Name
+		if len(name) > 16 {
+			name = name[:15] + "…"
+		}
+		line := fmt.Sprintf("%3d  %-16s %7d %6d %7.1fs  %-10s", r.Rank, name, r.Score, r.Keystrokes, r.Time.Seconds(), r.Date.Format("2006-01-02"))
+		sb.WriteString(marker + style.Render(line))
+		if i < len(rows)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	raceStatusStyle lipgloss.Style
+	raceLeftStyle   lipgloss.Style
+
+	ghostAheadStyle  lipgloss.Style
+	ghostBehindStyle lipgloss.Style
+)
+
+func buildRaceStyles(t Theme) {
+	raceStatusStyle = lipgloss.NewStyle().
+		Foreground(t.Text).
+		Padding(0, 1)
+
+	raceLeftStyle = lipgloss.NewStyle().
+		Foreground(t.Dim).
+		Strikethrough(true)
+
+	ghostAheadStyle = lipgloss.NewStyle().Foreground(t.Good).Bold(true)
+	ghostBehindStyle = lipgloss.NewStyle().Foreground(t.Bad).Bold(true)
+}
+
+// RaceRow is one player in the race standings.
+type RaceRow struct {
+	Name  string
+	Color int // ghost color; ignored for the player's own row
+	You   bool
+	Done  int
+	Total int
+	Place int // finishing place, 0 while racing
+	Left  bool
+}
+
+// RenderRaceStatus renders the standings as one line, each rival in their
+// ghost color.
+func RenderRaceStatus(rows []RaceRow) string {
+	parts := make([]string, len(rows))
+	for i, r := range rows {
+		name := r.Name
+		style := ghostTagStyle(r.Color)
+		if r.You {
+			name = "You"
+			style = hudLabelStyle
+		}
+		state := fmt.Sprintf("%d/%d", r.Done, r.Total)
+		if r.Place > 0 {
+			state = fmt.Sprintf("#%d", r.Place)
+		}
+		if r.Left {
+			parts[i] = raceLeftStyle.Render(name + " " + state)
+			continue
+		}
+		parts[i] = style.Render(name) + " " + state
+	}
+	return raceStatusStyle.Render("Race  " + strings.Join(parts, "  •  "))
+}
+
+// RenderRaceLobby renders the players waiting for a race, one per line.
+func RenderRaceLobby(rows []RaceRow) string {
+	var sb strings.Builder
+	for _, r := range rows {
+		style := ghostTagStyle(r.Color)
+		name := r.Name
+		if r.You {
+			name += " (you)"
+			style = hudLabelStyle
+		}
+		line := "  ● " + style.Render(name)
+		if r.Place > 0 {
+			line += fmt.Sprintf("  finished #%d", r.Place)
+		}
+		if r.Left {
+			line = "  ● " + raceLeftStyle.Render(r.Name) + "  left"
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// RenderGhostStatus renders how the run compares with the ghost's at the
+// last target: delta is the live time minus the ghost's. outrun means the
+// ghost never got that far.
+func RenderGhostStatus(name string, delta time.Duration, timed, outrun bool) string {
+	label := ghostTagStyle(0).Render("Ghost")
+	state := "racing " + name
+	switch {
+	case outrun:
+		state = ghostAheadStyle.Render("past the end of " + name + "'s run")
+	case timed && delta <= 0:
+		state = ghostAheadStyle.Render(fmt.Sprintf("▲ %.1fs ahead of %s", -delta.Seconds(), name))
+	case timed:
+		state = ghostBehindStyle.Render(fmt.Sprintf("▼ %.1fs behind %s", delta.Seconds(), name))
+	}
+	return raceStatusStyle.Render(label + "  " + state)
+}
@@ -8,75 +8,185 @@ import (
 )
 
 var (
+	lineNumStyle     lipgloss.Style
+	curLineNumStyle  lipgloss.Style
+	targetNumStyle   lipgloss.Style
+	cursorStyle      lipgloss.Style
+	targetStyle      lipgloss.Style
+	normalStyle      lipgloss.Style
+	borderStyle      lipgloss.Style
+	truncStyle       lipgloss.Style
+	goalLineNumStyle lipgloss.Style
+	goalTextStyle    lipgloss.Style
+	goalBorderStyle  lipgloss.Style
+	goalTitleStyle   lipgloss.Style
+)
+
+func buildRenderStyles(t Theme) {
 	lineNumStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241")).
-			Width(4).
-			Align(lipgloss.Right)
+		Foreground(t.Dim).
+		Width(4).
+		Align(lipgloss.Right)
+
+	curLineNumStyle = lineNumStyle.
+		Foreground(t.Title).
+		Bold(true)
+
+	targetNumStyle = lineNumStyle.
+		Foreground(t.Highlight).
+		Bold(true)
 
 	cursorStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("15")).
-			Foreground(lipgloss.Color("0")).
-			Bold(true)
+		Background(t.CursorBg).
+		Foreground(t.CursorFg).
+		Bold(true)
 
 	targetStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("226")).
-			Foreground(lipgloss.Color("0")).
-			Bold(true)
+		Background(t.TargetBg).
+		Foreground(t.TargetFg).
+		Bold(true)
+
+	if t.Mono {
+		cursorStyle = cursorStyle.Reverse(true)
+		targetStyle = targetStyle.Underline(true)
+		targetNumStyle = targetNumStyle.Underline(true)
+	}
 
 	normalStyle = lipgloss.NewStyle()
 
 	borderStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("62")).
-			Padding(0, 1)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Border).
+		Padding(0, 1)
 
 	truncStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241"))
+		Foreground(t.Dim)
 
 	goalLineNumStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("239")).
-				Width(4).
-				Align(lipgloss.Right)
+		Foreground(t.Faint).
+		Width(4).
+		Align(lipgloss.Right)
 
 	goalTextStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("243"))
+		Foreground(t.Muted)
 
 	goalBorderStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("241")).
-			Padding(0, 1)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Dim).
+		Padding(0, 1)
 
 	goalTitleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241")).
-			Bold(true)
+		Foreground(t.Dim).
+		Bold(true)
+}
+
+// Marker is another player's cursor drawn in a buffer. Color picks one of
+// the ghost colors, so the same player keeps the same color everywhere.
+type Marker struct {
+	Row, Col int
+	Color    int
+}
+
+func ghostStyle(color int) lipgloss.Style {
+	if theme.Mono {
+		return lipgloss.NewStyle().Underline(true).Italic(true)
+	}
+	return lipgloss.NewStyle().
+		Background(ghostColor(color)).
+		Foreground(theme.CursorFg)
+}
+
+// ghostAt returns the marker at r, c, if any.
+func ghostAt(ghosts []Marker, r, c int) (Marker, bool) {
+	for _, g := range ghosts {
+		if g.Row == r && g.Col == c {
+			return g, true
+		}
+	}
+	return Marker{}, false
+}
 
+// LineNumbers selects how RenderBuffer numbers lines, after vim's number
+// and relativenumber options.
+type LineNumbers int
+
+const (
+	NumberAbsolute LineNumbers = iota // every line by its line number
+	NumberRelative                    // every line by its distance from the cursor line
+	NumberHybrid                      // relative, with the cursor line's own number
 )
 
-// RenderBuffer renders the text buffer with cursor and target highlighting.
+// lineNumber renders the number of line r. A target line off the cursor
+// line shows the motion that reaches it, like "5j", in every mode.
+func lineNumber(r, cursorRow, targetRow int, mode LineNumbers) string {
+	dist := r - cursorRow
+	if r == targetRow && dist != 0 {
+		motion := fmt.Sprintf("%dj", dist)
+		if dist < 0 {
+			motion = fmt.Sprintf("%dk", -dist)
+		}
+		if len(motion) <= 4 {
+			return targetNumStyle.Render(motion)
+		}
+	}
+	switch {
+	case mode == NumberAbsolute:
+		return lineNumStyle.Render(fmt.Sprintf("%d", r+1))
+	case dist != 0:
+		return lineNumStyle.Render(fmt.Sprintf("%d", max(dist, -dist)))
+	case mode == NumberHybrid:
+		return curLineNumStyle.Align(lipgloss.Left).Render(fmt.Sprintf("%d", r+1))
+	}
+	return curLineNumStyle.Render("0")
+}
+
+// BufferView is how RenderBuffer draws a buffer beyond its text, cursor
+// and target.
+type BufferView struct {
+	Syntax    Syntax
+	Diff      DiffMarks // text that differs from the goal; the zero value marks nothing
+	Top       int       // first line shown before the cursor last moved
+	ScrollOff int       // lines kept in view above and below the cursor
+	Numbers   LineNumbers
+	Highlight *Highlighter // the buffer's highlight cache; nil scans every line
+}
+
+// RenderBuffer renders the text buffer with syntax, cursor and target
+// highlighting as view describes. Lines are numbered by view.Numbers.
 // cursorRow/Col and targetRow/Col are the cursor and target positions.
 // Pass -1 for targetRow/Col to hide the target highlight.
-// maxHeight limits the number of visible lines (0 = no limit).
+// maxHeight limits the number of visible lines (0 = no limit); the view
+// scrolls from view.Top as little as keeps view.ScrollOff lines around the
+// cursor.
+// When a visible line is wider than maxWidth allows, all lines scroll
+// horizontally together to keep the cursor in view, and ‹ › mark text
+// hidden off either side.
 // maxWidth limits the border box width (0 = no limit).
-func RenderBuffer(lines []string, cursorRow, cursorCol, targetRow, targetCol, maxHeight, maxWidth int) string {
-	startLine := 0
+// ghosts are drawn under the cursor and target.
+func RenderBuffer(lines []string, view BufferView, cursorRow, cursorCol, targetRow, targetCol, maxHeight, maxWidth int, ghosts ...Marker) string {
+	diff := view.Diff
+	startLine := ScrollTop(view.Top, cursorRow, len(lines), maxHeight, view.ScrollOff)
 	endLine := len(lines)
+	if maxHeight > 0 {
+		endLine = min(startLine+maxHeight, len(lines))
+	}
 
-	if maxHeight > 0 && len(lines) > maxHeight {
-		// Center viewport on cursor
-		half := maxHeight / 2
-		startLine = cursorRow - half
-		if startLine < 0 {
-			startLine = 0
-		}
-		endLine = startLine + maxHeight
-		if endLine > len(lines) {
-			endLine = len(lines)
-			startLine = endLine - maxHeight
-			if startLine < 0 {
-				startLine = 0
-			}
+	// Horizontal scrolling: bufferChrome columns go to the border, padding
+	// and line numbers, two more to the ‹ › indicators.
+	textWidth := maxWidth - bufferChrome - 2
+	scroll := false
+	for r := startLine; r < endLine && maxWidth > 0 && textWidth > 0; r++ {
+		if len(lines[r]) > maxWidth-bufferChrome {
+			scroll = true
+			break
 		}
 	}
+	colStart := 0
+	if scroll {
+		colStart = hscrollOffset(cursorCol, textWidth)
+	}
+
+	classes := view.Highlight.lines(lines, view.Syntax, endLine)
 
 	var sb strings.Builder
 
@@ -87,29 +197,52 @@ func RenderBuffer(lines []string, cursorRow, cursorCol, targetRow, targetCol, ma
 
 	for r := startLine; r < endLine; r++ {
 		line := lines[r]
-		sb.WriteString(lineNumStyle.Render(fmt.Sprintf("%d", r+1)))
+		sb.WriteString(lineNumber(r, cursorRow, targetRow, view.Numbers))
 		sb.WriteString("  ")
 
+		colEnd := len(line)
+		if scroll {
+			colEnd = min(colEnd, colStart+textWidth)
+			if colStart > 0 && len(line) > 0 {
+				sb.WriteString(truncStyle.Render("‹"))
+			} else {
+				sb.WriteString(" ")
+			}
+		}
+
 		if len(line) == 0 {
 			if cursorRow == r && cursorCol == 0 {
 				sb.WriteString(cursorStyle.Render(" "))
+			} else if g, ok := ghostAt(ghosts, r, 0); ok {
+				sb.WriteString(ghostStyle(g.Color).Render(" "))
+			} else if diff.at(r, 0) != DiffSame {
+				sb.WriteString(diffSpace(diffRemove))
 			}
 			sb.WriteString("\n")
 			continue
 		}
 
-		for c, ch := range line {
-			char := string(ch)
+		for c := colStart; c < colEnd; c++ {
+			char := string(line[c])
 			isCursor := r == cursorRow && c == cursorCol
 			isTarget := r == targetRow && c == targetCol
 			if isCursor {
 				sb.WriteString(cursorStyle.Render(char))
 			} else if isTarget {
 				sb.WriteString(targetStyle.Render(char))
+			} else if g, ok := ghostAt(ghosts, r, c); ok {
+				sb.WriteString(ghostStyle(g.Color).Render(char))
 			} else {
-				sb.WriteString(normalStyle.Render(char))
+				sb.WriteString(diffStyle(classStyles[classes[r][c]], diff.at(r, c), diffRemove).Render(char))
 			}
 		}
+		if colEnd == len(line) && diff.at(r, colEnd) != DiffSame && !(r == cursorRow && cursorCol == colEnd) {
+			// text missing at the end of the line
+			sb.WriteString(diffSpace(diffRemove))
+		}
+		if scroll && colEnd < len(line) {
+			sb.WriteString(truncStyle.Render("›"))
+		}
 		sb.WriteString("\n")
 	}
 
@@ -125,8 +258,41 @@ func RenderBuffer(lines []string, cursorRow, cursorCol, targetRow, targetCol, ma
 	return style.Render(sb.String())
 }
 
-// RenderGoalBuffer renders a read-only goal buffer with dimmed styling and no cursor.
-func RenderGoalBuffer(lines []string, maxHeight, maxWidth int) string {
+// ScrollTop returns the first line to show of n lines in a window height
+// lines tall. It moves top as little as keeps scrollOff lines visible
+// above and below the cursor; a scrollOff of half the height or more keeps
+// the cursor centered, like vim's scrolloff=999.
+func ScrollTop(top, cursorRow, n, height, scrollOff int) int {
+	if height <= 0 || n <= height {
+		return 0
+	}
+	if scrollOff*2 >= height {
+		top = cursorRow - height/2
+	} else {
+		top = min(top, cursorRow-scrollOff)
+		top = max(top, cursorRow+scrollOff-height+1)
+	}
+	return min(max(top, 0), n-height)
+}
+
+// bufferChrome is the width RenderBuffer spends around the text: border,
+// padding, the line number column and the gap after it.
+const bufferChrome = 2 + 2 + 4 + 2
+
+// hscrollOffset returns the first visible column so that col is on screen.
+// The view moves in half-screen steps so it stays put while the cursor
+// moves within it.
+func hscrollOffset(col, width int) int {
+	step := max(width/2, 1)
+	if col < width {
+		return 0
+	}
+	return (col - width + step) / step * step
+}
+
+// RenderGoalBuffer renders a read-only goal buffer with dimmed styling and no
+// cursor. diff marks the goal text the player's buffer is still missing.
+func RenderGoalBuffer(lines []string, diff DiffMarks, maxHeight, maxWidth int) string {
 	startLine := 0
 	endLine := len(lines)
 
@@ -146,7 +312,7 @@ func RenderGoalBuffer(lines []string, maxHeight, maxWidth int) string {
 		line := lines[r]
 		sb.WriteString(goalLineNumStyle.Render(fmt.Sprintf("%d", r+1)))
 		sb.WriteString("  ")
-		sb.WriteString(goalTextStyle.Render(line))
+		sb.WriteString(renderGoalLine(line, r, diff))
 		sb.WriteString("\n")
 	}
 
@@ -161,3 +327,21 @@ func RenderGoalBuffer(lines []string, maxHeight, maxWidth int) string {
 	}
 	return style.Render(sb.String())
 }
+
+// renderGoalLine renders one goal line, marking characters the buffer lacks.
+func renderGoalLine(line string, r int, diff DiffMarks) string {
+	if !diff.marked(r) {
+		return goalTextStyle.Render(line)
+	}
+	if len(line) == 0 {
+		return diffSpace(diffAdd)
+	}
+	var sb strings.Builder
+	for c := 0; c < len(line); c++ {
+		sb.WriteString(diffStyle(goalTextStyle, diff.at(r, c), diffAdd).Render(string(line[c])))
+	}
+	if diff.at(r, len(line)) != DiffSame {
+		sb.WriteString(diffSpace(diffAdd))
+	}
+	return sb.String()
+}
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	panelStyle     lipgloss.Style
+	panelInfoStyle lipgloss.Style
+)
+
+func buildSpectateStyles(t Theme) {
+	panelStyle = lipgloss.NewStyle().
+		Padding(0, 1)
+
+	panelInfoStyle = lipgloss.NewStyle().
+		Foreground(t.Dim)
+}
+
+// RenderPlayerTag renders a player's name in their ghost color.
+func RenderPlayerTag(name string, color int) string {
+	return ghostTagStyle(color).Render(name)
+}
+
+// RenderSpectatorPanel renders one player's panel: a header line, an info
+// line and the body, usually their buffer.
+func RenderSpectatorPanel(header, info, body string, width int) string {
+	style := panelStyle
+	if width > 0 {
+		style = style.Width(width)
+	}
+	return style.Render(lipgloss.JoinVertical(lipgloss.Left, header, panelInfoStyle.Render(info), body))
+}
+
+// RenderPanels lays panels out left to right, perRow to a row.
+func RenderPanels(panels []string, perRow int) string {
+	if perRow < 1 {
+		perRow = 1
+	}
+	var rows []string
+	for i := 0; i < len(panels); i += perRow {
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, panels[i:min(i+perRow, len(panels))]...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme holds every color the game draws with. Colors are anything
+// lipgloss.Color accepts: ANSI 256 numbers like "75" or hex like "#5fafff".
+// An empty color leaves the terminal default.
+type Theme struct {
+	Name string `json:"name"`
+	// Mono themes draw the cursor, target, ghosts and diffs with reverse
+	// video, underline and strikethrough instead of colors.
+	Mono bool `json:"mono,omitempty"`
+
+	Text      lipgloss.Color `json:"text"`      // ordinary text
+	Title     lipgloss.Color `json:"title"`     // titles, labels and the selected item
+	Dim       lipgloss.Color `json:"dim"`       // help lines and secondary text
+	Faint     lipgloss.Color `json:"faint"`     // the goal's line numbers
+	Muted     lipgloss.Color `json:"muted"`     // goal text and tips
+	Highlight lipgloss.Color `json:"highlight"` // keys, markers and the player's own row
+	Good      lipgloss.Color `json:"good"`      // success, time gained
+	Bad       lipgloss.Color `json:"bad"`       // errors, lives, time lost
+	Warn      lipgloss.Color `json:"warn"`      // counters that need attention
+	Border    lipgloss.Color `json:"border"`    // the buffer's border
+	Panel     lipgloss.Color `json:"panel"`     // background of status bars
+	Insert    lipgloss.Color `json:"insert"`    // background of the INSERT indicator
+
+	CursorFg lipgloss.Color `json:"cursor_fg"`
+	CursorBg lipgloss.Color `json:"cursor_bg"`
+	TargetFg lipgloss.Color `json:"target_fg"`
+	TargetBg lipgloss.Color `json:"target_bg"`
+
+	Keyword lipgloss.Color `json:"keyword"`
+	String  lipgloss.Color `json:"string"`
+	Comment lipgloss.Color `json:"comment"`
+	Number  lipgloss.Color `json:"number"`
+	Ident   lipgloss.Color `json:"ident"`
+	Builtin lipgloss.Color `json:"builtin"`
+
+	DiffRemove lipgloss.Color `json:"diff_remove"` // background of buffer text that has to go
+	DiffAdd    lipgloss.Color `json:"diff_add"`    // background of goal text still missing
+
+	Diamond lipgloss.Color `json:"diamond"`
+	Gold    lipgloss.Color `json:"gold"`
+	Silver  lipgloss.Color `json:"silver"`
+	Bronze  lipgloss.Color `json:"bronze"`
+
+	// Ghosts tell other players' cursors apart
+	Ghosts []lipgloss.Color `json:"ghosts"`
+}
+
+// DarkTheme is the default theme for dark terminals.
+var DarkTheme = Theme{
+	Name: "dark",
+	Text: "252", Title: "75", Dim: "241", Faint: "239", Muted: "243",
+	Highlight: "226", Good: "46", Bad: "203", Warn: "214",
+	Border: "62", Panel: "236", Insert: "28",
+	CursorFg: "0", CursorBg: "15", TargetFg: "0", TargetBg: "226",
+	Keyword: "204", String: "114", Comment: "244", Number: "173", Ident: "253", Builtin: "80",
+	DiffRemove: "52", DiffAdd: "22",
+	Diamond: "51", Gold: "220", Silver: "252", Bronze: "208",
+	Ghosts: []lipgloss.Color{"205", "45", "214", "141", "42", "203"},
+}
+
+// LightTheme suits terminals with a light background.
+var LightTheme = Theme{
+	Name: "light",
+	Text: "235", Title: "25", Dim: "244", Faint: "250", Muted: "242",
+	Highlight: "130", Good: "28", Bad: "160", Warn: "166",
+	Border: "61", Panel: "254", Insert: "114",
+	CursorFg: "15", CursorBg: "0", TargetFg: "0", TargetBg: "220",
+	Keyword: "125", String: "28", Comment: "245", Number: "130", Ident: "236", Builtin: "30",
+	DiffRemove: "224", DiffAdd: "194",
+	Diamond: "31", Gold: "136", Silver: "243", Bronze: "130",
+	Ghosts: []lipgloss.Color{"162", "31", "166", "91", "28", "160"},
+}
+
+// HighContrastTheme uses only the brightest colors, and a magenta target
+// that cannot be mistaken for the white cursor.
+var HighContrastTheme = Theme{
+	Name: "high-contrast",
+	Text: "15", Title: "51", Dim: "250", Faint: "248", Muted: "252",
+	Highlight: "226", Good: "46", Bad: "196", Warn: "214",
+	Border: "15", Panel: "0", Insert: "22",
+	CursorFg: "0", CursorBg: "15", TargetFg: "0", TargetBg: "201",
+	Keyword: "213", String: "120", Comment: "250", Number: "215", Ident: "15", Builtin: "87",
+	DiffRemove: "88", DiffAdd: "22",
+	Diamond: "51", Gold: "226", Silver: "15", Bronze: "208",
+	Ghosts: []lipgloss.Color{"201", "51", "226", "177", "46", "208"},
+}
+
+// DeuteranopiaTheme avoids telling things apart by red and green: success
+// is blue, failure orange, and the target is blue rather than yellow.
+var DeuteranopiaTheme = Theme{
+	Name: "deuteranopia",
+	Text: "252", Title: "75", Dim: "244", Faint: "239", Muted: "246",
+	Highlight: "220", Good: "39", Bad: "208", Warn: "220",
+	Border: "61", Panel: "236", Insert: "25",
+	CursorFg: "0", CursorBg: "15", TargetFg: "15", TargetBg: "27",
+	Keyword: "208", String: "117", Comment: "244", Number: "220", Ident: "253", Builtin: "111",
+	DiffRemove: "94", DiffAdd: "24",
+	Diamond: "117", Gold: "220", Silver: "252", Bronze: "172",
+	Ghosts: []lipgloss.Color{"208", "39", "220", "141", "117", "172"},
+}
+
+// MonoTheme draws without any color, for NO_COLOR.
+var MonoTheme = Theme{Name: "mono", Mono: true, Ghosts: []lipgloss.Color{""}}
+
+// Themes lists the built-in themes.
+var Themes = []Theme{DarkTheme, LightTheme, HighContrastTheme, DeuteranopiaTheme, MonoTheme}
+
+// ThemeByName returns the built-in theme called name.
+func ThemeByName(name string) (Theme, bool) {
+	i := slices.IndexFunc(Themes, func(t Theme) bool { return t.Name == name })
+	if i < 0 {
+		return Theme{}, false
+	}
+	return Themes[i], true
+}
+
+// DefaultTheme is the dark theme, or the mono theme when the NO_COLOR
+// environment variable is set (https://no-color.org).
+func DefaultTheme() Theme {
+	if os.Getenv("NO_COLOR") != "" {
+		return MonoTheme
+	}
+	return DarkTheme
+}
+
+// ParseTheme reads a custom theme from JSON. "base" names the built-in
+// theme it starts from (dark if unset); every other field overrides it:
+//
+//	{"name": "mine", "base": "dark", "target_bg": "#00afff"}
+func ParseTheme(data []byte) (Theme, error) {
+	var head struct {
+		Base string `json:"base"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return Theme{}, err
+	}
+	t := DarkTheme
+	if head.Base != "" {
+		var ok bool
+		if t, ok = ThemeByName(head.Base); !ok {
+			return Theme{}, fmt.Errorf("unknown base theme %q", head.Base)
+		}
+	}
+	t.Ghosts = slices.Clone(t.Ghosts)
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, err
+	}
+	if len(t.Ghosts) == 0 {
+		return Theme{}, fmt.Errorf("theme %q has no ghost colors", t.Name)
+	}
+	return t, nil
+}
+
+// LoadTheme resolves name to a theme: a built-in theme's name, or the path
+// of a JSON theme file (see ParseTheme).
+func LoadTheme(name string) (Theme, error) {
+	if t, ok := ThemeByName(name); ok {
+		return t, nil
+	}
+	if !strings.HasSuffix(name, ".json") {
+		return Theme{}, fmt.Errorf("unknown theme %q", name)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return Theme{}, err
+	}
+	t, err := ParseTheme(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
+
+var theme Theme
+
+func init() {
+	SetTheme(DefaultTheme())
+}
+
+// CurrentTheme returns the theme in use.
+func CurrentTheme() Theme {
+	return theme
+}
+
+// SetTheme restyles everything the package renders. Call it between
+// renders, e.g. from a model's Update; the styles are shared by every
+// program in the process, so programs with themes of their own render
+// through Paint instead.
+func SetTheme(t Theme) {
+	theme = t
+	buildRenderStyles(t)
+	buildHighlightStyles(t)
+	buildDiffStyles(t)
+	buildHUDStyles(t)
+	buildLeaderboardStyles(t)
+	buildRaceStyles(t)
+	buildSpectateStyles(t)
+}
+
+var paintMu sync.Mutex
+
+// Paint renders view with theme t in color profile p. Paints are
+// serialized and each restyles the package first, so programs serving
+// different terminals from one process can each have their own look.
+func Paint(t Theme, p termenv.Profile, view func() string) string {
+	paintMu.Lock()
+	defer paintMu.Unlock()
+	SetTheme(t)
+	lipgloss.SetColorProfile(p)
+	return view()
+}
+
+// ghostColor returns the color of the ghost numbered i.
+func ghostColor(i int) lipgloss.Color {
+	return theme.Ghosts[i%len(theme.Ghosts)]
+}
+
+// ghostTagStyle is the style of a player's name in their ghost color.
+func ghostTagStyle(i int) lipgloss.Style {
+	style := lipgloss.NewStyle().Foreground(ghostColor(i)).Bold(true)
+	if theme.Mono {
+		style = style.Italic(true)
+	}
+	return style
+}